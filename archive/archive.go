@@ -6,13 +6,28 @@ package archive
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
+	"filippo.io/age"
 	"github.com/mholt/archives"
 )
 
 func ArchiveDirectory(dir, outputFile string) error {
+	return archiveDirectory(dir, outputFile)
+}
+
+// ArchiveDirectoryEncrypted archives the directory as a tar archive encrypted for the recipients. The archive is
+// encrypted in authenticated chunks so that files can still be read from it without decrypting the whole archive.
+func ArchiveDirectoryEncrypted(dir, outputFile string, recipients ...age.Recipient) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients specified to encrypt archive for")
+	}
+	return archiveDirectory(dir, outputFile, recipients...)
+}
+
+func archiveDirectory(dir, outputFile string, recipients ...age.Recipient) error {
 	fi, err := os.ReadDir(dir)
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %w", err)
@@ -35,19 +50,40 @@ func ArchiveDirectory(dir, outputFile string) error {
 	defer os.Remove(tempTarArchive)
 	defer tempOutputFile.Close()
 
-	format, _, err := archives.Identify(context.Background(), filepath.Base(outputFile), nil)
-	if err != nil {
-		return fmt.Errorf("failed to identify output file format: %w", err)
-	}
-	archiver, ok := format.(archives.Archiver)
-	if !ok {
-		return fmt.Errorf("output file format is not an archiver")
+	var (
+		archiver         archives.Archiver = archives.Tar{}
+		w                io.Writer         = tempOutputFile
+		encryptingWriter io.WriteCloser
+	)
+	if len(recipients) > 0 {
+		// Encrypted archives are always tar archives as the output file name does not indicate the format.
+		encryptingWriter, err = age.Encrypt(tempOutputFile, recipients...)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt archive: %w", err)
+		}
+		w = encryptingWriter
+	} else {
+		format, _, err := archives.Identify(context.Background(), filepath.Base(outputFile), nil)
+		if err != nil {
+			return fmt.Errorf("failed to identify output file format: %w", err)
+		}
+		var ok bool
+		archiver, ok = format.(archives.Archiver)
+		if !ok {
+			return fmt.Errorf("output file format is not an archiver")
+		}
 	}
 
-	err = archiver.Archive(context.Background(), tempOutputFile, files)
+	err = archiver.Archive(context.Background(), w, files)
 	if err != nil {
 		return fmt.Errorf("failed to create archive: %w", err)
 	}
+	if encryptingWriter != nil {
+		// Closing the encrypting writer writes the final authenticated chunk.
+		if err := encryptingWriter.Close(); err != nil {
+			return fmt.Errorf("failed to encrypt archive: %w", err)
+		}
+	}
 	if err := tempOutputFile.Close(); err != nil {
 		return fmt.Errorf("failed to close temporary archive file: %w", err)
 	}
@@ -0,0 +1,47 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package archivetest provides helpers for tests reading encrypted bundles.
+package archivetest
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/require"
+)
+
+// NewKeyFile generates a new age key, returning the key and the file holding it.
+func NewKeyFile(t *testing.T) (identity *age.X25519Identity, keyFile string) {
+	t.Helper()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	keyFile = filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0o600))
+	return identity, keyFile
+}
+
+// EncryptBundle encrypts the bundle for a new age key, returning the encrypted bundle and the file holding the key.
+func EncryptBundle(t *testing.T, bundleFile string) (encryptedFile, keyFile string) {
+	t.Helper()
+
+	identity, keyFile := NewKeyFile(t)
+
+	src, err := os.Open(bundleFile)
+	require.NoError(t, err)
+	defer src.Close()
+	encryptedFile = filepath.Join(t.TempDir(), filepath.Base(bundleFile)+".age")
+	dst, err := os.Create(encryptedFile)
+	require.NoError(t, err)
+	defer dst.Close()
+	w, err := age.Encrypt(dst, identity.Recipient())
+	require.NoError(t, err)
+	_, err = io.Copy(w, src)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return encryptedFile, keyFile
+}
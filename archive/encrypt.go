@@ -0,0 +1,155 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// encryptedMagic is the start of the header of every file encrypted with age.
+const encryptedMagic = "age-encryption.org/v1\n"
+
+// ErrNoDecryptionKeys is returned when opening an encrypted archive without any decryption keys.
+var ErrNoDecryptionKeys = errors.New("archive is encrypted but no decryption keys were specified")
+
+// IsEncrypted returns whether the file is an encrypted archive.
+func IsEncrypted(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return string(magic) == encryptedMagic, nil
+}
+
+// DecryptedFile provides random access to the plaintext of an encrypted archive. The archive is encrypted in
+// authenticated chunks, so only the chunks containing the requested range are read and decrypted.
+type DecryptedFile struct {
+	*io.SectionReader
+	f *os.File
+}
+
+// OpenDecrypted opens the encrypted archive file for reading with the first of the identities that can decrypt it.
+func OpenDecrypted(file string, identities ...age.Identity) (*DecryptedFile, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("failed to open %s: %w", file, ErrNoDecryptionKeys)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to stat %s: %w", file, err)
+	}
+
+	plaintext, size, err := age.DecryptReaderAt(f, fi.Size(), identities...)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to decrypt %s: %w", file, err)
+	}
+
+	return &DecryptedFile{SectionReader: io.NewSectionReader(plaintext, 0, size), f: f}, nil
+}
+
+func (f *DecryptedFile) Close() error {
+	return f.f.Close()
+}
+
+// ParseRecipients returns the recipients to encrypt archives for. Each recipient is either an age public key
+// (age1...) or a file containing age public keys, one per line. If passphraseFile is not empty, the archive is
+// encrypted with the passphrase read from the file instead.
+func ParseRecipients(recipients []string, passphraseFile string) ([]age.Recipient, error) {
+	if passphraseFile != "" {
+		passphrase, err := readPassphrase(passphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid passphrase in %s: %w", passphraseFile, err)
+		}
+		return []age.Recipient{r}, nil
+	}
+
+	var parsed []age.Recipient
+	for _, recipient := range recipients {
+		if strings.HasPrefix(recipient, "age1") {
+			r, err := age.ParseRecipients(strings.NewReader(recipient))
+			if err != nil {
+				return nil, fmt.Errorf("invalid recipient %s: %w", recipient, err)
+			}
+			parsed = append(parsed, r...)
+			continue
+		}
+
+		f, err := os.Open(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open recipients file: %w", err)
+		}
+		r, err := age.ParseRecipients(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse recipients file %s: %w", recipient, err)
+		}
+		parsed = append(parsed, r...)
+	}
+	if len(parsed) == 0 {
+		return nil, errors.New("no recipients specified")
+	}
+	return parsed, nil
+}
+
+// ParseDecryptionKeys returns the identities to decrypt archives with. Each key file contains either age private keys
+// (AGE-SECRET-KEY-1...), one per line, or a passphrase.
+func ParseDecryptionKeys(keyFiles []string) ([]age.Identity, error) {
+	var identities []age.Identity
+	for _, keyFile := range keyFiles {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read decryption key file: %w", err)
+		}
+
+		if bytes.Contains(content, []byte("AGE-SECRET-KEY-")) {
+			ids, err := age.ParseIdentities(bytes.NewReader(content))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse decryption key file %s: %w", keyFile, err)
+			}
+			identities = append(identities, ids...)
+			continue
+		}
+
+		id, err := age.NewScryptIdentity(strings.TrimRight(string(content), "\r\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid passphrase in %s: %w", keyFile, err)
+		}
+		identities = append(identities, id)
+	}
+	return identities, nil
+}
+
+func readPassphrase(passphraseFile string) (string, error) {
+	content, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase file: %w", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
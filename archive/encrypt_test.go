@@ -0,0 +1,89 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/mindthegap/archive"
+)
+
+func TestArchiveDirectoryEncrypted(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte("# test key\n"+identity.String()+"\n"), 0o600))
+
+	dir, contents := writeTestBundleDir(t, map[string]int{
+		"images.yaml": 100,
+		"docker/registry/v2/blobs/sha256/aa/aa/data": 200 << 10,
+	})
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar.age")
+	recipients, err := archive.ParseRecipients([]string{identity.Recipient().String()}, "")
+	require.NoError(t, err)
+	require.NoError(t, archive.ArchiveDirectoryEncrypted(dir, outputFile, recipients...))
+
+	encrypted, err := archive.IsEncrypted(outputFile)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	require.ErrorIs(
+		t, archive.ExtractFileToDirectory(outputFile, t.TempDir(), "images.yaml"), archive.ErrNoDecryptionKeys,
+	)
+
+	otherIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	_, err = archive.OpenDecrypted(outputFile, otherIdentity)
+	var noMatchErr *age.NoIdentityMatchError
+	require.ErrorAs(t, err, &noMatchErr)
+
+	identities, err := archive.ParseDecryptionKeys([]string{keyFile})
+	require.NoError(t, err)
+	extractDir := t.TempDir()
+	require.NoError(t, archive.ExtractFileToDirectory(outputFile, extractDir, "images.yaml", identities...))
+	extracted, err := os.ReadFile(filepath.Join(extractDir, "images.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, contents["images.yaml"], extracted)
+}
+
+func TestArchiveDirectoryEncryptedWithPassphrase(t *testing.T) {
+	t.Parallel()
+
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("correct horse battery staple\n"), 0o600))
+
+	dir, contents := writeTestBundleDir(t, map[string]int{"images.yaml": 100})
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar")
+	recipients, err := archive.ParseRecipients(nil, passphraseFile)
+	require.NoError(t, err)
+	require.NoError(t, archive.ArchiveDirectoryEncrypted(dir, outputFile, recipients...))
+
+	identities, err := archive.ParseDecryptionKeys([]string{passphraseFile})
+	require.NoError(t, err)
+	extractDir := t.TempDir()
+	require.NoError(t, archive.ExtractFileToDirectory(outputFile, extractDir, "images.yaml", identities...))
+	extracted, err := os.ReadFile(filepath.Join(extractDir, "images.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, contents["images.yaml"], extracted)
+}
+
+func TestIsEncryptedPlainArchive(t *testing.T) {
+	t.Parallel()
+
+	dir, _ := writeTestBundleDir(t, map[string]int{"images.yaml": 100})
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar")
+	require.NoError(t, archive.ArchiveDirectory(dir, outputFile))
+
+	encrypted, err := archive.IsEncrypted(outputFile)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+}
@@ -10,13 +10,29 @@ import (
 	"os"
 	"path/filepath"
 
+	"filippo.io/age"
 	"github.com/mholt/archives"
 )
 
-func ExtractFileToDirectory(archive, destDir, fileName string) error {
-	archiveFile, err := os.Open(archive)
+// ExtractFileToDirectory extracts the named file from the archive to destDir. Encrypted archives are decrypted with
+// the first of the identities that can decrypt them.
+func ExtractFileToDirectory(archive, destDir, fileName string, identities ...age.Identity) error {
+	encrypted, err := IsEncrypted(archive)
 	if err != nil {
-		return fmt.Errorf("failed to open archive %s: %w", archive, err)
+		return err
+	}
+
+	var archiveFile io.ReadCloser
+	if encrypted {
+		archiveFile, err = OpenDecrypted(archive, identities...)
+		if err != nil {
+			return err
+		}
+	} else {
+		archiveFile, err = os.Open(archive)
+		if err != nil {
+			return fmt.Errorf("failed to open archive %s: %w", archive, err)
+		}
 	}
 	defer archiveFile.Close()
 
@@ -0,0 +1,424 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PartsIndexSuffix is the file name suffix of the index of a bundle split into parts.
+	PartsIndexSuffix = ".parts.yaml"
+
+	// MinPartSize is the smallest supported part size.
+	MinPartSize = 1 << 20
+
+	tarBlockSize = 512
+	// tarTrailerSize is the size of the end of archive marker written when closing a tar archive.
+	tarTrailerSize = 2 * tarBlockSize
+)
+
+// chunkNameRegexp matches the names of chunks of files that are split across parts. Chunks are hidden files in the
+// same directory as the split file so that the chunk names are never prefixed by the split file name.
+var chunkNameRegexp = regexp.MustCompile(`^\.(.+)\.chunk(\d{4,})$`)
+
+// ChunkName returns the name in a part of chunk i of the file name that is split across parts.
+func ChunkName(name string, i int) string {
+	dir, base := path.Split(name)
+	return fmt.Sprintf("%s.%s.chunk%04d", dir, base, i)
+}
+
+// ParseChunkName returns the name of the split file and the chunk index if name is a chunk name.
+func ParseChunkName(name string) (string, int, bool) {
+	dir, base := path.Split(name)
+	matches := chunkNameRegexp.FindStringSubmatch(base)
+	if matches == nil {
+		return "", 0, false
+	}
+	i, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return dir + matches[1], i, true
+}
+
+// PartsIndex lists the parts of a bundle split by ArchiveDirectoryInParts. Parts are plain tar archives that together
+// contain all files of the bundle.
+type PartsIndex struct {
+	Parts []Part `yaml:"parts"`
+}
+
+// Part is a single part of a bundle.
+type Part struct {
+	// Name is the file name of the part, relative to the directory of the index.
+	Name string `yaml:"name"`
+	// Size is the size of the part in bytes.
+	Size int64 `yaml:"size"`
+	// SHA256 is the hex encoded SHA256 checksum of the part.
+	SHA256 string `yaml:"sha256"`
+}
+
+// PartsIndexFile returns the path of the index of the parts of outputFile, e.g. bundle.parts.yaml for bundle.tar.
+func PartsIndexFile(outputFile string) string {
+	return strings.TrimSuffix(outputFile, ".tar") + PartsIndexSuffix
+}
+
+func partFile(outputFile string, n int) string {
+	return fmt.Sprintf("%s.part%04d.tar", strings.TrimSuffix(outputFile, ".tar"), n)
+}
+
+// IsPartsIndex returns true if file is the index of a bundle split into parts.
+func IsPartsIndex(file string) bool {
+	return strings.HasSuffix(file, PartsIndexSuffix)
+}
+
+type fileToArchive struct {
+	path string
+	name string
+	size int64
+}
+
+// ArchiveDirectoryInParts archives dir to tar archives of at most maxPartSize bytes each, next to outputFile, and
+// writes an index of the parts to PartsIndexFile(outputFile). Files are never split across parts, unless a file
+// does not fit in a part on its own, in which case it is split into chunks that are reassembled when reading.
+func ArchiveDirectoryInParts(dir, outputFile string, maxPartSize int64) (PartsIndex, error) {
+	if filepath.Ext(outputFile) != ".tar" {
+		return PartsIndex{}, fmt.Errorf("bundles split into parts must be written to .tar files: %s", outputFile)
+	}
+	if maxPartSize < MinPartSize {
+		return PartsIndex{}, fmt.Errorf("maximum part size must be at least %d bytes", MinPartSize)
+	}
+
+	var files []fileToArchive
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, fileToArchive{path: p, name: filepath.ToSlash(name), size: fi.Size()})
+		return nil
+	})
+	if err != nil {
+		return PartsIndex{}, fmt.Errorf("failed to read directory: %w", err)
+	}
+	// Sort files so that the bundle configs in the root directory are written to the first part.
+	slices.SortFunc(files, func(a, b fileToArchive) int {
+		aRoot, bRoot := !strings.Contains(a.name, "/"), !strings.Contains(b.name, "/")
+		if aRoot != bRoot {
+			if aRoot {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	w := &partsWriter{outputFile: outputFile, maxPartSize: maxPartSize}
+	defer w.cleanup()
+	for _, f := range files {
+		if err := w.addFile(f); err != nil {
+			return PartsIndex{}, err
+		}
+	}
+	return w.finish()
+}
+
+// partsWriter writes files to a sequence of parts, starting a new part whenever the next file does not fit.
+type partsWriter struct {
+	outputFile  string
+	maxPartSize int64
+
+	index     PartsIndex
+	tempFiles []string
+
+	current *partWriter
+}
+
+type partWriter struct {
+	file    *os.File
+	tw      *tar.Writer
+	written *countingWriter
+	sum     func() []byte
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// entrySize returns an upper bound of the size of a tar entry with the given name and size.
+func entrySize(name string, size int64) int64 {
+	// Long names are written as PAX records, requiring an additional header and record blocks.
+	headerSize := int64(tarBlockSize)
+	if len(name) >= 100 {
+		headerSize += tarBlockSize + roundUpToBlock(int64(len(name))+64)
+	}
+	return headerSize + roundUpToBlock(size)
+}
+
+func roundUpToBlock(n int64) int64 {
+	return (n + tarBlockSize - 1) / tarBlockSize * tarBlockSize
+}
+
+func (w *partsWriter) remaining() int64 {
+	if w.current == nil {
+		return w.maxPartSize - tarTrailerSize
+	}
+	return w.maxPartSize - tarTrailerSize - w.current.written.n
+}
+
+func (w *partsWriter) addFile(f fileToArchive) error {
+	// Start a new part if the file does not fit in the current part, but would fit in a new part.
+	if entrySize(f.name, f.size) > w.remaining() && entrySize(f.name, f.size) <= w.maxPartSize-tarTrailerSize {
+		if err := w.closePart(); err != nil {
+			return err
+		}
+	}
+	if entrySize(f.name, f.size) <= w.remaining() {
+		return w.writeEntry(f, f.name, 0, f.size)
+	}
+
+	// The file does not fit in a part on its own, so split it into chunks filling up parts.
+	var offset int64
+	for i := 0; offset < f.size; i++ {
+		chunkName := ChunkName(f.name, i)
+		available := (w.remaining() - entrySize(chunkName, 0)) / tarBlockSize * tarBlockSize
+		if available < tarBlockSize {
+			if err := w.closePart(); err != nil {
+				return err
+			}
+			available = (w.remaining() - entrySize(chunkName, 0)) / tarBlockSize * tarBlockSize
+		}
+		n := min(available, f.size-offset)
+		if err := w.writeEntry(f, chunkName, offset, n); err != nil {
+			return err
+		}
+		offset += n
+	}
+	return nil
+}
+
+func (w *partsWriter) writeEntry(f fileToArchive, name string, offset, size int64) error {
+	if w.current == nil {
+		if err := w.openPart(); err != nil {
+			return err
+		}
+	}
+
+	src, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	defer src.Close()
+
+	if err := w.current.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+	}
+	if _, err := io.Copy(w.current.tw, io.NewSectionReader(src, offset, size)); err != nil {
+		return fmt.Errorf("failed to write %s to tar archive: %w", name, err)
+	}
+	return nil
+}
+
+func (w *partsWriter) openPart() error {
+	finalName := partFile(w.outputFile, len(w.index.Parts)+1)
+	tempName := filepath.Join(filepath.Dir(finalName), "."+filepath.Base(finalName))
+	f, err := os.Create(tempName)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle part: %w", err)
+	}
+	w.tempFiles = append(w.tempFiles, tempName)
+
+	h := sha256.New()
+	written := &countingWriter{w: io.MultiWriter(f, h)}
+	w.current = &partWriter{
+		file:    f,
+		tw:      tar.NewWriter(written),
+		written: written,
+		sum:     func() []byte { return h.Sum(nil) },
+	}
+	return nil
+}
+
+func (w *partsWriter) closePart() error {
+	if w.current == nil {
+		return nil
+	}
+	p := w.current
+	w.current = nil
+	if err := p.tw.Close(); err != nil {
+		_ = p.file.Close()
+		return fmt.Errorf("failed to write bundle part: %w", err)
+	}
+	if err := p.file.Close(); err != nil {
+		return fmt.Errorf("failed to close bundle part: %w", err)
+	}
+	w.index.Parts = append(w.index.Parts, Part{
+		Name:   filepath.Base(partFile(w.outputFile, len(w.index.Parts)+1)),
+		Size:   p.written.n,
+		SHA256: hex.EncodeToString(p.sum()),
+	})
+	return nil
+}
+
+// finish closes the last part, moves all parts into place and writes the index.
+func (w *partsWriter) finish() (PartsIndex, error) {
+	if err := w.closePart(); err != nil {
+		return PartsIndex{}, err
+	}
+	if len(w.index.Parts) == 0 {
+		return PartsIndex{}, errors.New("no files to archive")
+	}
+
+	for i := range w.index.Parts {
+		finalName := partFile(w.outputFile, i+1)
+		if err := os.Rename(w.tempFiles[i], finalName); err != nil {
+			return PartsIndex{}, fmt.Errorf("failed to rename temporary bundle part to %s: %w", finalName, err)
+		}
+	}
+	w.tempFiles = nil
+
+	indexBytes, err := yaml.Marshal(w.index)
+	if err != nil {
+		return PartsIndex{}, fmt.Errorf("failed to marshal bundle parts index: %w", err)
+	}
+	if err := os.WriteFile(PartsIndexFile(w.outputFile), indexBytes, 0o644); err != nil {
+		return PartsIndex{}, fmt.Errorf("failed to write bundle parts index: %w", err)
+	}
+	return w.index, nil
+}
+
+func (w *partsWriter) cleanup() {
+	if w.current != nil {
+		_ = w.current.file.Close()
+	}
+	for _, f := range w.tempFiles {
+		_ = os.Remove(f)
+	}
+}
+
+// ReadPartsIndex reads the index of a bundle split into parts.
+func ReadPartsIndex(indexFile string) (PartsIndex, error) {
+	b, err := os.ReadFile(indexFile)
+	if err != nil {
+		return PartsIndex{}, fmt.Errorf("failed to read bundle parts index: %w", err)
+	}
+	var index PartsIndex
+	if err := yaml.Unmarshal(b, &index); err != nil {
+		return PartsIndex{}, fmt.Errorf("failed to parse bundle parts index %s: %w", indexFile, err)
+	}
+	if len(index.Parts) == 0 {
+		return PartsIndex{}, fmt.Errorf("bundle parts index %s does not list any parts", indexFile)
+	}
+	// Parts are always written next to the index, so names that would resolve anywhere else are rejected.
+	for _, p := range index.Parts {
+		if p.Name == "" || p.Name == "." || p.Name == ".." || p.Name != filepath.Base(p.Name) {
+			return PartsIndex{}, fmt.Errorf("bundle parts index %s lists invalid part name %q", indexFile, p.Name)
+		}
+	}
+	return index, nil
+}
+
+// VerifyParts checks that all parts listed in the index at indexFile exist and match their checksums, returning
+// the paths of the parts.
+func VerifyParts(indexFile string) ([]string, error) {
+	index, err := ReadPartsIndex(indexFile)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(index.Parts))
+	for _, p := range index.Parts {
+		partPath := filepath.Join(filepath.Dir(indexFile), p.Name)
+		if err := verifyPart(partPath, p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, partPath)
+	}
+	return paths, nil
+}
+
+func verifyPart(partPath string, p Part) error {
+	f, err := os.Open(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle part: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle part %s: %w", partPath, err)
+	}
+	if n != p.Size {
+		return fmt.Errorf("bundle part %s has size %d, expected %d", partPath, n, p.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != p.SHA256 {
+		return fmt.Errorf("bundle part %s has checksum %s, expected %s", partPath, sum, p.SHA256)
+	}
+	return nil
+}
+
+// ExpandPartsIndexes replaces every bundle parts index in files with the verified parts that it lists. Parts that
+// are also listed explicitly, e.g. when matched by the same glob pattern as the index, are only returned once.
+func ExpandPartsIndexes(files []string) ([]string, error) {
+	expanded := make([]string, 0, len(files))
+	seen := make(map[string]struct{}, len(files))
+	add := func(f string) {
+		if _, ok := seen[filepath.Clean(f)]; ok {
+			return
+		}
+		seen[filepath.Clean(f)] = struct{}{}
+		expanded = append(expanded, f)
+	}
+
+	for _, f := range files {
+		if !IsPartsIndex(f) {
+			add(f)
+			continue
+		}
+		parts, err := VerifyParts(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parts {
+			add(p)
+		}
+	}
+	return expanded, nil
+}
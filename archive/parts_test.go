@@ -0,0 +1,194 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/mindthegap/archive"
+)
+
+// writeTestBundleDir writes files with random content of the given sizes to a new directory.
+func writeTestBundleDir(t *testing.T, sizes map[string]int) (string, map[string][]byte) {
+	t.Helper()
+
+	dir := t.TempDir()
+	contents := make(map[string][]byte, len(sizes))
+	for name, size := range sizes {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o644))
+		contents[name] = content
+	}
+	return dir, contents
+}
+
+// readParts returns the names of the entries of each part and the contents of all files, reassembling chunks.
+func readParts(t *testing.T, parts []string) ([][]string, map[string][]byte) {
+	t.Helper()
+
+	partEntries := make([][]string, 0, len(parts))
+	chunks := map[string]map[int][]byte{}
+	for _, p := range parts {
+		f, err := os.Open(p)
+		require.NoError(t, err)
+		defer f.Close()
+
+		var entries []string
+		tr := tar.NewReader(f)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			entries = append(entries, hdr.Name)
+
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			name, i, ok := archive.ParseChunkName(hdr.Name)
+			if !ok {
+				name, i = hdr.Name, 0
+			}
+			if chunks[name] == nil {
+				chunks[name] = map[int][]byte{}
+			}
+			chunks[name][i] = content
+		}
+		partEntries = append(partEntries, entries)
+	}
+
+	contents := make(map[string][]byte, len(chunks))
+	for name, fileChunks := range chunks {
+		var buf bytes.Buffer
+		for i := range len(fileChunks) {
+			require.Contains(t, fileChunks, i, "missing chunk %d of %s", i, name)
+			buf.Write(fileChunks[i])
+		}
+		contents[name] = buf.Bytes()
+	}
+	return partEntries, contents
+}
+
+func TestArchiveDirectoryInParts(t *testing.T) {
+	t.Parallel()
+
+	dir, contents := writeTestBundleDir(t, map[string]int{
+		"images.yaml":                  100,
+		"docker/registry/v2/blobs/a":   600 << 10,
+		"docker/registry/v2/blobs/b":   600 << 10,
+		"docker/registry/v2/blobs/c":   300 << 10,
+		"docker/registry/v2/blobs/big": 5<<19 + 123,
+	})
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar")
+
+	index, err := archive.ArchiveDirectoryInParts(dir, outputFile, archive.MinPartSize)
+	require.NoError(t, err)
+	require.FileExists(t, archive.PartsIndexFile(outputFile))
+	assert.Equal(t, filepath.Join(filepath.Dir(outputFile), "bundle.parts.yaml"), archive.PartsIndexFile(outputFile))
+
+	parts, err := archive.VerifyParts(archive.PartsIndexFile(outputFile))
+	require.NoError(t, err)
+	require.Len(t, parts, len(index.Parts))
+	for i, p := range index.Parts {
+		fi, err := os.Stat(parts[i])
+		require.NoError(t, err)
+		assert.Equal(t, p.Size, fi.Size())
+		assert.LessOrEqual(t, fi.Size(), int64(archive.MinPartSize), "part %s is too large", p.Name)
+	}
+	assert.Equal(t, "bundle.part0001.tar", index.Parts[0].Name)
+
+	partEntries, archivedContents := readParts(t, parts)
+	assert.Equal(t, "images.yaml", partEntries[0][0], "bundle configs should be in the first part")
+	assert.Equal(t, contents, archivedContents)
+
+	// Only the file larger than a part is split into chunks, filling up the remaining space of the current part.
+	chunked := map[string]int{}
+	for _, entries := range partEntries {
+		for _, e := range entries {
+			if name, _, ok := archive.ParseChunkName(e); ok {
+				chunked[name]++
+			}
+		}
+	}
+	assert.Equal(t, map[string]int{"docker/registry/v2/blobs/big": 4}, chunked)
+}
+
+func TestArchiveDirectoryInPartsInvalidPartSize(t *testing.T) {
+	t.Parallel()
+
+	dir, _ := writeTestBundleDir(t, map[string]int{"images.yaml": 100})
+	_, err := archive.ArchiveDirectoryInParts(dir, filepath.Join(t.TempDir(), "bundle.tar"), 1024)
+	require.ErrorContains(t, err, "maximum part size must be at least")
+}
+
+func TestVerifyPartsChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	dir, _ := writeTestBundleDir(t, map[string]int{"images.yaml": 100, "blobs/a": 2 << 20})
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar")
+	index, err := archive.ArchiveDirectoryInParts(dir, outputFile, archive.MinPartSize)
+	require.NoError(t, err)
+
+	partFile := filepath.Join(filepath.Dir(outputFile), index.Parts[1].Name)
+	f, err := os.OpenFile(partFile, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("corrupt"), 2048)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = archive.ExpandPartsIndexes([]string{archive.PartsIndexFile(outputFile)})
+	require.ErrorContains(t, err, "has checksum")
+}
+
+func TestReadPartsIndexInvalidPartName(t *testing.T) {
+	t.Parallel()
+
+	for _, partName := range []string{"../outside.tar", "/etc/passwd", "parts/bundle.tar.part-001", "..", ""} {
+		t.Run(partName, func(t *testing.T) {
+			t.Parallel()
+
+			indexFile := filepath.Join(t.TempDir(), "bundle.tar.parts.yaml")
+			content := "parts:\n- name: \"" + partName + "\"\n  size: 1\n  sha256: abc\n"
+			require.NoError(t, os.WriteFile(indexFile, []byte(content), 0o600))
+
+			_, err := archive.ReadPartsIndex(indexFile)
+			require.ErrorContains(t, err, "invalid part name")
+			_, err = archive.ExpandPartsIndexes([]string{indexFile})
+			require.ErrorContains(t, err, "invalid part name")
+		})
+	}
+}
+
+func TestExpandPartsIndexes(t *testing.T) {
+	t.Parallel()
+
+	dir, _ := writeTestBundleDir(t, map[string]int{"images.yaml": 100, "blobs/a": 2 << 20})
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar")
+	index, err := archive.ArchiveDirectoryInParts(dir, outputFile, archive.MinPartSize)
+	require.NoError(t, err)
+	firstPart := filepath.Join(filepath.Dir(outputFile), index.Parts[0].Name)
+
+	expanded, err := archive.ExpandPartsIndexes(
+		[]string{"other.tar", firstPart, archive.PartsIndexFile(outputFile)},
+	)
+	require.NoError(t, err)
+	want := []string{"other.tar"}
+	for _, p := range index.Parts {
+		want = append(want, filepath.Join(filepath.Dir(outputFile), p.Name))
+	}
+	assert.Equal(t, want, expanded)
+}
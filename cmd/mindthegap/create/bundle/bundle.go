@@ -10,9 +10,14 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 
+	"filippo.io/age"
+	"github.com/containers/image/v5/types"
+	"github.com/docker/go-units"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/logs"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -21,6 +26,7 @@ import (
 	"github.com/mholt/archives"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 	"helm.sh/helm/v4/pkg/action"
 	"k8s.io/utils/ptr"
 
@@ -36,6 +42,7 @@ import (
 	"github.com/mesosphere/mindthegap/images"
 	"github.com/mesosphere/mindthegap/images/authnhelpers"
 	"github.com/mesosphere/mindthegap/images/httputils"
+	"github.com/mesosphere/mindthegap/images/mirrors"
 )
 
 func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more readable.
@@ -50,7 +57,20 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 		outputFile             string
 		overwrite              bool
 		merge                  bool
+		maxPartSize            string
+		maxPartSizeBytes       int64
+		encryption             flags.Encryption
+		recipients             []age.Recipient
 		imagePullConcurrency   int
+		maxBandwidth           flags.Bandwidth
+		registryMirrors        []string
+		useRegistriesConf      bool
+		fromRegistry           flags.RegistryURI
+		fromRegistrySkipTLS    bool
+		fromRegistryUsername   string
+		fromRegistryPassword   string
+		fromRegistryFilter     catalogFilter
+		imageArchives          []string
 	)
 
 	cmd := &cobra.Command{
@@ -61,6 +81,20 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 				return err
 			}
 
+			if err := fromRegistryFilter.validate(); err != nil {
+				return err
+			}
+
+			var err error
+			recipients, err = encryption.ParseRecipients()
+			if err != nil {
+				return err
+			}
+			if encryption.Encrypt {
+				// Encrypted bundles are always tar archives, whatever the name of the output file.
+				return nil
+			}
+
 			archiver, _, err := archives.Identify(context.Background(), outputFile, nil)
 			if err != nil {
 				return fmt.Errorf(
@@ -76,6 +110,19 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 				return fmt.Errorf("compressed tar archives (%s) are not supported", ext)
 			}
 
+			if maxPartSize != "" {
+				if ext != ".tar" {
+					return fmt.Errorf("--max-part-size requires a .tar output file, got %s", outputFile)
+				}
+				maxPartSizeBytes, err = flags.ParseByteSize(maxPartSize)
+				if err != nil {
+					return fmt.Errorf("invalid --max-part-size: %w", err)
+				}
+				if maxPartSizeBytes < archive.MinPartSize {
+					return fmt.Errorf("--max-part-size must be at least 1MiB")
+				}
+			}
+
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -96,6 +143,31 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 				imagesConfig = cfg
 			}
 
+			if fromRegistry.Host() != "" {
+				out.StartOperation(fmt.Sprintf("Listing images in registry %s", fromRegistry.Address()))
+				var creds *types.DockerAuthConfig
+				if fromRegistryUsername != "" {
+					creds = &types.DockerAuthConfig{
+						Username: fromRegistryUsername,
+						Password: fromRegistryPassword,
+					}
+				}
+				cfg, err := imagesConfigFromRegistry(
+					context.Background(),
+					&fromRegistry,
+					fromRegistrySkipTLS,
+					creds,
+					fromRegistryFilter,
+				)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return err
+				}
+				out.EndOperationWithStatus(output.Success())
+				out.V(4).Infof("Images config from registry: %+v", cfg)
+				imagesConfig = *imagesConfig.Merge(cfg)
+			}
+
 			if helmChartsConfigFile != "" {
 				out.StartOperation("Parsing Helm chart bundle config")
 				cfg, err := config.ParseHelmChartsConfigFile(helmChartsConfigFile)
@@ -121,22 +193,28 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 				ociArtifactsConfig = cfg
 			}
 
+			// Bundles split into parts are identified by the index listing the parts.
+			existingOutputFile := outputFile
+			if maxPartSizeBytes > 0 {
+				existingOutputFile = archive.PartsIndexFile(outputFile)
+			}
+
 			if !overwrite && !merge {
 				out.StartOperation("Checking if output file already exists")
-				_, err := os.Stat(outputFile)
+				_, err := os.Stat(existingOutputFile)
 				switch {
 				case err == nil:
 					out.EndOperationWithStatus(output.Failure())
 					return fmt.Errorf(
 						"%s already exists: specify --overwrite to overwrite existing file"+
 							"                   or --merge to add new images to the existing file",
-						outputFile,
+						existingOutputFile,
 					)
 				case !errors.Is(err, os.ErrNotExist):
 					out.EndOperationWithStatus(output.Failure())
 					return fmt.Errorf(
 						"failed to check if output file %s already exists: %w",
-						outputFile,
+						existingOutputFile,
 						err,
 					)
 				default:
@@ -223,17 +301,58 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 			logs.Debug.SetOutput(out.V(4).InfoWriter())
 			logs.Warn.SetOutput(out.V(2).InfoWriter())
 
-			if imagesConfigFile != "" || ociArtifactsConfigFile != "" {
+			if imagesConfigFile != "" || fromRegistry.Host() != "" || ociArtifactsConfigFile != "" ||
+				len(imageArchives) > 0 {
 				if allPlatforms {
 					platforms = flags.NewPlatformsValue("*/*")
 				}
 
+				// Images from archives are added to the bundle registry directly and only need to be recorded
+				// in the bundle images config.
+				var archivesConfig config.ImagesConfig
+				if len(imageArchives) > 0 {
+					archivesConfig, err = pushImageArchives(imageArchives, platforms.GetSlice(), reg, out)
+					if err != nil {
+						return err
+					}
+					// Images pulled from the configs would silently replace the images from the archives.
+					conflicts := archiveImageConflicts(archivesConfig, imagesConfig, ociArtifactsConfig)
+					if len(conflicts) > 0 {
+						return fmt.Errorf(
+							"images from --image-archive are also configured to be pulled, remove them from one "+
+								"of the sources: %s",
+							strings.Join(conflicts, ", "),
+						)
+					}
+				}
+
+				parsedRegistryMirrors := make(map[string][]mirrors.Mirror, len(registryMirrors))
+				for _, m := range registryMirrors {
+					registryName, mirror, err := mirrors.ParseRegistryMirror(m)
+					if err != nil {
+						return err
+					}
+					parsedRegistryMirrors[registryName] = append(parsedRegistryMirrors[registryName], mirror)
+				}
+
+				var registriesConf *mirrors.RegistriesConf
+				if useRegistriesConf {
+					registriesConf, err = mirrors.LoadRegistriesConf(nil)
+					if err != nil {
+						return err
+					}
+				}
+
 				if err := PullImagesAndOCIArtifacts(
 					imagesConfig,
 					ociArtifactsConfig,
 					existingImagesConfig,
+					archivesConfig,
 					platforms,
 					imagePullConcurrency,
+					maxBandwidth.BytesPerSecond(),
+					parsedRegistryMirrors,
+					registriesConf,
 					reg,
 					tempDir,
 					out,
@@ -252,6 +371,7 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 					helmChartsConfig,
 					existingHelmChartsConfig,
 					helmChartsConfigFileAbs,
+					maxBandwidth.BytesPerSecond(),
 					reg,
 					tempDir,
 					cleaner,
@@ -261,6 +381,26 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 				}
 			}
 
+			if merge {
+				if err := garbageCollectBundle(tempDir, out); err != nil {
+					return err
+				}
+			}
+
+			if maxPartSizeBytes > 0 {
+				return archiveBundleInParts(tempDir, outputFile, maxPartSize, maxPartSizeBytes, out)
+			}
+
+			if encryption.Encrypt {
+				out.StartOperation(fmt.Sprintf("Archiving encrypted bundle to %s", outputFile))
+				if err := archive.ArchiveDirectoryEncrypted(tempDir, outputFile, recipients...); err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return fmt.Errorf("failed to create encrypted bundle tarball: %w", err)
+				}
+				out.EndOperationWithStatus(output.Success())
+				return nil
+			}
+
 			out.StartOperation(fmt.Sprintf("Archiving bundle to %s", outputFile))
 			if err := archive.ArchiveDirectory(tempDir, outputFile); err != nil {
 				out.EndOperationWithStatus(output.Failure())
@@ -279,7 +419,31 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 	cmd.Flags().StringVar(&ociArtifactsConfigFile, "oci-artifacts-file", "",
 		"File containing list of oci artifacts to create bundle from, "+
 			"either as YAML configuration or a simple list of images")
-	cmd.MarkFlagsOneRequired("images-file", "helm-charts-file", "oci-artifacts-file")
+	cmd.Flags().Var(&fromRegistry, "from-registry",
+		"Registry to create bundle from, including all images in the registry, or under the path if specified "+
+			"(e.g. registry.example.com/staging)")
+	cmd.Flags().StringSliceVar(&fromRegistryFilter.includeRepositories, "from-registry-include", nil,
+		"Glob patterns of repositories to include from --from-registry, relative to the registry path "+
+			"(default all repositories)")
+	cmd.Flags().StringSliceVar(&fromRegistryFilter.excludeRepositories, "from-registry-exclude", nil,
+		"Glob patterns of repositories to exclude from --from-registry, relative to the registry path")
+	cmd.Flags().StringSliceVar(&fromRegistryFilter.includeTags, "from-registry-include-tags", nil,
+		"Glob patterns of tags to include from --from-registry (default all tags)")
+	cmd.Flags().StringSliceVar(&fromRegistryFilter.excludeTags, "from-registry-exclude-tags", nil,
+		"Glob patterns of tags to exclude from --from-registry")
+	cmd.Flags().BoolVar(&fromRegistrySkipTLS, "from-registry-insecure-skip-tls-verify", false,
+		"Skip TLS verification of --from-registry (also use for non-TLS http registries)")
+	cmd.Flags().StringVar(&fromRegistryUsername, "from-registry-username", "",
+		"Username to use to log in to --from-registry")
+	cmd.Flags().StringVar(&fromRegistryPassword, "from-registry-password", "",
+		"Password to use to log in to --from-registry")
+	cmd.MarkFlagsRequiredTogether("from-registry-username", "from-registry-password")
+	cmd.Flags().StringSliceVar(&imageArchives, "image-archive", nil,
+		"Docker-save or OCI image layout tarball of images to include in the bundle. Can be specified multiple "+
+			"times or as a glob pattern. Images are added under the references embedded in the archive")
+	cmd.MarkFlagsOneRequired(
+		"images-file", "helm-charts-file", "oci-artifacts-file", "from-registry", "image-archive",
+	)
 	cmd.Flags().
 		Var(&platforms, "platform", "platforms to download images for (required format: <os>/<arch>[/<variant>])")
 	cmd.Flags().
@@ -292,22 +456,134 @@ func NewCommand( //nolint:gocyclo // TODO: Refactor this command to make it more
 	cmd.Flags().
 		BoolVar(&merge, "merge", false, "Merge new images into existing bundle file if it already exists")
 	cmd.MarkFlagsMutuallyExclusive("overwrite", "merge")
+	cmd.Flags().StringVar(&maxPartSize, "max-part-size", "",
+		"Split the bundle into tar archives of at most this size, e.g. 4GiB, named after --output-file with a "+
+			".partNNNN.tar suffix, plus a .parts.yaml index listing all parts and their checksums. "+
+			"Pass the index to other commands to use the parts as a single bundle")
+	cmd.MarkFlagsMutuallyExclusive("max-part-size", "merge")
+	encryption.AddFlags(cmd)
+	cmd.MarkFlagsMutuallyExclusive("encrypt", "merge")
+	cmd.MarkFlagsMutuallyExclusive("encrypt", "max-part-size")
 	cmd.Flags().
 		IntVar(&imagePullConcurrency, "image-pull-concurrency", 1, "Image pull concurrency")
+	cmd.Flags().Var(&maxBandwidth, "max-bandwidth",
+		"Maximum bandwidth to use across all concurrent image pulls and chart downloads, e.g. 50MiB/s "+
+			"(default unlimited)")
+	cmd.Flags().StringArrayVar(&registryMirrors, "registry-mirror", nil,
+		"Mirror to pull images for a registry from, specified as <registry>=<mirror>, "+
+			"e.g. docker.io=mirror.corp:5000. Can be repeated, mirrors are tried in order "+
+			"before falling back to the registry itself. Prefix the mirror with http:// to pull over plain HTTP")
+	cmd.Flags().BoolVar(&useRegistriesConf, "use-registries-conf", false,
+		"Pull images for registries without mirrors from the mirrors configured in containers registries.conf, "+
+			"and fail for images from registries blocked there")
 
 	return cmd
 }
 
+// archiveBundleInParts archives the bundle in dir to parts of at most maxPartSizeBytes. Parts of a previous bundle
+// written to the same output file that are not overwritten by the new parts are removed.
+func archiveBundleInParts(
+	dir, outputFile, maxPartSize string, maxPartSizeBytes int64, out output.Output,
+) error {
+	indexFile := archive.PartsIndexFile(outputFile)
+	previousIndex, err := archive.ReadPartsIndex(indexFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read parts of existing bundle: %w", err)
+	}
+
+	out.StartOperation(fmt.Sprintf("Archiving bundle to parts of at most %s indexed by %s", maxPartSize, indexFile))
+	index, err := archive.ArchiveDirectoryInParts(dir, outputFile, maxPartSizeBytes)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create bundle parts: %w", err)
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	for _, p := range previousIndex.Parts {
+		if slices.ContainsFunc(index.Parts, func(newPart archive.Part) bool { return newPart.Name == p.Name }) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(filepath.Dir(indexFile), p.Name)); err != nil &&
+			!errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove part of previous bundle: %w", err)
+		}
+	}
+
+	out.Infof("Bundle written to %d parts indexed by %s", len(index.Parts), indexFile)
+
+	return nil
+}
+
+// garbageCollectBundle removes the blobs and manifests from the bundle in dir that are no longer referenced by any
+// image or Helm chart in the bundle configs, e.g. the previous content of tags pulled again when merging.
+func garbageCollectBundle(dir string, out output.Output) error {
+	out.StartOperation("Removing unreferenced blobs from bundle")
+	imagesCfg, err := config.ParseImagesConfigFile(filepath.Join(dir, "images.yaml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to parse bundle config: %w", err)
+	}
+	chartsCfg, err := config.ParseHelmChartsConfigFile(filepath.Join(dir, "charts.yaml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to parse bundle config: %w", err)
+	}
+	keepTags := bundleRepositoryTags(imagesCfg, chartsCfg)
+
+	result, err := registry.GarbageCollect(dir, keepTags)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to remove unreferenced blobs from bundle: %w", err)
+	}
+	out.EndOperationWithStatus(output.Success())
+	out.Infof(
+		"Removed %d unreferenced blobs and %d tags no longer in the bundle configs, reclaiming %s",
+		result.BlobsRemoved, result.TagsRemoved, units.BytesSize(float64(result.BytesReclaimed)),
+	)
+
+	return nil
+}
+
+// bundleRepositoryTags returns the tags of the images and Helm charts in the bundle configs, keyed by the name of
+// the repository they are stored in in the bundle. Images are stored under their name without the registry, so
+// images with the same name from different registries share a repository and all their tags are returned for it.
+func bundleRepositoryTags(imagesCfg config.ImagesConfig, chartsCfg config.HelmChartsConfig) map[string][]string {
+	repositoryTags := map[string][]string{}
+	for _, registryConfig := range imagesCfg {
+		for imageName, tags := range registryConfig.Images {
+			repositoryTags[imageName] = append(repositoryTags[imageName], tags...)
+		}
+	}
+	for _, repoConfig := range chartsCfg.Repositories {
+		for chartName, versions := range repoConfig.Charts {
+			repository := "charts/" + chartName
+			for _, version := range versions {
+				// Helm replaces + in chart versions with _ in OCI tags.
+				repositoryTags[repository] = append(repositoryTags[repository], strings.ReplaceAll(version, "+", "_"))
+			}
+		}
+	}
+	return repositoryTags
+}
+
 func PullImagesAndOCIArtifacts(
 	imagesConfig config.ImagesConfig,
 	ociArtifactsConfig config.ImagesConfig,
 	existingImagesConfig config.ImagesConfig,
+	archivesConfig config.ImagesConfig,
 	platforms flags.Platforms,
 	imagePullConcurrency int,
+	maxBandwidthBytesPerSecond int64,
+	registryMirrors map[string][]mirrors.Mirror,
+	registriesConf *mirrors.RegistriesConf,
 	reg *registry.Registry,
 	outputDir string,
 	out output.Output,
 ) error {
+	// A single limiter is shared by all pulls so that the bandwidth limit applies to the total of all
+	// concurrent pulls.
+	bandwidthLimiter := httputils.NewBandwidthLimiter(maxBandwidthBytesPerSecond)
+
 	pullGauge := &output.ProgressGauge{}
 	pullGauge.SetCapacity(imagesConfig.TotalImages() + ociArtifactsConfig.TotalImages())
 	pullGauge.SetStatus("Pulling requested images")
@@ -319,6 +595,9 @@ func PullImagesAndOCIArtifacts(
 			imagesConfig,
 			platforms,
 			imagePullConcurrency,
+			bandwidthLimiter,
+			registryMirrors,
+			registriesConf,
 			reg,
 			progressFn,
 			false,
@@ -333,6 +612,9 @@ func PullImagesAndOCIArtifacts(
 			ociArtifactsConfig,
 			platforms,
 			imagePullConcurrency,
+			bandwidthLimiter,
+			registryMirrors,
+			registriesConf,
 			reg,
 			progressFn,
 			true,
@@ -347,6 +629,7 @@ func PullImagesAndOCIArtifacts(
 		imagesConfig,
 		ociArtifactsConfig,
 		existingImagesConfig,
+		archivesConfig,
 	); err != nil {
 		out.EndOperationWithStatus(output.Failure())
 		return err
@@ -360,6 +643,9 @@ func pullImages(
 	cfg config.ImagesConfig,
 	platforms flags.Platforms,
 	imagePullConcurrency int,
+	bandwidthLimiter *rate.Limiter,
+	registryMirrors map[string][]mirrors.Mirror,
+	registriesConf *mirrors.RegistriesConf,
 	reg *registry.Registry,
 	progressFn func(),
 	isOCIArtifact bool,
@@ -375,7 +661,7 @@ func pullImages(
 		return fmt.Errorf("error configuring TLS for destination registry: %w", err)
 	}
 	defer func() {
-		if tr, ok := destTLSRoundTripper.(*http.Transport); ok {
+		if tr, ok := destTLSRoundTripper.(interface{ CloseIdleConnections() }); ok {
 			tr.CloseIdleConnections()
 		}
 	}()
@@ -385,6 +671,43 @@ func pullImages(
 		remote.WithUserAgent(utils.Useragent()),
 	}
 
+	// Remote options for mirrors are shared by all registries mirrored by the same mirror host.
+	mirrorRemoteOpts := map[mirrors.Mirror][]remote.Option{}
+	var mirrorRoundTrippers []http.RoundTripper
+	defer func() {
+		for _, rt := range mirrorRoundTrippers {
+			if tr, ok := rt.(interface{ CloseIdleConnections() }); ok {
+				tr.CloseIdleConnections()
+			}
+		}
+	}()
+	remoteOptsForMirror := func(m mirrors.Mirror) ([]remote.Option, error) {
+		m.Location = m.Host()
+		if opts, ok := mirrorRemoteOpts[m]; ok {
+			return opts, nil
+		}
+		mirrorTLSRoundTripper, err := httputils.TLSConfiguredRoundTripper(
+			remote.DefaultTransport,
+			m.Location,
+			m.Insecure,
+			"",
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring TLS for registry mirror: %w", err)
+		}
+		mirrorRoundTrippers = append(mirrorRoundTrippers, mirrorTLSRoundTripper)
+		opts := []remote.Option{
+			remote.WithTransport(
+				httputils.BandwidthLimitedRoundTripper(mirrorTLSRoundTripper, bandwidthLimiter),
+			),
+			remote.WithAuthFromKeychain(authn.DefaultKeychain),
+			remote.WithContext(egCtx),
+			remote.WithUserAgent(utils.Useragent()),
+		}
+		mirrorRemoteOpts[m] = opts
+		return opts, nil
+	}
+
 	for registryIdx := range regNames {
 		registryName := regNames[registryIdx]
 
@@ -399,6 +722,7 @@ func pullImages(
 		if err != nil {
 			return fmt.Errorf("error configuring TLS for source registry: %w", err)
 		}
+		sourceTLSRoundTripper = httputils.BandwidthLimitedRoundTripper(sourceTLSRoundTripper, bandwidthLimiter)
 
 		keychain := authn.NewMultiKeychain(
 			authn.NewKeychainFromHelper(
@@ -414,6 +738,16 @@ func pullImages(
 			remote.WithUserAgent(utils.Useragent()),
 		}
 
+		var registryConfigMirrors []mirrors.Mirror
+		for _, m := range registryConfig.Mirrors {
+			mirror, err := mirrors.ParseMirror(m)
+			if err != nil {
+				return fmt.Errorf("invalid mirror for registry %s: %w", registryName, err)
+			}
+			registryConfigMirrors = append(registryConfigMirrors, mirror)
+		}
+		registryConfigMirrors = append(registryMirrors[registryName], registryConfigMirrors...)
+
 		platformsStrings := platforms.GetSlice()
 
 		// Sort images for deterministic ordering.
@@ -429,15 +763,34 @@ func pullImages(
 			for j := range imageTags {
 				imageTag := imageTags[j]
 
+				srcImageName := fmt.Sprintf(
+					"%s/%s:%s",
+					registryName,
+					imageName,
+					imageTag,
+				)
+				sources, err := mirrors.PullSources(srcImageName, registryConfigMirrors, registriesConf)
+				if err != nil {
+					return err
+				}
+				sourcesRemoteOpts := make([][]remote.Option, len(sources))
+				for i, src := range sources {
+					if !src.Mirror {
+						sourcesRemoteOpts[i] = sourceRemoteOpts
+						sources[i].Insecure = src.Insecure ||
+							(registryConfig.TLSVerify != nil && !*registryConfig.TLSVerify)
+						continue
+					}
+					if sourcesRemoteOpts[i], err = remoteOptsForMirror(
+						mirrors.Mirror{Location: src.Reference, Insecure: src.Insecure},
+					); err != nil {
+						return err
+					}
+				}
+
 				eg.Go(func() error {
 					defer wg.Done()
 
-					srcImageName := fmt.Sprintf(
-						"%s/%s:%s",
-						registryName,
-						imageName,
-						imageTag,
-					)
 					destImageName := fmt.Sprintf(
 						"%s/%s:%s",
 						reg.Address(),
@@ -449,27 +802,30 @@ func pullImages(
 						return err
 					}
 
-					var image remote.Taggable
-					if isOCIArtifact {
-						image, err = images.OCIArtifactImage(
-							srcImageName,
-							sourceRemoteOpts...,
-						)
-					} else {
-						image, err = images.ManifestListForImage(
-							srcImageName,
+					// Images are pulled from the first source that succeeds, falling back to the next source
+					// on failure. The origin registry is always the last source.
+					for i, src := range sources {
+						err = pullImage(
+							src,
+							sourcesRemoteOpts[i],
+							ref,
+							destRemoteOpts,
 							platformsStrings,
-							sourceRemoteOpts...,
+							isOCIArtifact,
 						)
+						if err == nil {
+							break
+						}
+						if src.Mirror {
+							logs.Warn.Printf(
+								"failed to pull image %q from mirror, trying next source: %v", src.Reference, err,
+							)
+						}
 					}
 					if err != nil {
 						return fmt.Errorf("failed to get image %q: %w", srcImageName, err)
 					}
 
-					if err := remote.Push(ref, image, destRemoteOpts...); err != nil {
-						return err
-					}
-
 					progressFn()
 
 					return nil
@@ -480,7 +836,7 @@ func pullImages(
 		go func() {
 			wg.Wait()
 
-			if tr, ok := sourceTLSRoundTripper.(*http.Transport); ok {
+			if tr, ok := sourceTLSRoundTripper.(interface{ CloseIdleConnections() }); ok {
 				tr.CloseIdleConnections()
 			}
 		}()
@@ -493,9 +849,41 @@ func pullImages(
 	return nil
 }
 
+// pullImage pulls the image from the source and pushes it to the destination reference.
+func pullImage(
+	src mirrors.Source,
+	srcRemoteOpts []remote.Option,
+	destRef name.Reference,
+	destRemoteOpts []remote.Option,
+	platforms []string,
+	isOCIArtifact bool,
+) error {
+	var nameOpts []name.Option
+	if src.Insecure {
+		nameOpts = append(nameOpts, name.Insecure)
+	}
+	srcRef, err := name.ParseReference(src.Reference, nameOpts...)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %q: %w", src.Reference, err)
+	}
+
+	var image remote.Taggable
+	if isOCIArtifact {
+		image, err = images.OCIArtifactForReference(srcRef, srcRemoteOpts...)
+	} else {
+		image, err = images.ManifestListForReference(srcRef, platforms, srcRemoteOpts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	return remote.Push(destRef, image, destRemoteOpts...)
+}
+
 func pullCharts(
 	cfg, existingCfg config.HelmChartsConfig,
 	helmChartsConfigFileAbs string,
+	maxBandwidthBytesPerSecond int64,
 	reg *registry.Registry,
 	outputDir string,
 	cleaner cleanup.Cleaner,
@@ -516,6 +904,7 @@ func pullCharts(
 
 	helmClient, helmCleanup := helm.NewClient(out)
 	cleaner.AddCleanupFn(func() { _ = helmCleanup() })
+	helmClient.WithBandwidthLimiter(httputils.NewBandwidthLimiter(maxBandwidthBytesPerSecond))
 
 	ociAddress := fmt.Sprintf("%s://%s/charts", helm.OCIScheme, reg.Address())
 
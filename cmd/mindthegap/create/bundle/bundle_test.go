@@ -0,0 +1,126 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/images/httputils"
+	"github.com/mesosphere/mindthegap/images/mirrors"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(srv.Close)
+	return srv.Listener.Addr().String()
+}
+
+func newBundleRegistry(t *testing.T) *registry.Registry {
+	t.Helper()
+	return bundletest.StartRegistry(t, registry.FilesystemStorage(t.TempDir()))
+}
+
+func TestPullImagesFromMirrors(t *testing.T) {
+	t.Parallel()
+
+	origin := newTestRegistry(t)
+	emptyMirror := newTestRegistry(t)
+	mirror := newTestRegistry(t)
+
+	mirrored := bundletest.NewPlatformImage(t, "amd64")
+	require.NoError(t, remote.Write(bundletest.ParseReference(t, mirror+"/app:v1"), mirrored))
+	originOnly := bundletest.NewPlatformImage(t, "amd64")
+	require.NoError(t, remote.Write(bundletest.ParseReference(t, origin+"/app:v2"), originOnly))
+
+	reg := newBundleRegistry(t)
+	err := pullImages(
+		config.ImagesConfig{
+			origin: {
+				Images:    map[string][]string{"app": {"v1", "v2"}},
+				TLSVerify: new(false),
+				Mirrors:   []string{"http://" + mirror},
+			},
+		},
+		flags.NewPlatformsValue("linux/amd64"),
+		1,
+		httputils.NewBandwidthLimiter(0),
+		map[string][]mirrors.Mirror{origin: {{Location: emptyMirror, Insecure: true}}},
+		nil,
+		reg,
+		func() {},
+		false,
+	)
+	require.NoError(t, err)
+
+	// Images are stored in the bundle under their original names, regardless of the source they were pulled from.
+	for _, tc := range []struct {
+		tag  string
+		want string
+	}{
+		{tag: "v1", want: bundletest.Digest(t, mirrored).String()},
+		{tag: "v2", want: bundletest.Digest(t, originOnly).String()},
+	} {
+		idx, err := remote.Index(bundletest.ParseReference(t, reg.Address()+"/app:"+tc.tag))
+		require.NoError(t, err)
+		manifest, err := idx.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Manifests, 1, tc.tag)
+		assert.Equal(t, tc.want, manifest.Manifests[0].Digest.String(), tc.tag)
+	}
+}
+
+func TestPullImagesAllSourcesFail(t *testing.T) {
+	t.Parallel()
+
+	origin := newTestRegistry(t)
+	mirror := newTestRegistry(t)
+
+	reg := newBundleRegistry(t)
+	err := pullImages(
+		config.ImagesConfig{
+			origin: {Images: map[string][]string{"app": {"v1"}}, TLSVerify: new(false)},
+		},
+		flags.NewPlatformsValue("linux/amd64"),
+		1,
+		httputils.NewBandwidthLimiter(0),
+		map[string][]mirrors.Mirror{origin: {{Location: mirror, Insecure: true}}},
+		nil,
+		reg,
+		func() {},
+		false,
+	)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), `failed to get image "`+origin+`/app:v1"`), err.Error())
+}
+
+func TestBundleRepositoryTags(t *testing.T) {
+	t.Parallel()
+
+	got := bundleRepositoryTags(
+		config.ImagesConfig{
+			"docker.io": {Images: map[string][]string{"library/nginx": {"1.25"}, "team/app": {"v1"}}},
+			// Images with the same name from different registries are stored in the same repository.
+			"mirror.example.com": {Images: map[string][]string{"library/nginx": {"1.26"}}},
+		},
+		config.HelmChartsConfig{Repositories: map[string]config.HelmRepositorySyncConfig{
+			"podinfo": {Charts: map[string][]string{"podinfo": {"6.1.0", "6.2.0+build.1"}}},
+		}},
+	)
+	assert.ElementsMatch(t, []string{"1.25", "1.26"}, got["library/nginx"])
+	assert.Equal(t, []string{"v1"}, got["team/app"])
+	assert.ElementsMatch(t, []string{"6.1.0", "6.2.0_build.1"}, got["charts/podinfo"])
+	assert.Len(t, got, 3)
+}
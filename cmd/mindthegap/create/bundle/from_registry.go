@@ -0,0 +1,165 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/containers/image/v5/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/images/authnhelpers"
+	"github.com/mesosphere/mindthegap/images/httputils"
+)
+
+// catalogFilter filters the repositories and tags found in a registry catalog. Filters are glob patterns as
+// supported by path.Match. Repositories are matched relative to the registry prefix. An empty include list
+// includes everything, and excludes take precedence over includes.
+type catalogFilter struct {
+	includeRepositories []string
+	excludeRepositories []string
+	includeTags         []string
+	excludeTags         []string
+}
+
+func (f catalogFilter) validate() error {
+	for _, patterns := range [][]string{
+		f.includeRepositories, f.excludeRepositories, f.includeTags, f.excludeTags,
+	} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid filter pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f catalogFilter) includeRepository(repository string) bool {
+	return matchesFilters(repository, f.includeRepositories, f.excludeRepositories)
+}
+
+func (f catalogFilter) includeTag(tag string) bool {
+	return matchesFilters(tag, f.includeTags, f.excludeTags)
+}
+
+func matchesFilters(s string, includes, excludes []string) bool {
+	for _, p := range excludes {
+		if matched, _ := path.Match(p, s); matched {
+			return false
+		}
+	}
+	if len(includes) == 0 {
+		return true
+	}
+	for _, p := range includes {
+		if matched, _ := path.Match(p, s); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// imagesConfigFromRegistry builds an images config containing all images in the registry under the registry URI's
+// path that match the filter, by walking the registry catalog and the tags of each repository.
+func imagesConfigFromRegistry(
+	ctx context.Context,
+	registryURI *flags.RegistryURI,
+	skipTLSVerify bool,
+	credentials *types.DockerAuthConfig,
+	filter catalogFilter,
+) (config.ImagesConfig, error) {
+	skipTLSVerify = flags.SkipTLSVerify(skipTLSVerify, registryURI)
+
+	var nameOpts []name.Option
+	if skipTLSVerify {
+		nameOpts = append(nameOpts, name.Insecure)
+	}
+	reg, err := name.NewRegistry(registryURI.Host(), nameOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry %q: %w", registryURI.Host(), err)
+	}
+
+	transport, err := httputils.TLSConfiguredRoundTripper(
+		remote.DefaultTransport,
+		registryURI.Host(),
+		skipTLSVerify,
+		"",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring TLS for source registry: %w", err)
+	}
+	defer func() {
+		if tr, ok := transport.(interface{ CloseIdleConnections() }); ok {
+			tr.CloseIdleConnections()
+		}
+	}()
+
+	remoteOpts := []remote.Option{
+		remote.WithTransport(transport),
+		remote.WithAuthFromKeychain(authn.NewMultiKeychain(
+			authn.NewKeychainFromHelper(authnhelpers.NewStaticHelper(registryURI.Host(), credentials)),
+			authn.DefaultKeychain,
+		)),
+		remote.WithContext(ctx),
+		remote.WithUserAgent(utils.Useragent()),
+	}
+
+	repositories, err := remote.Catalog(ctx, reg, remoteOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories in registry %s: %w", registryURI.Host(), err)
+	}
+
+	prefix := strings.Trim(registryURI.Path(), "/")
+	imgs := map[string][]string{}
+	for _, repository := range repositories {
+		relativeRepository := repository
+		if prefix != "" {
+			var ok bool
+			relativeRepository, ok = strings.CutPrefix(repository, prefix+"/")
+			if !ok {
+				continue
+			}
+		}
+		if !filter.includeRepository(relativeRepository) {
+			continue
+		}
+
+		repo := reg.Repo(repository)
+		tags, err := remote.List(repo, remoteOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for repository %s: %w", repo, err)
+		}
+
+		var includedTags []string
+		for _, tag := range tags {
+			if filter.includeTag(tag) {
+				includedTags = append(includedTags, tag)
+			}
+		}
+		if len(includedTags) > 0 {
+			imgs[repository] = includedTags
+		}
+	}
+
+	if len(imgs) == 0 {
+		return nil, fmt.Errorf("no images matching the filters found in registry %s", registryURI.Address())
+	}
+
+	return config.ImagesConfig{
+		registryURI.Host(): config.RegistrySyncConfig{
+			Images:      imgs,
+			TLSVerify:   new(!skipTLSVerify),
+			Credentials: credentials,
+		},
+	}, nil
+}
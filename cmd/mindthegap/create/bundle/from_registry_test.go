@@ -0,0 +1,106 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+func TestImagesConfigFromRegistry(t *testing.T) {
+	t.Parallel()
+
+	reg := newBundleRegistry(t)
+	img := bundletest.NewPlatformImage(t, "amd64")
+	for _, ref := range []string{
+		"staging/app:v1",
+		"staging/app:v2",
+		"staging/app:v2-rc1",
+		"staging/tools/debug:latest",
+		"staging/internal:v1",
+		"production/app:v1",
+	} {
+		require.NoError(t, remote.Write(bundletest.ParseReference(t, reg.Address()+"/"+ref), img))
+	}
+
+	tests := []struct {
+		name   string
+		uri    string
+		filter catalogFilter
+		want   map[string][]string
+	}{{
+		name: "all repositories",
+		uri:  reg.Address(),
+		want: map[string][]string{
+			"staging/app":         {"v1", "v2", "v2-rc1"},
+			"staging/tools/debug": {"latest"},
+			"staging/internal":    {"v1"},
+			"production/app":      {"v1"},
+		},
+	}, {
+		name: "prefix with filters",
+		uri:  "http://" + reg.Address() + "/staging",
+		filter: catalogFilter{
+			includeRepositories: []string{"app", "tools/*"},
+			excludeRepositories: []string{"internal"},
+			excludeTags:         []string{"*-rc*"},
+		},
+		want: map[string][]string{
+			"staging/app":         {"v1", "v2"},
+			"staging/tools/debug": {"latest"},
+		},
+	}, {
+		name:   "tag includes",
+		uri:    reg.Address() + "/staging/",
+		filter: catalogFilter{includeTags: []string{"v*"}},
+		want: map[string][]string{
+			"staging/app":      {"v1", "v2", "v2-rc1"},
+			"staging/internal": {"v1"},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			uri, err := flags.NewRegistryURI(tt.uri)
+			require.NoError(t, err)
+			cfg, err := imagesConfigFromRegistry(context.Background(), uri, true, nil, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, config.ImagesConfig{
+				reg.Address(): {Images: tt.want, TLSVerify: new(false)},
+			}, cfg)
+		})
+	}
+}
+
+func TestImagesConfigFromRegistryNoMatches(t *testing.T) {
+	t.Parallel()
+
+	reg := newBundleRegistry(t)
+	require.NoError(t, remote.Write(bundletest.ParseReference(t, reg.Address()+"/app:v1"), bundletest.NewPlatformImage(t, "amd64")))
+
+	uri, err := flags.NewRegistryURI(reg.Address() + "/missing")
+	require.NoError(t, err)
+	_, err = imagesConfigFromRegistry(context.Background(), uri, true, nil, catalogFilter{})
+	require.ErrorContains(t, err, "no images matching the filters found in registry "+reg.Address()+"/missing")
+}
+
+func TestCatalogFilterValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, catalogFilter{includeRepositories: []string{"app/*"}}.validate())
+	require.ErrorContains(
+		t,
+		catalogFilter{excludeTags: []string{"[v"}}.validate(),
+		`invalid filter pattern "[v"`,
+	)
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/images"
+	"github.com/mesosphere/mindthegap/images/archive"
+	"github.com/mesosphere/mindthegap/images/httputils"
+)
+
+// pushImageArchives writes the images in the docker-save and OCI layout archives to the bundle registry, retaining
+// only the requested platforms. Images are stored under the references embedded in the archives. Returns the
+// images config describing the written images.
+func pushImageArchives(
+	archiveFiles []string,
+	platforms []string,
+	reg *registry.Registry,
+	out output.Output,
+) (config.ImagesConfig, error) {
+	paths, err := utils.FilesWithGlobs(archiveFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	destTLSRoundTripper, err := httputils.InsecureTLSRoundTripper(remote.DefaultTransport)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring TLS for destination registry: %w", err)
+	}
+	defer func() {
+		if tr, ok := destTLSRoundTripper.(interface{ CloseIdleConnections() }); ok {
+			tr.CloseIdleConnections()
+		}
+	}()
+	destRemoteOpts := []remote.Option{
+		remote.WithTransport(destTLSRoundTripper),
+		remote.WithUserAgent(utils.Useragent()),
+	}
+
+	cfg := config.ImagesConfig{}
+	for _, p := range paths {
+		out.StartOperation(fmt.Sprintf("Adding images from archive %s", p))
+		if err := pushImageArchive(p, platforms, reg, destRemoteOpts, cfg); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return nil, err
+		}
+		out.EndOperationWithStatus(output.Success())
+	}
+
+	return cfg, nil
+}
+
+func pushImageArchive(
+	archivePath string,
+	platforms []string,
+	reg *registry.Registry,
+	destRemoteOpts []remote.Option,
+	cfg config.ImagesConfig,
+) error {
+	a, err := archive.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	entries, err := a.Entries()
+	if err != nil {
+		return fmt.Errorf("failed to read entries from %s: %w", archivePath, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Ref == nil {
+			return fmt.Errorf(
+				"image in archive %s has no embedded reference: tag the image before saving the archive",
+				archivePath,
+			)
+		}
+
+		normalized, err := reference.ParseNormalizedNamed(entry.Ref.Name())
+		if err != nil {
+			return fmt.Errorf("invalid image reference %q in archive %s: %w", entry.Ref, archivePath, err)
+		}
+		registryName := reference.Domain(normalized)
+		imageName := reference.Path(normalized)
+		imageTag := "latest"
+		if tagged, ok := normalized.(reference.Tagged); ok {
+			imageTag = tagged.Tag()
+		}
+
+		var image remote.Taggable
+		switch {
+		case entry.Image != nil:
+			image, err = images.IndexForSinglePlatformImage(entry.Ref, entry.Image, platforms...)
+		case entry.Index != nil:
+			image, err = images.RetainOnlyRequestedPlatformsInIndex(entry.Index, platforms...)
+		default:
+			return fmt.Errorf("archive %s: entry has neither image nor index", archivePath)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get image %q from archive %s: %w", entry.Ref, archivePath, err)
+		}
+
+		destRef, err := name.ParseReference(
+			fmt.Sprintf("%s/%s:%s", reg.Address(), imageName, imageTag),
+			name.StrictValidation,
+		)
+		if err != nil {
+			return err
+		}
+		if err := remote.Push(destRef, image, destRemoteOpts...); err != nil {
+			return fmt.Errorf("failed to write image %q from archive %s: %w", entry.Ref, archivePath, err)
+		}
+
+		registryConfig, ok := cfg[registryName]
+		if !ok {
+			registryConfig = config.RegistrySyncConfig{Images: map[string][]string{}}
+		}
+		if !slices.Contains(registryConfig.Images[imageName], imageTag) {
+			registryConfig.Images[imageName] = append(registryConfig.Images[imageName], imageTag)
+		}
+		cfg[registryName] = registryConfig
+	}
+
+	return nil
+}
+
+// archiveImageConflicts returns the images from archives that would be overwritten in the bundle registry by images
+// pulled for the given configs. Images are stored under their name without the registry, so an image from an archive
+// conflicts with a pulled image with the same name and tag from any registry.
+func archiveImageConflicts(archivesCfg config.ImagesConfig, pulledCfgs ...config.ImagesConfig) []string {
+	pulled := map[string]struct{}{}
+	for _, cfg := range pulledCfgs {
+		for _, registryConfig := range cfg {
+			for imageName, tags := range registryConfig.Images {
+				for _, tag := range tags {
+					pulled[imageName+":"+tag] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var conflicts []string
+	for _, registryName := range archivesCfg.SortedRegistryNames() {
+		for imageName, tags := range archivesCfg[registryName].Images {
+			for _, tag := range tags {
+				if _, ok := pulled[imageName+":"+tag]; ok {
+					conflicts = append(conflicts, fmt.Sprintf("%s/%s:%s", registryName, imageName, tag))
+				}
+			}
+		}
+	}
+	slices.Sort(conflicts)
+	return conflicts
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/images/archive/testutil"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+func TestPushImageArchives(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	dockerImage := bundletest.NewPlatformImage(t, "amd64")
+	require.NoError(t, tarball.MultiWriteToFile(
+		filepath.Join(dir, "docker.tar"),
+		map[name.Tag]v1.Image{
+			name.MustParseReference("docker.io/library/nginx:local").(name.Tag): dockerImage,
+			name.MustParseReference("example.com/team/app:v1").(name.Tag):       dockerImage,
+		},
+	))
+
+	amd64Image := bundletest.NewPlatformImage(t, "amd64")
+	arm64Image := bundletest.NewPlatformImage(t, "arm64")
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        amd64Image,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64Image,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}},
+		},
+	)
+	layoutDir := t.TempDir()
+	p, err := layout.Write(layoutDir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendIndex(index, layout.WithAnnotations(map[string]string{
+		"org.opencontainers.image.ref.name": "example.com/team/multiarch:v2",
+	})))
+	testutil.TarLayoutDir(t, layoutDir, filepath.Join(dir, "oci.tar"))
+
+	reg := newBundleRegistry(t)
+	buf := &bytes.Buffer{}
+	cfg, err := pushImageArchives(
+		[]string{filepath.Join(dir, "*.tar")},
+		[]string{"linux/amd64"},
+		reg,
+		output.NewNonInteractiveShell(buf, buf, 0),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, config.ImagesConfig{
+		"docker.io": {Images: map[string][]string{"library/nginx": {"local"}}},
+		"example.com": {Images: map[string][]string{
+			"team/app":       {"v1"},
+			"team/multiarch": {"v2"},
+		}},
+	}, cfg)
+
+	for ref, want := range map[string][]v1.Image{
+		"library/nginx:local": {dockerImage},
+		"team/app:v1":         {dockerImage},
+		"team/multiarch:v2":   {amd64Image},
+	} {
+		idx, err := remote.Index(bundletest.ParseReference(t, reg.Address()+"/"+ref))
+		require.NoError(t, err, ref)
+		manifest, err := idx.IndexManifest()
+		require.NoError(t, err, ref)
+		require.Len(t, manifest.Manifests, len(want), ref)
+		for i, img := range want {
+			assert.Equal(t, bundletest.Digest(t, img).String(), manifest.Manifests[i].Digest.String(), ref)
+		}
+	}
+}
+
+func TestPushImageArchivesWithoutReference(t *testing.T) {
+	t.Parallel()
+
+	archivePath := filepath.Join(t.TempDir(), "oci.tar")
+	testutil.BuildOCIArchive(t, archivePath, "")
+
+	buf := &bytes.Buffer{}
+	_, err := pushImageArchives(
+		[]string{archivePath},
+		[]string{"*/*"},
+		newBundleRegistry(t),
+		output.NewNonInteractiveShell(buf, buf, 0),
+	)
+	require.ErrorContains(t, err, "has no embedded reference")
+}
+
+func TestArchiveImageConflicts(t *testing.T) {
+	t.Parallel()
+
+	archivesCfg := config.ImagesConfig{
+		"docker.io": {Images: map[string][]string{"team/app": {"v1", "v2"}, "team/db": {"v1"}}},
+	}
+
+	assert.Empty(t, archiveImageConflicts(archivesCfg, config.ImagesConfig{
+		"docker.io": {Images: map[string][]string{"team/app": {"v3"}, "team/web": {"v1"}}},
+	}))
+
+	// Images from different registries are stored in the same repository in the bundle, so they conflict too.
+	assert.Equal(t, []string{"docker.io/team/app:v2", "docker.io/team/db:v1"}, archiveImageConflicts(
+		archivesCfg,
+		config.ImagesConfig{"docker.io": {Images: map[string][]string{"team/app": {"v2"}}}},
+		config.ImagesConfig{"ghcr.io": {Images: map[string][]string{"team/db": {"v1"}}}},
+	))
+}
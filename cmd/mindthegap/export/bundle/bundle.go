@@ -0,0 +1,446 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/cleanup"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/images"
+	imagearchive "github.com/mesosphere/mindthegap/images/archive"
+	"github.com/mesosphere/mindthegap/images/httputils"
+)
+
+type exportFormat enumflag.Flag
+
+const (
+	OCIArchive exportFormat = iota
+	OCILayout
+	DockerArchive
+)
+
+var exportFormats = map[exportFormat][]string{
+	OCIArchive:    {"oci-archive"},
+	OCILayout:     {"oci-layout"},
+	DockerArchive: {"docker-archive"},
+}
+
+func NewCommand(out output.Output) *cobra.Command {
+	var (
+		bundleFiles        []string
+		decryptionKeyFiles []string
+		format             exportFormat
+		outputPath         string
+		overwrite          bool
+		imagePatterns      []string
+		platforms          = flags.NewPlatformsValue()
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Export images from bundles to an OCI image layout, OCI archive or docker archive",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.ValidateRequiredFlags(); err != nil {
+				return err
+			}
+
+			if err := flags.ValidateFlagsThatRequireValues(cmd, "bundle", "output"); err != nil {
+				return err
+			}
+
+			for _, p := range imagePatterns {
+				if _, err := path.Match(p, ""); err != nil {
+					return fmt.Errorf("invalid image pattern %q: %w", p, err)
+				}
+			}
+
+			if format == DockerArchive && len(platforms.GetSlice()) > 1 {
+				return errors.New("only a single platform can be exported to a docker archive")
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundleFiles, err := utils.FilesWithGlobs(bundleFiles)
+			if err != nil {
+				return err
+			}
+			bundleFiles, err = utils.ExpandBundleParts(out, bundleFiles)
+			if err != nil {
+				return err
+			}
+
+			return exportBundles(
+				out,
+				bundleFiles,
+				decryptionKeyFiles,
+				format,
+				outputPath,
+				overwrite,
+				imagePatterns,
+				platforms.GetSlice(),
+			)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&bundleFiles, "bundle", nil,
+		"Bundle to export images from. Can also be a glob pattern.")
+	_ = cmd.MarkFlagRequired("bundle")
+	cmd.Flags().StringSliceVar(&decryptionKeyFiles, "decrypt-key", nil,
+		"File containing age private keys (AGE-SECRET-KEY-1...) or a passphrase to decrypt encrypted bundles with. "+
+			"Can be specified multiple times")
+	cmd.Flags().Var(
+		enumflag.New(&format, "string", exportFormats, enumflag.EnumCaseSensitive),
+		"format",
+		`format to export to: one of "oci-archive", "oci-layout" or "docker-archive"`,
+	)
+	cmd.Flags().StringVar(&outputPath, "output", "",
+		"File to write the archive to, or directory to write the OCI image layout to")
+	_ = cmd.MarkFlagRequired("output")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite output if it already exists")
+	cmd.Flags().StringSliceVar(&imagePatterns, "image", nil,
+		"Glob patterns of images to export, matched against <registry>/<image>:<tag> and <registry>/<image> "+
+			"(default all images)")
+	cmd.Flags().Var(&platforms, "platform",
+		"platforms to export images for (required format: <os>/<arch>[/<variant>]). Defaults to all platforms, "+
+			"or the current platform for docker archives which can only contain a single platform")
+
+	return cmd
+}
+
+func exportBundles(
+	out output.Output,
+	bundleFiles []string,
+	decryptionKeyFiles []string,
+	format exportFormat,
+	outputPath string,
+	overwrite bool,
+	imagePatterns []string,
+	platforms []string,
+) error {
+	if err := checkOutput(outputPath, overwrite); err != nil {
+		return err
+	}
+
+	cleaner := cleanup.NewCleaner()
+	defer cleaner.Cleanup()
+
+	out.StartOperation("Creating temporary directory")
+	tempDir, err := os.MkdirTemp("", ".export-bundle-*")
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleaner.AddCleanupFn(func() { _ = os.RemoveAll(tempDir) })
+	out.EndOperationWithStatus(output.Success())
+
+	cfg, _, err := utils.ExtractConfigs(tempDir, out, decryptionKeyFiles, bundleFiles...)
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return errors.New(
+			"no bundle configuration(s) found: please check that you have specified valid air-gapped bundle(s)",
+		)
+	}
+
+	out.StartOperation("Starting temporary Docker registry")
+	storage, err := registry.ArchiveStorage("", decryptionKeyFiles, bundleFiles...)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create storage for Docker registry from supplied bundles: %w", err)
+	}
+	reg, err := registry.NewRegistry(registry.Config{Storage: storage, ReadOnly: true})
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create local Docker registry: %w", err)
+	}
+	go func() {
+		if err := reg.ListenAndServe(output.NewOutputLogr(out)); err != nil {
+			out.Error(err, "error serving Docker registry")
+			os.Exit(2)
+		}
+	}()
+	defer func() { _ = reg.Shutdown(context.Background()) }()
+	out.EndOperationWithStatus(output.Success())
+
+	if format == DockerArchive && len(platforms) == 0 {
+		platforms = []string{runtime.GOOS + "/" + runtime.GOARCH}
+	}
+
+	entries, err := bundleEntries(out, reg, *cfg, imagePatterns, platforms, format == DockerArchive)
+	if err != nil {
+		return err
+	}
+
+	out.StartOperation(fmt.Sprintf("Writing %d image(s) to %s", len(entries), outputPath))
+	// Write next to the output so that it can be renamed into place once complete, leaving any existing output
+	// untouched if the export fails.
+	outputDir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create output directory %s: %w", outputDir, err)
+	}
+	stagingDir, err := os.MkdirTemp(outputDir, "."+filepath.Base(outputPath)+"-*")
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create temporary output directory: %w", err)
+	}
+	cleaner.AddCleanupFn(func() { _ = os.RemoveAll(stagingDir) })
+	stagedOutput := filepath.Join(stagingDir, filepath.Base(outputPath))
+
+	switch format {
+	case OCILayout:
+		err = imagearchive.WriteOCILayout(stagedOutput, entries)
+	case OCIArchive:
+		layoutDir := filepath.Join(tempDir, "oci-layout")
+		err = imagearchive.WriteOCILayout(layoutDir, entries)
+		if err == nil {
+			err = archive.ArchiveDirectory(layoutDir, stagedOutput)
+		}
+	case DockerArchive:
+		err = imagearchive.WriteDockerArchive(stagedOutput, entries)
+	default:
+		err = fmt.Errorf("unsupported export format: %v", format)
+	}
+	if err == nil {
+		err = replaceOutput(stagedOutput, outputPath, filepath.Join(stagingDir, "previous"))
+	}
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return err
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	return nil
+}
+
+// checkOutput returns an error if the output already exists and cannot be overwritten. Only files, such as
+// previously exported archives, and OCI image layouts are overwritten to avoid deleting unrelated directories.
+func checkOutput(outputPath string, overwrite bool) error {
+	fi, err := os.Lstat(outputPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check if output %s already exists: %w", outputPath, err)
+	}
+	if !overwrite {
+		return fmt.Errorf("%s already exists: specify --overwrite to overwrite it", outputPath)
+	}
+
+	switch {
+	case fi.Mode().IsRegular():
+		return nil
+	case fi.IsDir():
+		layoutFile, err := os.Lstat(filepath.Join(outputPath, "oci-layout"))
+		if err == nil && layoutFile.Mode().IsRegular() {
+			return nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to check if %s is an OCI image layout: %w", outputPath, err)
+		}
+	}
+	return fmt.Errorf("refusing to overwrite %s: it is neither a file nor an OCI image layout", outputPath)
+}
+
+// replaceOutput renames the staged output to the output path. Any existing output is first moved to previousPath,
+// and restored if the staged output cannot be renamed into place.
+func replaceOutput(stagedOutput, outputPath, previousPath string) error {
+	replacing := false
+	if err := os.Rename(outputPath, previousPath); err == nil {
+		replacing = true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to move existing output %s aside: %w", outputPath, err)
+	}
+
+	if err := os.Rename(stagedOutput, outputPath); err != nil {
+		if replacing {
+			_ = os.Rename(previousPath, outputPath)
+		}
+		return fmt.Errorf("failed to move output to %s: %w", outputPath, err)
+	}
+	return nil
+}
+
+// bundleEntries reads the images matching the patterns from the bundle registry, retaining only the requested
+// platforms. Single platform images for other platforms are skipped. If singleImage is true, the image for the
+// requested platform is read instead of the image index and OCI artifacts, which are not container images, are
+// skipped.
+func bundleEntries(
+	out output.Output,
+	reg *registry.Registry,
+	cfg config.ImagesConfig,
+	imagePatterns []string,
+	platforms []string,
+	singleImage bool,
+) ([]imagearchive.Entry, error) {
+	transport, err := httputils.InsecureTLSRoundTripper(remote.DefaultTransport)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring TLS for source registry: %w", err)
+	}
+	remoteOpts := []remote.Option{
+		remote.WithTransport(transport),
+		remote.WithUserAgent(utils.Useragent()),
+	}
+
+	var platform *v1.Platform
+	if singleImage {
+		platform, err = v1.ParsePlatform(platforms[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid platform %q: %w", platforms[0], err)
+		}
+	}
+
+	var entries []imagearchive.Entry
+	for _, registryName := range cfg.SortedRegistryNames() {
+		registryConfig := cfg[registryName]
+		for _, imageName := range registryConfig.SortedImageNames() {
+			for _, imageTag := range registryConfig.Images[imageName] {
+				imageRepo := fmt.Sprintf("%s/%s", registryName, imageName)
+				imageRef := fmt.Sprintf("%s:%s", imageRepo, imageTag)
+				if !matchesImagePatterns(imagePatterns, imageRepo, imageRef) {
+					continue
+				}
+
+				out.StartOperation(fmt.Sprintf("Reading %s from bundle", imageRef))
+				entry, skipReason, err := bundleEntry(
+					reg, imageName, imageTag, imageRef, platform, platforms, remoteOpts,
+				)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return nil, err
+				}
+				if skipReason != "" {
+					out.EndOperationWithStatus(output.Skipped())
+					out.Warnf("Skipping %s: %s", imageRef, skipReason)
+					continue
+				}
+				out.EndOperationWithStatus(output.Success())
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no images to export: check the --image patterns")
+	}
+
+	return entries, nil
+}
+
+// bundleEntry reads a single image from the bundle registry. If the image cannot be exported with the requested
+// platforms and format, the reason it is skipped is returned instead.
+func bundleEntry(
+	reg *registry.Registry,
+	imageName, imageTag, imageRef string,
+	platform *v1.Platform,
+	platforms []string,
+	remoteOpts []remote.Option,
+) (imagearchive.Entry, string, error) {
+	ref, err := name.NewTag(imageRef, name.StrictValidation)
+	if err != nil {
+		return imagearchive.Entry{}, "", err
+	}
+	srcRef, err := name.ParseReference(
+		fmt.Sprintf("%s/%s:%s", reg.Address(), imageName, imageTag),
+		name.StrictValidation,
+	)
+	if err != nil {
+		return imagearchive.Entry{}, "", err
+	}
+
+	desc, err := remote.Get(srcRef, remoteOpts...)
+	if err != nil {
+		return imagearchive.Entry{}, "", fmt.Errorf("failed to read %s from bundle: %w", imageRef, err)
+	}
+
+	switch {
+	case desc.MediaType.IsIndex() && platform != nil:
+		img, err := remote.Image(srcRef, append(remoteOpts, remote.WithPlatform(*platform))...)
+		if err != nil {
+			return imagearchive.Entry{}, "", fmt.Errorf(
+				"failed to read %s for platform %s from bundle: %w", imageRef, platform, err,
+			)
+		}
+		return imagearchive.Entry{Ref: ref, Image: img}, "", nil
+	case desc.MediaType.IsIndex():
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return imagearchive.Entry{}, "", fmt.Errorf("failed to read image index for %s: %w", imageRef, err)
+		}
+		idx, err = images.RetainOnlyRequestedPlatformsInIndex(idx, platforms...)
+		if err != nil {
+			return imagearchive.Entry{}, "", err
+		}
+		return imagearchive.Entry{Ref: ref, Index: idx}, "", nil
+	case desc.MediaType.IsImage():
+		img, err := desc.Image()
+		if err != nil {
+			return imagearchive.Entry{}, "", fmt.Errorf("failed to read image for %s: %w", imageRef, err)
+		}
+		if platform != nil {
+			manifest, err := img.Manifest()
+			if err != nil {
+				return imagearchive.Entry{}, "", fmt.Errorf(
+					"failed to read image manifest for %s: %w", imageRef, err,
+				)
+			}
+			if !manifest.Config.MediaType.IsConfig() {
+				return imagearchive.Entry{}, "OCI artifacts cannot be exported to a docker archive", nil
+			}
+		}
+		matches, err := images.ImageMatchesPlatforms(img, platforms...)
+		if err != nil {
+			return imagearchive.Entry{}, "", fmt.Errorf("failed to read platform of %s: %w", imageRef, err)
+		}
+		if !matches {
+			return imagearchive.Entry{}, fmt.Sprintf(
+				"single platform image does not match the requested platforms %s", strings.Join(platforms, ", "),
+			), nil
+		}
+		return imagearchive.Entry{Ref: ref, Image: img}, "", nil
+	default:
+		return imagearchive.Entry{}, "", fmt.Errorf(
+			"unexpected media type in descriptor for %s: %v", imageRef, desc.MediaType,
+		)
+	}
+}
+
+// matchesImagePatterns returns true if there are no patterns, or any of the patterns match either the image
+// repository or the full image reference.
+func matchesImagePatterns(patterns []string, imageRepo, imageRef string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matched, _ := path.Match(p, imageRepo); matched {
+			return true
+		}
+		if matched, _ := path.Match(p, imageRef); matched {
+			return true
+		}
+	}
+	return false
+}
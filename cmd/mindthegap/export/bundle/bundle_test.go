@@ -0,0 +1,222 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/archive/archivetest"
+	"github.com/mesosphere/mindthegap/config"
+	imagearchive "github.com/mesosphere/mindthegap/images/archive"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+// writeTestBundle creates a bundle containing a multi-arch image, a single amd64 image and an OCI artifact.
+func writeTestBundle(t *testing.T) (bundleFile string, amd64Image, arm64Image v1.Image) {
+	t.Helper()
+
+	amd64Image = bundletest.NewPlatformImage(t, "amd64")
+	arm64Image = bundletest.NewPlatformImage(t, "arm64")
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        amd64Image,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64Image,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}},
+		},
+	)
+
+	artifact := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	artifact = mutate.ConfigMediaType(artifact, "application/vnd.example.config.v1+json")
+
+	bundleFile = bundletest.WriteBundle(
+		t,
+		map[string]remote.Taggable{
+			"example.com/team/app:v1":        idx,
+			"example.com/team/other:v1":      bundletest.NewPlatformImage(t, "amd64"),
+			"oci.example.com/artifact:1.0.0": artifact,
+		},
+		config.HelmChartsConfig{},
+	)
+	return bundleFile, amd64Image, arm64Image
+}
+
+func openArchiveEntries(t *testing.T, archivePath string) []imagearchive.Entry {
+	t.Helper()
+	a, err := imagearchive.Open(archivePath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+	entries, err := a.Entries()
+	require.NoError(t, err)
+	return entries
+}
+
+func TestExportBundle(t *testing.T) {
+	bundleFile, amd64Image, arm64Image := writeTestBundle(t)
+	buf := &bytes.Buffer{}
+	out := output.NewNonInteractiveShell(buf, buf, 0)
+
+	t.Run("oci-archive", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "oci.tar")
+		require.NoError(t, exportBundles(out, []string{bundleFile}, nil, OCIArchive, outputFile, false, nil, nil))
+
+		entries := openArchiveEntries(t, outputFile)
+		require.Len(t, entries, 3)
+		assert.Equal(t, "example.com/team/app:v1", entries[0].Ref.String())
+		require.NotNil(t, entries[0].Index)
+		manifest, err := entries[0].Index.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Manifests, 2)
+		assert.Equal(t, bundletest.Digest(t, amd64Image), manifest.Manifests[0].Digest)
+		assert.Equal(t, bundletest.Digest(t, arm64Image), manifest.Manifests[1].Digest)
+		assert.Equal(t, "example.com/team/other:v1", entries[1].Ref.String())
+		assert.NotNil(t, entries[1].Image)
+		assert.Equal(t, "oci.example.com/artifact:1.0.0", entries[2].Ref.String())
+		assert.NotNil(t, entries[2].Image)
+
+		require.ErrorContains(
+			t,
+			exportBundles(out, []string{bundleFile}, nil, OCIArchive, outputFile, false, nil, nil),
+			"already exists",
+		)
+	})
+
+	t.Run("oci-layout with platform and image filters", func(t *testing.T) {
+		outputDir := filepath.Join(t.TempDir(), "layout")
+		require.NoError(t, exportBundles(
+			out, []string{bundleFile}, nil, OCILayout, outputDir, false, []string{"example.com/team/*"},
+			[]string{"linux/arm64"},
+		))
+
+		// The single amd64 image of team/other is skipped as it is not for the requested platform.
+		p, err := layout.FromPath(outputDir)
+		require.NoError(t, err)
+		idx, err := p.ImageIndex()
+		require.NoError(t, err)
+		manifest, err := idx.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Manifests, 1)
+		assert.Equal(
+			t,
+			"example.com/team/app:v1",
+			manifest.Manifests[0].Annotations["org.opencontainers.image.ref.name"],
+		)
+
+		appIndex, err := idx.ImageIndex(manifest.Manifests[0].Digest)
+		require.NoError(t, err)
+		appManifest, err := appIndex.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, appManifest.Manifests, 1)
+		assert.Equal(t, bundletest.Digest(t, arm64Image), appManifest.Manifests[0].Digest)
+	})
+
+	t.Run("docker-archive", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "docker.tar")
+		require.NoError(t, exportBundles(
+			out, []string{bundleFile}, nil, DockerArchive, outputFile, false, nil, []string{"linux/amd64"},
+		))
+
+		// The OCI artifact is skipped as it cannot be loaded by docker.
+		entries := openArchiveEntries(t, outputFile)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "example.com/team/app:v1", entries[0].Ref.String())
+		require.NotNil(t, entries[0].Image)
+		assert.Equal(t, bundletest.Digest(t, amd64Image), bundletest.Digest(t, entries[0].Image))
+		assert.Equal(t, "example.com/team/other:v1", entries[1].Ref.String())
+
+		// The single amd64 image is skipped when exporting for another platform.
+		require.NoError(t, exportBundles(
+			out, []string{bundleFile}, nil, DockerArchive, outputFile, true, nil, []string{"linux/arm64"},
+		))
+		entries = openArchiveEntries(t, outputFile)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "example.com/team/app:v1", entries[0].Ref.String())
+		assert.Equal(t, bundletest.Digest(t, arm64Image), bundletest.Digest(t, entries[0].Image))
+	})
+
+	t.Run("encrypted bundle", func(t *testing.T) {
+		encryptedFile, keyFile := archivetest.EncryptBundle(t, bundleFile)
+		outputFile := filepath.Join(t.TempDir(), "oci.tar")
+		require.ErrorIs(
+			t,
+			exportBundles(out, []string{encryptedFile}, nil, OCIArchive, outputFile, false, nil, nil),
+			archive.ErrNoDecryptionKeys,
+		)
+		require.NoError(t, exportBundles(
+			out, []string{encryptedFile}, []string{keyFile}, OCIArchive, outputFile, false, nil, nil,
+		))
+		assert.Len(t, openArchiveEntries(t, outputFile), 3)
+	})
+
+	t.Run("no matching images", func(t *testing.T) {
+		require.ErrorContains(
+			t,
+			exportBundles(
+				out, []string{bundleFile}, nil, OCIArchive, filepath.Join(t.TempDir(), "oci.tar"), false,
+				[]string{"missing/*"}, nil,
+			),
+			"no images to export",
+		)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		dir := t.TempDir()
+
+		outputFile := filepath.Join(dir, "oci.tar")
+		require.NoError(t, os.WriteFile(outputFile, []byte("previous"), 0o600))
+		// A failed export leaves the existing output untouched.
+		require.ErrorContains(
+			t,
+			exportBundles(out, []string{bundleFile}, nil, OCIArchive, outputFile, true, []string{"missing/*"}, nil),
+			"no images to export",
+		)
+		assert.FileExists(t, outputFile)
+		require.NoError(t, exportBundles(out, []string{bundleFile}, nil, OCIArchive, outputFile, true, nil, nil))
+		assert.Len(t, openArchiveEntries(t, outputFile), 3)
+
+		// An existing OCI layout can be replaced by a docker archive.
+		layoutDir := filepath.Join(dir, "layout")
+		require.NoError(t, exportBundles(out, []string{bundleFile}, nil, OCILayout, layoutDir, false, nil, nil))
+		require.NoError(t, exportBundles(
+			out, []string{bundleFile}, nil, DockerArchive, layoutDir, true, nil, []string{"linux/amd64"},
+		))
+		assert.Len(t, openArchiveEntries(t, layoutDir), 2)
+
+		otherDir := filepath.Join(dir, "other")
+		require.NoError(t, os.Mkdir(otherDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(otherDir, "keep"), nil, 0o600))
+		require.ErrorContains(
+			t,
+			exportBundles(out, []string{bundleFile}, nil, OCILayout, otherDir, true, nil, nil),
+			"refusing to overwrite",
+		)
+		assert.FileExists(t, filepath.Join(otherDir, "keep"))
+
+		// No temporary output is left behind.
+		dirEntries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		names := make([]string, 0, len(dirEntries))
+		for _, e := range dirEntries {
+			names = append(names, e.Name())
+		}
+		assert.ElementsMatch(t, []string{"oci.tar", "layout", "other"}, names)
+	})
+}
@@ -0,0 +1,460 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/cleanup"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/images"
+	"github.com/mesosphere/mindthegap/images/httputils"
+)
+
+// bundleFilter selects the images and Helm charts to keep in a filtered bundle. Excludes take precedence over
+// includes, and empty includes include everything.
+type bundleFilter struct {
+	includeImages []string
+	excludeImages []string
+	includeTags   []string
+	excludeTags   []string
+	includeCharts []string
+	excludeCharts []string
+	platforms     []string
+}
+
+func (f bundleFilter) validate() error {
+	for _, patterns := range [][]string{
+		f.includeImages, f.excludeImages, f.includeTags, f.excludeTags, f.includeCharts, f.excludeCharts,
+	} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid filter pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// includeImage returns true if the image is included, matching image patterns against both <registry>/<image>
+// and <registry>/<image>:<tag>, and tag patterns against the tag.
+func (f bundleFilter) includeImage(registryName, imageName, imageTag string) bool {
+	imageRepo := fmt.Sprintf("%s/%s", registryName, imageName)
+	imageRef := fmt.Sprintf("%s:%s", imageRepo, imageTag)
+	return matchesFilters(f.includeImages, f.excludeImages, imageRepo, imageRef) &&
+		matchesFilters(f.includeTags, f.excludeTags, imageTag)
+}
+
+// includeChart returns true if the chart is included, matching chart patterns against both <chart> and
+// <chart>:<version>.
+func (f bundleFilter) includeChart(chartName, chartVersion string) bool {
+	return matchesFilters(f.includeCharts, f.excludeCharts, chartName, fmt.Sprintf("%s:%s", chartName, chartVersion))
+}
+
+// matchesFilters returns true if none of the candidates match any exclude pattern, and any candidate matches any
+// include pattern or there are no include patterns.
+func matchesFilters(includes, excludes []string, candidates ...string) bool {
+	matchesAny := func(patterns []string) bool {
+		for _, p := range patterns {
+			for _, c := range candidates {
+				if matched, _ := path.Match(p, c); matched {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if matchesAny(excludes) {
+		return false
+	}
+	return len(includes) == 0 || matchesAny(includes)
+}
+
+func NewCommand(out output.Output) *cobra.Command {
+	var (
+		outputFile         string
+		overwrite          bool
+		decryptionKeyFiles []string
+		encryption         flags.Encryption
+		recipients         []age.Recipient
+		filter             bundleFilter
+		platforms          = flags.NewPlatformsValue()
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bundle <bundle>",
+		Short: "Filter the images, Helm charts and platforms of a bundle without network access",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.ValidateRequiredFlags(); err != nil {
+				return err
+			}
+
+			var err error
+			recipients, err = encryption.ParseRecipients()
+			if err != nil {
+				return err
+			}
+
+			return filter.validate()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundleFiles, err := utils.FilesWithGlobs(args)
+			if err != nil {
+				return err
+			}
+			bundleFiles, err = utils.ExpandBundleParts(out, bundleFiles)
+			if err != nil {
+				return err
+			}
+
+			if !overwrite {
+				out.StartOperation("Checking if output file already exists")
+				_, err := os.Stat(outputFile)
+				switch {
+				case err == nil:
+					out.EndOperationWithStatus(output.Failure())
+					return fmt.Errorf(
+						"%s already exists: specify --overwrite to overwrite existing file", outputFile,
+					)
+				case !errors.Is(err, os.ErrNotExist):
+					out.EndOperationWithStatus(output.Failure())
+					return fmt.Errorf("failed to check if output file %s already exists: %w", outputFile, err)
+				default:
+					out.EndOperationWithStatus(output.Success())
+				}
+			}
+
+			filter.platforms = platforms.GetSlice()
+			return filterBundle(out, bundleFiles, decryptionKeyFiles, outputFile, recipients, filter)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Output file to write filtered bundle to")
+	_ = cmd.MarkFlagRequired("output-file")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite output file if it already exists")
+	cmd.Flags().StringSliceVar(&decryptionKeyFiles, "decrypt-key", nil,
+		"File containing age private keys (AGE-SECRET-KEY-1...) or a passphrase to decrypt encrypted bundles with. "+
+			"Can be specified multiple times")
+	encryption.AddFlags(cmd)
+	cmd.Flags().StringSliceVar(&filter.includeImages, "include-image", nil,
+		"Glob patterns of images to keep, matched against <registry>/<image>:<tag> and <registry>/<image> "+
+			"(default all images)")
+	cmd.Flags().StringSliceVar(&filter.excludeImages, "exclude-image", nil,
+		"Glob patterns of images to drop, matched against <registry>/<image>:<tag> and <registry>/<image>")
+	cmd.Flags().StringSliceVar(&filter.includeTags, "include-tags", nil,
+		"Glob patterns of image tags to keep (default all tags)")
+	cmd.Flags().StringSliceVar(&filter.excludeTags, "exclude-tags", nil,
+		"Glob patterns of image tags to drop")
+	cmd.Flags().StringSliceVar(&filter.includeCharts, "include-chart", nil,
+		"Glob patterns of Helm charts to keep, matched against <chart>:<version> and <chart> (default all charts)")
+	cmd.Flags().StringSliceVar(&filter.excludeCharts, "exclude-chart", nil,
+		"Glob patterns of Helm charts to drop, matched against <chart>:<version> and <chart>")
+	cmd.Flags().Var(&platforms, "platform",
+		"platforms to keep in image indexes (required format: <os>/<arch>[/<variant>]) (default all platforms)")
+
+	return cmd
+}
+
+// filterBundle copies the images and Helm charts selected by the filter from the bundles to a new bundle written
+// to outputFile. Images are copied between two local registries, which rewrites image indexes to only contain the
+// requested platforms and copies only the blobs that are still referenced. Encrypted bundles are decrypted with the
+// keys read from decryptionKeyFiles. The filtered bundle is encrypted for the recipients if there are any.
+func filterBundle(
+	out output.Output,
+	bundleFiles, decryptionKeyFiles []string,
+	outputFile string,
+	recipients []age.Recipient,
+	filter bundleFilter,
+) error {
+	if len(recipients) == 0 {
+		if err := utils.WarnUnencryptedOutput(out, bundleFiles); err != nil {
+			return err
+		}
+	}
+
+	cleaner := cleanup.NewCleaner()
+	defer cleaner.Cleanup()
+
+	out.StartOperation("Creating temporary directories")
+	outputFileAbs, err := filepath.Abs(outputFile)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to determine where to create temporary directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(filepath.Dir(outputFileAbs), ".bundle-*")
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleaner.AddCleanupFn(func() { _ = os.RemoveAll(tempDir) })
+	configsDir, err := os.MkdirTemp("", ".bundle-configs-*")
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleaner.AddCleanupFn(func() { _ = os.RemoveAll(configsDir) })
+	out.EndOperationWithStatus(output.Success())
+
+	imagesCfg, chartsCfg, err := utils.ExtractConfigs(configsDir, out, decryptionKeyFiles, bundleFiles...)
+	if err != nil {
+		return err
+	}
+	if imagesCfg == nil && chartsCfg == nil {
+		return errors.New(
+			"no bundle configuration(s) found: please check that you have specified a valid air-gapped bundle",
+		)
+	}
+
+	out.StartOperation("Starting temporary Docker registries")
+	storage, err := registry.ArchiveStorage("", decryptionKeyFiles, bundleFiles...)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create storage for Docker registry from supplied bundles: %w", err)
+	}
+	srcReg, err := startRegistry(out, registry.Config{Storage: storage, ReadOnly: true})
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return err
+	}
+	defer func() { _ = srcReg.Shutdown(context.Background()) }()
+	destReg, err := startRegistry(out, registry.Config{Storage: registry.FilesystemStorage(tempDir)})
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return err
+	}
+	defer func() { _ = destReg.Shutdown(context.Background()) }()
+	out.EndOperationWithStatus(output.Success())
+
+	transport, err := httputils.InsecureTLSRoundTripper(remote.DefaultTransport)
+	if err != nil {
+		return fmt.Errorf("error configuring TLS for temporary registries: %w", err)
+	}
+	c := copier{
+		src:        srcReg.Address(),
+		dest:       destReg.Address(),
+		platforms:  filter.platforms,
+		remoteOpts: []remote.Option{remote.WithTransport(transport), remote.WithUserAgent(utils.Useragent())},
+	}
+
+	var filteredImagesCfg config.ImagesConfig
+	if imagesCfg != nil {
+		filteredImagesCfg, err = c.copyImages(out, *imagesCfg, filter)
+		if err != nil {
+			return err
+		}
+	}
+	var filteredChartsCfg config.HelmChartsConfig
+	if chartsCfg != nil {
+		filteredChartsCfg, err = c.copyCharts(out, *chartsCfg, filter)
+		if err != nil {
+			return err
+		}
+	}
+	if filteredImagesCfg.TotalImages() == 0 && filteredChartsCfg.TotalCharts() == 0 {
+		return errors.New("no images or Helm charts match the filters")
+	}
+
+	out.StartOperation("Writing filtered bundle configs")
+	if filteredImagesCfg.TotalImages() > 0 {
+		if err := config.WriteSanitizedImagesConfigs(
+			filepath.Join(tempDir, "images.yaml"), filteredImagesCfg,
+		); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+	}
+	if filteredChartsCfg.TotalCharts() > 0 {
+		if err := config.WriteSanitizedHelmChartsConfig(
+			filepath.Join(tempDir, "charts.yaml"), filteredChartsCfg,
+		); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	out.StartOperation(fmt.Sprintf("Archiving filtered bundle to %s", outputFile))
+	if len(recipients) > 0 {
+		err = archive.ArchiveDirectoryEncrypted(tempDir, outputFile, recipients...)
+	} else {
+		err = archive.ArchiveDirectory(tempDir, outputFile)
+	}
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create bundle tarball: %w", err)
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	return nil
+}
+
+func startRegistry(out output.Output, cfg registry.Config) (*registry.Registry, error) {
+	reg, err := registry.NewRegistry(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local Docker registry: %w", err)
+	}
+	go func() {
+		if err := reg.ListenAndServe(output.NewOutputLogr(out)); err != nil {
+			out.Error(err, "error serving Docker registry")
+			os.Exit(2)
+		}
+	}()
+	if err := utils.WaitForRegistry(reg.Address()); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// copier copies repositories from the registry serving the source bundle to the registry of the filtered bundle.
+type copier struct {
+	src, dest  string
+	platforms  []string
+	remoteOpts []remote.Option
+}
+
+func (c copier) copyImages(
+	out output.Output, cfg config.ImagesConfig, filter bundleFilter,
+) (config.ImagesConfig, error) {
+	filtered := config.ImagesConfig{}
+	for _, registryName := range cfg.SortedRegistryNames() {
+		registryConfig := cfg[registryName]
+		for _, imageName := range registryConfig.SortedImageNames() {
+			for _, imageTag := range registryConfig.Images[imageName] {
+				if !filter.includeImage(registryName, imageName, imageTag) {
+					continue
+				}
+
+				imageRef := fmt.Sprintf("%s/%s:%s", registryName, imageName, imageTag)
+				out.StartOperation(fmt.Sprintf("Copying %s", imageRef))
+				copied, err := c.copy(imageName + ":" + imageTag)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return nil, fmt.Errorf("failed to copy %s: %w", imageRef, err)
+				}
+				if !copied {
+					out.EndOperationWithStatus(output.Skipped())
+					out.Warnf("Skipping %s: image is not available for any of the requested platforms", imageRef)
+					continue
+				}
+				out.EndOperationWithStatus(output.Success())
+
+				if _, ok := filtered[registryName]; !ok {
+					filtered[registryName] = config.RegistrySyncConfig{Images: map[string][]string{}}
+				}
+				filtered[registryName].Images[imageName] = append(filtered[registryName].Images[imageName], imageTag)
+			}
+		}
+	}
+	return filtered, nil
+}
+
+func (c copier) copyCharts(
+	out output.Output, cfg config.HelmChartsConfig, filter bundleFilter,
+) (config.HelmChartsConfig, error) {
+	filtered := config.HelmChartsConfig{Repositories: map[string]config.HelmRepositorySyncConfig{}}
+	for _, repoName := range cfg.SortedRepositoryNames() {
+		repoConfig := cfg.Repositories[repoName]
+		for _, chartName := range repoConfig.SortedChartNames() {
+			for _, chartVersion := range repoConfig.Charts[chartName] {
+				if !filter.includeChart(chartName, chartVersion) {
+					continue
+				}
+
+				out.StartOperation(fmt.Sprintf("Copying Helm chart %s:%s", chartName, chartVersion))
+				// Helm replaces + in chart versions with _ in OCI tags.
+				chartTag := strings.ReplaceAll(chartVersion, "+", "_")
+				if _, err := c.copy(fmt.Sprintf("charts/%s:%s", chartName, chartTag)); err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return config.HelmChartsConfig{}, fmt.Errorf(
+						"failed to copy Helm chart %s:%s: %w", chartName, chartVersion, err,
+					)
+				}
+				out.EndOperationWithStatus(output.Success())
+
+				if _, ok := filtered.Repositories[repoName]; !ok {
+					filtered.Repositories[repoName] = config.HelmRepositorySyncConfig{
+						RepoURL: repoConfig.RepoURL,
+						Charts:  map[string][]string{},
+					}
+				}
+				filtered.Repositories[repoName].Charts[chartName] = append(
+					filtered.Repositories[repoName].Charts[chartName], chartVersion,
+				)
+			}
+		}
+	}
+	return filtered, nil
+}
+
+// copy copies the repository tag from the source to the destination registry, retaining only the requested
+// platforms in image indexes. Returns false if the tag is an image index without any of the requested platforms,
+// or a single image for a platform that was not requested.
+func (c copier) copy(repositoryTag string) (bool, error) {
+	srcRef, err := name.ParseReference(fmt.Sprintf("%s/%s", c.src, repositoryTag), name.StrictValidation)
+	if err != nil {
+		return false, err
+	}
+	destRef, err := name.ParseReference(fmt.Sprintf("%s/%s", c.dest, repositoryTag), name.StrictValidation)
+	if err != nil {
+		return false, err
+	}
+
+	desc, err := remote.Get(srcRef, c.remoteOpts...)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from bundle: %w", err)
+	}
+
+	switch {
+	case desc.MediaType.IsIndex():
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return false, fmt.Errorf("failed to read image index: %w", err)
+		}
+		idx, err = images.RetainOnlyRequestedPlatformsInIndex(idx, c.platforms...)
+		if err != nil {
+			return false, err
+		}
+		manifest, err := idx.IndexManifest()
+		if err != nil {
+			return false, fmt.Errorf("failed to read image index manifest: %w", err)
+		}
+		if len(manifest.Manifests) == 0 {
+			return false, nil
+		}
+		return true, remote.WriteIndex(destRef, idx, c.remoteOpts...)
+	default:
+		// Single images, OCI artifacts and Helm charts are copied as they are.
+		img, err := desc.Image()
+		if err != nil {
+			return false, fmt.Errorf("failed to read image: %w", err)
+		}
+		matches, err := images.ImageMatchesPlatforms(img, c.platforms...)
+		if err != nil {
+			return false, err
+		}
+		if !matches {
+			return false, nil
+		}
+		return true, remote.Write(destRef, img, c.remoteOpts...)
+	}
+}
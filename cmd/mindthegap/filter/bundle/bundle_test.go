@@ -0,0 +1,240 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/archive/archivetest"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+// writeTestBundle creates a bundle containing a multi-arch image, a single image and versions of a Helm chart,
+// including a version with build metadata.
+func writeTestBundle(t *testing.T) (bundleFile string, amd64Image, arm64Image v1.Image) {
+	t.Helper()
+
+	amd64Image = bundletest.NewPlatformImage(t, "amd64")
+	arm64Image = bundletest.NewPlatformImage(t, "arm64")
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        amd64Image,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+		mutate.IndexAddendum{
+			Add:        arm64Image,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}},
+		},
+	)
+
+	bundleFile = bundletest.WriteBundle(
+		t,
+		map[string]remote.Taggable{
+			"example.com/team/app:v1":   idx,
+			"example.com/team/app:v2":   idx,
+			"example.com/team/other:v1": bundletest.NewPlatformImage(t, "amd64"),
+		},
+		config.HelmChartsConfig{Repositories: map[string]config.HelmRepositorySyncConfig{
+			"podinfo": {
+				RepoURL: "https://stefanprodan.github.io/podinfo",
+				Charts:  map[string][]string{"podinfo": {"6.1.0", "6.2.0", "6.3.0+build.1"}},
+			},
+		}},
+	)
+	return bundleFile, amd64Image, arm64Image
+}
+
+func bundleBlobs(t *testing.T, bundleFile string) []string {
+	t.Helper()
+
+	f, err := os.Open(bundleFile)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var blobs []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		if dir, ok := strings.CutSuffix(hdr.Name, "/data"); ok && strings.Contains(dir, "/blobs/sha256/") {
+			blobs = append(blobs, "sha256:"+filepath.Base(dir))
+		}
+	}
+	return blobs
+}
+
+func TestFilterBundle(t *testing.T) {
+	bundleFile, amd64Image, arm64Image := writeTestBundle(t)
+
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+	filtered := filepath.Join(t.TempDir(), "filtered.tar")
+	require.NoError(t, filterBundle(out, []string{bundleFile}, nil, filtered, nil, bundleFilter{
+		excludeImages: []string{"*/team/other"},
+		excludeTags:   []string{"v2"},
+		includeCharts: []string{"podinfo:6.2.*"},
+		platforms:     []string{"linux/arm64"},
+	}))
+
+	configsDir := t.TempDir()
+	require.NoError(t, archive.ExtractFileToDirectory(filtered, configsDir, "images.yaml"))
+	require.NoError(t, archive.ExtractFileToDirectory(filtered, configsDir, "charts.yaml"))
+	imagesCfg, err := config.ParseImagesConfigFile(filepath.Join(configsDir, "images.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, config.ImagesConfig{
+		"example.com": {Images: map[string][]string{"team/app": {"v1"}}},
+	}, imagesCfg)
+	chartsCfg, err := config.ParseHelmChartsConfigFile(filepath.Join(configsDir, "charts.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"podinfo": {"6.2.0"}}, chartsCfg.Repositories["podinfo"].Charts)
+
+	storage, err := registry.ArchiveStorage("", nil, filtered)
+	require.NoError(t, err)
+	reg := bundletest.StartRegistry(t, storage)
+
+	idx, err := remote.Index(bundletest.ParseReference(t, reg.Address()+"/team/app:v1"))
+	require.NoError(t, err)
+	manifest, err := idx.IndexManifest()
+	require.NoError(t, err)
+	require.Len(t, manifest.Manifests, 1)
+	assert.Equal(t, "arm64", manifest.Manifests[0].Platform.Architecture)
+
+	_, err = remote.Head(bundletest.ParseReference(t, reg.Address()+"/charts/podinfo:6.2.0"))
+	require.NoError(t, err)
+	_, err = remote.Head(bundletest.ParseReference(t, reg.Address()+"/charts/podinfo:6.1.0"))
+	require.Error(t, err)
+	_, err = remote.Head(bundletest.ParseReference(t, reg.Address()+"/team/other:v1"))
+	require.Error(t, err)
+
+	// Only blobs referenced by the retained images are copied.
+	blobs := bundleBlobs(t, filtered)
+	for _, img := range []v1.Image{amd64Image, arm64Image} {
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		digest, err := layers[0].Digest()
+		require.NoError(t, err)
+		if img == arm64Image {
+			assert.Contains(t, blobs, digest.String())
+		} else {
+			assert.NotContains(t, blobs, digest.String())
+		}
+	}
+}
+
+func TestFilterBundleSinglePlatformImages(t *testing.T) {
+	bundleFile, _, _ := writeTestBundle(t)
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+
+	for platform, want := range map[string]map[string][]string{
+		// The single amd64 image of team/other is skipped when only arm64 is requested.
+		"linux/arm64": {"team/app": {"v1", "v2"}},
+		"linux/amd64": {"team/app": {"v1", "v2"}, "team/other": {"v1"}},
+	} {
+		filtered := filepath.Join(t.TempDir(), "filtered.tar")
+		require.NoError(t, filterBundle(out, []string{bundleFile}, nil, filtered, nil, bundleFilter{
+			platforms: []string{platform},
+		}), platform)
+
+		configsDir := t.TempDir()
+		require.NoError(t, archive.ExtractFileToDirectory(filtered, configsDir, "images.yaml"))
+		imagesCfg, err := config.ParseImagesConfigFile(filepath.Join(configsDir, "images.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, config.ImagesConfig{"example.com": {Images: want}}, imagesCfg, platform)
+
+		// Helm charts have no platform and are always kept.
+		require.NoError(t, archive.ExtractFileToDirectory(filtered, configsDir, "charts.yaml"))
+		chartsCfg, err := config.ParseHelmChartsConfigFile(filepath.Join(configsDir, "charts.yaml"))
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			map[string][]string{"podinfo": {"6.1.0", "6.2.0", "6.3.0+build.1"}},
+			chartsCfg.Repositories["podinfo"].Charts,
+			platform,
+		)
+	}
+}
+
+func TestFilterBundleNothingMatches(t *testing.T) {
+	bundleFile, _, _ := writeTestBundle(t)
+
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+	filtered := filepath.Join(t.TempDir(), "filtered.tar")
+	err := filterBundle(out, []string{bundleFile}, nil, filtered, nil, bundleFilter{
+		includeImages: []string{"docker.io/*"},
+		excludeCharts: []string{"*"},
+	})
+	require.ErrorContains(t, err, "no images or Helm charts match the filters")
+}
+
+func TestFilterEncryptedBundle(t *testing.T) {
+	bundleFile, _, _ := writeTestBundle(t)
+	encryptedBundle, keyFile := archivetest.EncryptBundle(t, bundleFile)
+	f := bundleFilter{excludeImages: []string{"*/team/other"}}
+	wantImagesCfg := config.ImagesConfig{"example.com": {Images: map[string][]string{"team/app": {"v1", "v2"}}}}
+
+	buf := &bytes.Buffer{}
+	out := output.NewNonInteractiveShell(buf, buf, 0)
+	filtered := filepath.Join(t.TempDir(), "filtered.tar")
+	require.NoError(t, filterBundle(out, []string{encryptedBundle}, []string{keyFile}, filtered, nil, f))
+	assert.Contains(t, buf.String(), "Bundle "+encryptedBundle+" is encrypted, but the output bundle is not")
+
+	identity, filteredKeyFile := archivetest.NewKeyFile(t)
+	encryptedFiltered := filepath.Join(t.TempDir(), "filtered.tar")
+	buf.Reset()
+	require.NoError(t, filterBundle(
+		out, []string{encryptedBundle}, []string{keyFile}, encryptedFiltered, []age.Recipient{identity.Recipient()}, f,
+	))
+	assert.NotContains(t, buf.String(), "output bundle is not")
+	encrypted, err := archive.IsEncrypted(encryptedFiltered)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	identities, err := archive.ParseDecryptionKeys([]string{filteredKeyFile})
+	require.NoError(t, err)
+	configsDir := t.TempDir()
+	require.NoError(t, archive.ExtractFileToDirectory(encryptedFiltered, configsDir, "images.yaml", identities...))
+	imagesCfg, err := config.ParseImagesConfigFile(filepath.Join(configsDir, "images.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, wantImagesCfg, imagesCfg)
+}
+
+func TestMatchesFilters(t *testing.T) {
+	t.Parallel()
+
+	f := bundleFilter{
+		includeImages: []string{"docker.io/library/*"},
+		excludeImages: []string{"docker.io/library/*:*-debug"},
+		includeTags:   []string{"v*"},
+	}
+	assert.True(t, f.includeImage("docker.io", "library/nginx", "v1.25"))
+	assert.False(t, f.includeImage("docker.io", "library/nginx", "v1.25-debug"))
+	assert.False(t, f.includeImage("docker.io", "library/nginx", "latest"))
+	assert.False(t, f.includeImage("quay.io", "library/nginx", "v1.25"))
+
+	assert.True(t, bundleFilter{}.includeChart("podinfo", "6.1.0"))
+	assert.True(t, bundleFilter{includeCharts: []string{"podinfo"}}.includeChart("podinfo", "6.1.0"))
+	assert.False(t, bundleFilter{excludeCharts: []string{"podinfo:6.*"}}.includeChart("podinfo", "6.1.0"))
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package flags
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1000,
+	"kb":  1000,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1000 * 1000,
+	"mb":  1000 * 1000,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1000 * 1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+}
+
+// Bandwidth is a flag value holding a transfer rate in bytes per second, e.g. `50MiB/s` or `10MB`.
+// Decimal (KB, MB, GB) and binary (KiB, MiB, GiB) units are supported, case insensitively, with an
+// optional `/s` suffix. A zero value means unlimited.
+type Bandwidth struct {
+	raw            string
+	bytesPerSecond int64
+}
+
+func (v *Bandwidth) String() string {
+	return v.raw
+}
+
+func (v *Bandwidth) Set(value string) error {
+	bytesPerSecond, err := parseBandwidth(value)
+	if err != nil {
+		return err
+	}
+	v.raw = value
+	v.bytesPerSecond = bytesPerSecond
+	return nil
+}
+
+func (*Bandwidth) Type() string {
+	return "bandwidth"
+}
+
+// BytesPerSecond returns the configured bandwidth in bytes per second, or 0 if unlimited.
+func (v *Bandwidth) BytesPerSecond() int64 {
+	return v.bytesPerSecond
+}
+
+func parseBandwidth(raw string) (int64, error) {
+	bytesPerSecond, err := ParseByteSize(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(raw)), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q: expected a value such as 50MiB/s: %w", raw, err)
+	}
+	return bytesPerSecond, nil
+}
+
+// ParseByteSize parses a size in bytes such as `10GiB` or `500MB`. Decimal (KB, MB, GB) and binary (KiB, MiB,
+// GiB) units are supported, case insensitively.
+func ParseByteSize(raw string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	numEnd := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if numEnd == -1 {
+		numEnd = len(s)
+	}
+	num, unit := s[:numEnd], strings.TrimSpace(s[numEnd:])
+
+	multiplier, ok := byteSizeUnits[unit]
+	if num == "" || !ok {
+		return 0, fmt.Errorf("invalid size %q", raw)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", raw, err)
+	}
+	size := f * multiplier
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: value too large", raw)
+	}
+	if f > 0 && size < 1 {
+		return 0, fmt.Errorf("invalid size %q: must be at least 1 byte", raw)
+	}
+	return int64(size), nil
+}
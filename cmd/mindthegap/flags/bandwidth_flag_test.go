@@ -0,0 +1,50 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package flags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "1024", want: 1024},
+		{value: "100B/s", want: 100},
+		{value: "50MiB/s", want: 50 << 20},
+		{value: "50mib/s", want: 50 << 20},
+		{value: "1.5GiB", want: 3 << 29},
+		{value: "10MB/s", want: 10_000_000},
+		{value: "10M", want: 10_000_000},
+		{value: "512Ki", want: 512 << 10},
+		{value: "", wantErr: true},
+		{value: "MiB/s", wantErr: true},
+		{value: "50Mbit/s", wantErr: true},
+		{value: "-1MiB", wantErr: true},
+		{value: "0.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+			var b Bandwidth
+			err := b.Set(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, b.BytesPerSecond())
+			assert.Equal(t, tt.value, b.String())
+		})
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"strings"
+
+	"github.com/spf13/pflag"
 )
 
 const (
@@ -77,3 +80,48 @@ func (v *RegistryURI) Path() string {
 func (*RegistryURI) Type() string {
 	return "string"
 }
+
+// RegistryURIs is a repeatable flag value holding one or more registry URIs.
+type RegistryURIs []*RegistryURI
+
+var _ pflag.SliceValue = &RegistryURIs{}
+
+func (v *RegistryURIs) String() string {
+	return "[" + strings.Join(v.GetSlice(), ",") + "]"
+}
+
+func (v *RegistryURIs) Set(value string) error {
+	return v.Append(value)
+}
+
+func (v *RegistryURIs) Append(value string) error {
+	uri, err := NewRegistryURI(value)
+	if err != nil {
+		return err
+	}
+	*v = append(*v, uri)
+	return nil
+}
+
+func (v *RegistryURIs) Replace(values []string) error {
+	uris := make(RegistryURIs, 0, len(values))
+	for _, value := range values {
+		if err := uris.Append(value); err != nil {
+			return err
+		}
+	}
+	*v = uris
+	return nil
+}
+
+func (v *RegistryURIs) GetSlice() []string {
+	strs := make([]string, 0, len(*v))
+	for _, uri := range *v {
+		strs = append(strs, uri.String())
+	}
+	return strs
+}
+
+func (*RegistryURIs) Type() string {
+	return "stringArray"
+}
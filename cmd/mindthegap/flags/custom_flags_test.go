@@ -6,6 +6,7 @@ package flags
 import (
 	"testing"
 
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,3 +76,19 @@ func Test_parsePossibleURI(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryURIs(t *testing.T) {
+	t.Parallel()
+	f := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	var uris RegistryURIs
+	f.Var(&uris, "to-registry", "")
+	require.NoError(t, f.Parse([]string{
+		"--to-registry", "https://primary.example.com/mirror",
+		"--to-registry", "dr.example.com:5000",
+	}))
+	require.Len(t, uris, 2)
+	require.Equal(t, "primary.example.com", uris[0].Host())
+	require.Equal(t, "/mirror", uris[0].Path())
+	require.Equal(t, "dr.example.com:5000", uris[1].Host())
+	require.Equal(t, []string{"https://primary.example.com/mirror", "dr.example.com:5000"}, uris.GetSlice())
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package flags
+
+import (
+	"errors"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/mindthegap/archive"
+)
+
+// Encryption holds the flags to encrypt the bundle written by a command.
+type Encryption struct {
+	Encrypt        bool
+	Recipients     []string
+	PassphraseFile string
+}
+
+// AddFlags adds the --encrypt, --encrypt-recipient and --encrypt-passphrase-file flags to the command.
+func (e *Encryption) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&e.Encrypt, "encrypt", false,
+		"Encrypt the bundle so that it can only be read with --decrypt-key. The bundle is encrypted in authenticated "+
+			"chunks so that it can still be served without decrypting it to disk")
+	cmd.Flags().StringSliceVar(&e.Recipients, "encrypt-recipient", nil,
+		"age public key (age1...) or file containing age public keys, one per line, to encrypt the bundle for. "+
+			"Can be specified multiple times")
+	cmd.Flags().StringVar(&e.PassphraseFile, "encrypt-passphrase-file", "",
+		"File containing the passphrase to encrypt the bundle with")
+	cmd.MarkFlagsMutuallyExclusive("encrypt-recipient", "encrypt-passphrase-file")
+}
+
+// ParseRecipients validates the flags and returns the recipients to encrypt the bundle for, or nil if the bundle is
+// not encrypted.
+func (e *Encryption) ParseRecipients() ([]age.Recipient, error) {
+	if !e.Encrypt {
+		if len(e.Recipients) > 0 || e.PassphraseFile != "" {
+			return nil, errors.New("--encrypt-recipient and --encrypt-passphrase-file require --encrypt")
+		}
+		return nil, nil
+	}
+	if len(e.Recipients) == 0 && e.PassphraseFile == "" {
+		return nil, errors.New("--encrypt requires either --encrypt-recipient or --encrypt-passphrase-file")
+	}
+	return archive.ParseRecipients(e.Recipients, e.PassphraseFile)
+}
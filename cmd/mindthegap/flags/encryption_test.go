@@ -0,0 +1,34 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package flags
+
+import (
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptionParseRecipients(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	recipient := identity.Recipient().String()
+
+	recipients, err := (&Encryption{}).ParseRecipients()
+	require.NoError(t, err)
+	assert.Nil(t, recipients)
+
+	recipients, err = (&Encryption{Encrypt: true, Recipients: []string{recipient}}).ParseRecipients()
+	require.NoError(t, err)
+	require.Len(t, recipients, 1)
+
+	_, err = (&Encryption{Recipients: []string{recipient}}).ParseRecipients()
+	require.EqualError(t, err, "--encrypt-recipient and --encrypt-passphrase-file require --encrypt")
+
+	_, err = (&Encryption{Encrypt: true}).ParseRecipients()
+	require.EqualError(t, err, "--encrypt requires either --encrypt-recipient or --encrypt-passphrase-file")
+}
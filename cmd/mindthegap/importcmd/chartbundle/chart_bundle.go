@@ -0,0 +1,162 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package chartbundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/cleanup"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/helm"
+)
+
+func NewCommand(out output.Output) *cobra.Command {
+	var (
+		chartBundleFiles   []string
+		decryptionKeyFiles []string
+		toDir              string
+		generateIndex      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "chart-bundle",
+		Short: "Extract Helm charts from bundles to a directory",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cmd.ValidateRequiredFlags(); err != nil {
+				return err
+			}
+
+			return flags.ValidateFlagsThatRequireValues(cmd, "chart-bundle", "to-dir")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chartBundleFiles, err := utils.FilesWithGlobs(chartBundleFiles)
+			if err != nil {
+				return err
+			}
+			chartBundleFiles, err = utils.ExpandBundleParts(out, chartBundleFiles)
+			if err != nil {
+				return err
+			}
+
+			return extractCharts(out, chartBundleFiles, decryptionKeyFiles, toDir, generateIndex)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&chartBundleFiles, "chart-bundle", nil,
+		"Tarball containing Helm charts to extract. Can also be a glob pattern.")
+	_ = cmd.MarkFlagRequired("chart-bundle")
+	cmd.Flags().StringSliceVar(&decryptionKeyFiles, "decrypt-key", nil,
+		"File containing age private keys (AGE-SECRET-KEY-1...) or a passphrase to decrypt encrypted bundles with. "+
+			"Can be specified multiple times")
+	cmd.Flags().StringVar(&toDir, "to-dir", "",
+		"Directory to extract Helm charts to as .tgz files. Created if it does not exist.")
+	_ = cmd.MarkFlagRequired("to-dir")
+	cmd.Flags().BoolVar(&generateIndex, "generate-index", false,
+		"Generate a Helm repository index.yaml in the directory so it can be served as a Helm repository")
+
+	return cmd
+}
+
+// extractCharts pulls every chart listed in the bundles' charts.yaml from the bundles to toDir as .tgz files.
+// Encrypted bundles are decrypted with the keys read from decryptionKeyFiles.
+func extractCharts(
+	out output.Output, chartBundleFiles, decryptionKeyFiles []string, toDir string, generateIndex bool,
+) error {
+	cleaner := cleanup.NewCleaner()
+	defer cleaner.Cleanup()
+
+	out.StartOperation("Creating temporary directory")
+	tempDir, err := os.MkdirTemp("", ".chart-bundle-*")
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleaner.AddCleanupFn(func() { _ = os.RemoveAll(tempDir) })
+	out.EndOperationWithStatus(output.Success())
+
+	_, cfg, err := utils.ExtractConfigs(tempDir, out, decryptionKeyFiles, chartBundleFiles...)
+	if err != nil {
+		return err
+	}
+	if cfg == nil || cfg.TotalCharts() == 0 {
+		return errors.New(
+			"no Helm charts found: please check that you have specified valid Helm chart bundle(s)",
+		)
+	}
+
+	out.StartOperation(fmt.Sprintf("Creating output directory %s", toDir))
+	if err := os.MkdirAll(toDir, 0o755); err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	out.StartOperation("Starting temporary Docker registry")
+	storage, err := registry.ArchiveStorage("", decryptionKeyFiles, chartBundleFiles...)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create storage for Docker registry from supplied bundles: %w", err)
+	}
+	reg, err := registry.NewRegistry(registry.Config{Storage: storage})
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create local Docker registry: %w", err)
+	}
+	go func() {
+		if err := reg.ListenAndServe(output.NewOutputLogr(out)); err != nil {
+			out.Error(err, "error serving Docker registry")
+			os.Exit(2)
+		}
+	}()
+	defer func() { _ = reg.Shutdown(context.Background()) }()
+	// Helm does not retry failed requests.
+	if err := utils.WaitForRegistry(reg.Address()); err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return err
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	helmClient, helmCleanup := helm.NewClient(out)
+	cleaner.AddCleanupFn(func() { _ = helmCleanup() })
+
+	for _, repoName := range cfg.SortedRepositoryNames() {
+		repoConfig := cfg.Repositories[repoName]
+		for _, chartName := range repoConfig.SortedChartNames() {
+			for _, chartVersion := range repoConfig.Charts[chartName] {
+				out.StartOperation(fmt.Sprintf("Extracting Helm chart %s:%s", chartName, chartVersion))
+				if _, err := helmClient.GetChartFromRepo(
+					toDir,
+					"",
+					fmt.Sprintf("%s://%s/charts/%s", helm.OCIScheme, reg.Address(), chartName),
+					chartVersion,
+					helm.PlainHTTPOpt(),
+				); err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return fmt.Errorf("failed to extract Helm chart %s:%s: %w", chartName, chartVersion, err)
+				}
+				out.EndOperationWithStatus(output.Success())
+			}
+		}
+	}
+
+	if generateIndex {
+		out.StartOperation("Generating Helm repository index")
+		if err := helmClient.CreateHelmRepoIndex(toDir); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+		out.EndOperationWithStatus(output.Success())
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package chartbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	repov1 "helm.sh/helm/v4/pkg/repo/v1"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/helm"
+)
+
+// writeTestChart writes a minimal packaged chart to dir.
+func writeTestChart(t *testing.T, dir, chartName, chartVersion string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	files := map[string]string{
+		"Chart.yaml":               fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n", chartName, chartVersion),
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n",
+	}
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: chartName + "/" + name,
+			Mode: 0o644,
+			Size: int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	chartFile := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", chartName, chartVersion))
+	require.NoError(t, os.WriteFile(chartFile, buf.Bytes(), 0o644))
+	return chartFile
+}
+
+// writeTestChartBundle creates a bundle containing the charts podinfo 6.1.0 and 6.2.0 and nginx 1.0.0.
+func writeTestChartBundle(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	reg, err := registry.NewRegistry(registry.Config{Storage: registry.FilesystemStorage(dir)})
+	require.NoError(t, err)
+	go func() {
+		assert.NoError(t, reg.ListenAndServe(logr.Discard()))
+	}()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", reg.Address())
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+	helmClient, helmCleanup := helm.NewClient(out)
+	t.Cleanup(func() { _ = helmCleanup() })
+
+	chartsDir := t.TempDir()
+	charts := map[string][]string{"podinfo": {"6.1.0", "6.2.0"}, "nginx": {"1.0.0"}}
+	for chartName, chartVersions := range charts {
+		for _, chartVersion := range chartVersions {
+			require.NoError(t, helmClient.PushHelmChartToPlainHTTPOCIRegistry(
+				writeTestChart(t, chartsDir, chartName, chartVersion),
+				fmt.Sprintf("%s://%s/charts", helm.OCIScheme, reg.Address()),
+			))
+		}
+	}
+	require.NoError(t, reg.Shutdown(context.Background()))
+
+	require.NoError(t, config.WriteSanitizedHelmChartsConfig(
+		filepath.Join(dir, "charts.yaml"),
+		config.HelmChartsConfig{
+			Repositories: map[string]config.HelmRepositorySyncConfig{
+				"example": {RepoURL: "https://charts.example.com", Charts: charts},
+			},
+		},
+	))
+
+	bundleFile := filepath.Join(t.TempDir(), "charts-bundle.tar")
+	require.NoError(t, archive.ArchiveDirectory(dir, bundleFile))
+	return bundleFile
+}
+
+func TestExtractCharts(t *testing.T) {
+	bundleFile := writeTestChartBundle(t)
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+
+	toDir := filepath.Join(t.TempDir(), "charts")
+	require.NoError(t, extractCharts(out, []string{bundleFile}, nil, toDir, true))
+
+	for _, chartFile := range []string{"nginx-1.0.0.tgz", "podinfo-6.1.0.tgz", "podinfo-6.2.0.tgz"} {
+		chrt, err := helm.LoadChart(filepath.Join(toDir, chartFile))
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			chartFile,
+			fmt.Sprintf("%s-%s.tgz", chrt.Name(), chrt.MetadataAsMap()["Version"]),
+		)
+	}
+
+	index, err := repov1.LoadIndexFile(filepath.Join(toDir, "index.yaml"))
+	require.NoError(t, err)
+	assert.Len(t, index.Entries["podinfo"], 2)
+	assert.Len(t, index.Entries["nginx"], 1)
+}
+
+func TestExtractChartsNoCharts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, config.WriteSanitizedImagesConfigs(
+		filepath.Join(dir, "images.yaml"),
+		config.ImagesConfig{"example.com": {Images: map[string][]string{"app": {"v1"}}}},
+	))
+	bundleFile := filepath.Join(t.TempDir(), "images-bundle.tar")
+	require.NoError(t, archive.ArchiveDirectory(dir, bundleFile))
+
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+	require.ErrorContains(
+		t,
+		extractCharts(out, []string{bundleFile}, nil, t.TempDir(), false),
+		"no Helm charts found",
+	)
+}
@@ -8,14 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"runtime"
+	"slices"
+	"sync"
 
+	"github.com/containerd/platforms"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/google/go-containerregistry/pkg/v1/remote"
-	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/spf13/cobra"
+	"github.com/thediveo/enumflag/v2"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 
@@ -23,24 +25,50 @@ import (
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
 	"github.com/mesosphere/mindthegap/containerd"
+	"github.com/mesosphere/mindthegap/docker/engine"
 	"github.com/mesosphere/mindthegap/docker/registry"
-	"github.com/mesosphere/mindthegap/images/httputils"
 )
 
 func NewCommand(out output.Output) *cobra.Command {
 	var (
-		imageBundleFiles    []string
-		containerdNamespace string
+		imageBundleFiles       []string
+		decryptionKeyFiles     []string
+		target                 importTarget
+		host                   string
+		containerdAddress      string
+		containerdNamespace    string
+		unpack                 bool
+		snapshotter            string
+		imageImportConcurrency int
+		importPlatforms        = flags.NewPlatformsValue()
+		allPlatforms           bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "image-bundle",
-		Short: "Import images from image bundles into Containerd",
+		Short: "Import images from image bundles into containerd, Docker or Podman",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if err := cmd.ValidateRequiredFlags(); err != nil {
 				return err
 			}
 
+			if target == ContainerdTarget && cmd.Flags().Changed("host") {
+				return errors.New("--host can only be specified with --target docker or --target podman")
+			}
+			if target != ContainerdTarget {
+				if allPlatforms || len(importPlatforms.GetSlice()) > 1 {
+					return errors.New(
+						"docker and podman image stores can only hold a single platform per image: " +
+							"please specify at most one --platform",
+					)
+				}
+				for _, f := range []string{"containerd-address", "containerd-namespace", "unpack", "snapshotter"} {
+					if cmd.Flags().Changed(f) {
+						return fmt.Errorf("--%s can only be specified with --target containerd", f)
+					}
+				}
+			}
+
 			if err := flags.ValidateFlagsThatRequireValues(cmd, "image-bundle"); err != nil {
 				return err
 			}
@@ -64,7 +92,11 @@ func NewCommand(out output.Output) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			cfg, _, err := utils.ExtractConfigs(tempDir, out, imageBundleFiles...)
+			imageBundleFiles, err = utils.ExpandBundleParts(out, imageBundleFiles)
+			if err != nil {
+				return err
+			}
+			cfg, _, err := utils.ExtractConfigs(tempDir, out, decryptionKeyFiles, imageBundleFiles...)
 			if err != nil {
 				return err
 			}
@@ -75,7 +107,7 @@ func NewCommand(out output.Output) *cobra.Command {
 			}
 
 			out.StartOperation("Starting temporary Docker registry")
-			storage, err := registry.ArchiveStorage("", imageBundleFiles...)
+			storage, err := registry.ArchiveStorage("", decryptionKeyFiles, imageBundleFiles...)
 			if err != nil {
 				out.EndOperationWithStatus(output.Failure())
 				return fmt.Errorf("failed to create storage for Docker registry from supplied bundles: %w", err)
@@ -96,44 +128,75 @@ func NewCommand(out output.Output) *cobra.Command {
 			}()
 			out.EndOperationWithStatus(output.Success())
 
-			ociExportsTempDir, err := os.MkdirTemp("", ".oci-exports-*")
-			if err != nil {
-				return fmt.Errorf("failed to create temporary directory for OCI exports: %w", err)
-			}
-			cleaner.AddCleanupFn(func() { _ = os.RemoveAll(ociExportsTempDir) })
+			var (
+				importImage importFunc
+				targetName  = importTargets[target][0]
+			)
+			switch target {
+			case ContainerdTarget:
+				out.StartOperation(fmt.Sprintf("Connecting to containerd at %s", containerdAddress))
+				importer, err := containerd.NewImporter(containerdAddress, containerdNamespace)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return err
+				}
+				defer importer.Close()
 
-			sourceTLSRoundTripper, err := httputils.InsecureTLSRoundTripper(remote.DefaultTransport)
-			if err != nil {
-				out.Error(err, "error configuring TLS for source registry")
-				os.Exit(2)
+				platformMatcher, err := containerdPlatformMatcher(importPlatforms.GetSlice(), allPlatforms)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return err
+				}
+				importOpts := []containerd.ImportOption{containerd.WithPlatforms(platformMatcher)}
+				if unpack {
+					importOpts = append(importOpts, containerd.WithUnpack(snapshotter))
+				}
+				importImage = containerdImportFunc(importer, importOpts...)
+			case DockerTarget, PodmanTarget:
+				if host == "" && target == PodmanTarget {
+					host = engine.PodmanHost()
+				}
+				out.StartOperation(fmt.Sprintf("Connecting to %s", targetName))
+				loader, err := engine.NewLoader(host)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return err
+				}
+				defer loader.Close()
+
+				platform, err := enginePlatform(cmd.Context(), importPlatforms.GetSlice(), loader.Platform)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return err
+				}
+				importImage, err = engineImportFunc(loader, platform)
+				if err != nil {
+					out.EndOperationWithStatus(output.Failure())
+					return err
+				}
 			}
+			out.EndOperationWithStatus(output.Success())
 
-			// Import the images from the merged bundle config.
-			for registryName, registryConfig := range *cfg {
-				for imageName, imageTags := range registryConfig.Images {
-					for _, imageTag := range imageTags {
-						srcImageName := fmt.Sprintf("%s/%s:%s", reg.Address(), imageName, imageTag)
-						destImageName := fmt.Sprintf("%s/%s:%s", registryName, imageName, imageTag)
+			importGauge := &output.ProgressGauge{}
+			importGauge.SetCapacity(cfg.TotalImages())
+			importGauge.SetStatus(fmt.Sprintf("Importing images into %s", targetName))
+			out.StartOperationWithProgress(importGauge)
 
-						out.StartOperation(fmt.Sprintf("Importing %s", destImageName))
+			eg, egCtx := errgroup.WithContext(cmd.Context())
+			eg.SetLimit(imageImportConcurrency)
 
-						ref, err := name.ParseReference(srcImageName, name.StrictValidation)
-						if err != nil {
-							out.EndOperationWithStatus(output.Failure())
-							return err
-						}
+			var (
+				skippedMu sync.Mutex
+				skipped   []string
+			)
 
-						v1Image, err := remote.Image(
-							ref,
-							remote.WithTransport(sourceTLSRoundTripper),
-							remote.WithPlatform(
-								v1.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH},
-							),
-						)
-						if err != nil {
-							out.EndOperationWithStatus(output.Failure())
-							return err
-						}
+			// Import the images from the merged bundle config.
+			for _, registryName := range cfg.SortedRegistryNames() {
+				registryConfig := (*cfg)[registryName]
+				for _, imageName := range registryConfig.SortedImageNames() {
+					for _, imageTag := range registryConfig.Images[imageName] {
+						srcImageName := fmt.Sprintf("%s/%s:%s", reg.Address(), imageName, imageTag)
+						destImageName := fmt.Sprintf("%s/%s:%s", registryName, imageName, imageTag)
 
 						tag, err := name.NewTag(destImageName, name.StrictValidation)
 						if err != nil {
@@ -141,32 +204,33 @@ func NewCommand(out output.Output) *cobra.Command {
 							return err
 						}
 
-						exportTarball := filepath.Join(ociExportsTempDir, "docker-archive.tar")
-
-						if err := tarball.MultiWriteToFile(
-							exportTarball,
-							map[name.Tag]v1.Image{tag: v1Image},
-						); err != nil {
-							out.EndOperationWithStatus(output.Failure())
-							return err
-						}
-
-						ctrOutput, err := containerd.ImportImageArchive(
-							context.TODO(), exportTarball, containerdNamespace,
-						)
-						if err != nil {
-							out.Warn(string(ctrOutput))
-							out.EndOperationWithStatus(output.Failure())
-							return err
-						}
+						eg.Go(func() error {
+							isSkipped, err := importImage(egCtx, srcImageName, tag)
+							if err != nil {
+								return fmt.Errorf("failed to import %s: %w", destImageName, err)
+							}
+							if isSkipped {
+								skippedMu.Lock()
+								skipped = append(skipped, destImageName)
+								skippedMu.Unlock()
+							}
+							importGauge.Inc()
+							return nil
+						})
+					}
+				}
+			}
 
-						out.V(4).Info(string(ctrOutput))
+			if err := eg.Wait(); err != nil {
+				out.EndOperationWithStatus(output.Failure())
+				return err
+			}
 
-						_ = os.Remove(exportTarball)
+			out.EndOperationWithStatus(output.Success())
 
-						out.EndOperationWithStatus(output.Success())
-					}
-				}
+			slices.Sort(skipped)
+			for _, skippedImage := range skipped {
+				out.Warnf("Skipped %s: OCI artifacts cannot be imported into %s", skippedImage, targetName)
 			}
 
 			return nil
@@ -174,10 +238,73 @@ func NewCommand(out output.Output) *cobra.Command {
 	}
 
 	cmd.Flags().StringSliceVar(&imageBundleFiles, "image-bundle", nil,
-		"Tarball containing list of images to import. Can also be a glob pattern.")
+		"Tarball containing list of images to import, or the .parts.yaml index of a split bundle. "+
+			"Can also be a glob pattern.")
 	_ = cmd.MarkFlagRequired("image-bundle")
+	cmd.Flags().StringSliceVar(&decryptionKeyFiles, "decrypt-key", nil,
+		"File containing age private keys (AGE-SECRET-KEY-1...) or a passphrase to decrypt encrypted bundles with. "+
+			"Can be specified multiple times")
+	cmd.Flags().Var(
+		enumflag.New(&target, "string", importTargets, enumflag.EnumCaseSensitive),
+		"target",
+		`Image store to import images into: one of "containerd", "docker" or "podman"`,
+	)
+	cmd.Flags().StringVar(&host, "host", "",
+		"Address of the Docker or Podman API socket, e.g. unix:///var/run/docker.sock (defaults to DOCKER_HOST "+
+			"for docker and CONTAINER_HOST or the default Podman socket for podman)")
 	cmd.Flags().StringVar(&containerdNamespace, "containerd-namespace", "k8s.io",
 		"Containerd namespace to import images into")
+	cmd.Flags().StringVar(&containerdAddress, "containerd-address", containerd.DefaultAddress,
+		"Address of the containerd socket")
+	cmd.Flags().BoolVar(&unpack, "unpack", false,
+		"Unpack imported images into the containerd snapshotter")
+	cmd.Flags().StringVar(&snapshotter, "snapshotter", "",
+		"Snapshotter to unpack images into when --unpack is specified (defaults to the containerd default snapshotter)")
+	cmd.Flags().IntVar(&imageImportConcurrency, "image-import-concurrency", 1, "Image import concurrency")
+	cmd.Flags().Var(&importPlatforms, "platform",
+		"Platforms to import (defaults to the platform mindthegap is running on for containerd, or the platform "+
+			"of the Docker or Podman engine)")
+	cmd.Flags().BoolVar(&allPlatforms, "all-platforms", false, "Import all platforms included in the bundles")
+	cmd.MarkFlagsMutuallyExclusive("platform", "all-platforms")
 
 	return cmd
 }
+
+// containerdPlatformMatcher returns the matcher for the platforms to import into containerd: all platforms, the
+// requested platforms in order of preference, or the platform mindthegap is running on if none are requested.
+func containerdPlatformMatcher(requested []string, allPlatforms bool) (platforms.MatchComparer, error) {
+	if allPlatforms {
+		return platforms.All, nil
+	}
+	if len(requested) == 0 {
+		return platforms.Default(), nil
+	}
+
+	ps := make([]ocispec.Platform, 0, len(requested))
+	for _, p := range requested {
+		parsed, err := platforms.Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse platform %s: %w", p, err)
+		}
+		ps = append(ps, parsed)
+	}
+	return platforms.Ordered(ps...), nil
+}
+
+// enginePlatform returns the single platform to load into a Docker or Podman image store: the requested platform,
+// or the platform of the engine if none is requested.
+func enginePlatform(
+	ctx context.Context,
+	requested []string,
+	defaultPlatform func(context.Context) (v1.Platform, error),
+) (v1.Platform, error) {
+	if len(requested) == 0 {
+		return defaultPlatform(ctx)
+	}
+
+	parsed, err := v1.ParsePlatform(requested[0])
+	if err != nil {
+		return v1.Platform{}, fmt.Errorf("failed to parse platform %s: %w", requested[0], err)
+	}
+	return *parsed, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagebundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/containerd/platforms"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// validateFlags parses args and runs the flag validation of the command without importing anything.
+func validateFlags(t *testing.T, args ...string) error {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	cmd := NewCommand(output.NewNonInteractiveShell(buf, buf, 0))
+	if err := cmd.ParseFlags(append([]string{"--image-bundle", "bundle.tar"}, args...)); err != nil {
+		return err
+	}
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		return err
+	}
+	return cmd.PreRunE(cmd, nil)
+}
+
+func TestPlatformFlagValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{{
+		name: "containerd defaults",
+	}, {
+		name: "containerd with multiple platforms",
+		args: []string{"--platform", "linux/amd64", "--platform", "linux/arm/v7"},
+	}, {
+		name: "containerd with all platforms",
+		args: []string{"--all-platforms"},
+	}, {
+		name:    "platform and all platforms",
+		args:    []string{"--platform", "linux/amd64", "--all-platforms"},
+		wantErr: "none of the others can be",
+	}, {
+		name: "docker with single platform",
+		args: []string{"--target", "docker", "--platform", "linux/arm64"},
+	}, {
+		name:    "docker with multiple platforms",
+		args:    []string{"--target", "docker", "--platform", "linux/amd64,linux/arm64"},
+		wantErr: "please specify at most one --platform",
+	}, {
+		name:    "podman with all platforms",
+		args:    []string{"--target", "podman", "--all-platforms"},
+		wantErr: "please specify at most one --platform",
+	}, {
+		name:    "invalid platform",
+		args:    []string{"--platform", "linux"},
+		wantErr: "invalid argument",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateFlags(t, tt.args...)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestContainerdPlatformMatcher(t *testing.T) {
+	t.Parallel()
+
+	amd64 := ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := ocispec.Platform{OS: "linux", Architecture: "arm64"}
+	armv7 := ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+	armv6 := ocispec.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}
+
+	m, err := containerdPlatformMatcher(nil, true)
+	require.NoError(t, err)
+	for _, p := range []ocispec.Platform{amd64, arm64, armv7, armv6} {
+		assert.True(t, m.Match(p), platforms.Format(p))
+	}
+
+	// Defaults to the platform mindthegap is running on.
+	m, err = containerdPlatformMatcher(nil, false)
+	require.NoError(t, err)
+	assert.True(t, m.Match(platforms.DefaultSpec()))
+
+	m, err = containerdPlatformMatcher([]string{"linux/arm64", "linux/arm/v7"}, false)
+	require.NoError(t, err)
+	assert.True(t, m.Match(arm64))
+	assert.True(t, m.Match(armv7))
+	assert.False(t, m.Match(amd64))
+	assert.False(t, m.Match(armv6))
+	// Platforms are preferred in the order they are requested.
+	assert.True(t, m.Less(arm64, armv7))
+
+	_, err = containerdPlatformMatcher([]string{"linux/arm64/v8/extra"}, false)
+	require.ErrorContains(t, err, "failed to parse platform")
+}
+
+func TestEnginePlatform(t *testing.T) {
+	t.Parallel()
+
+	enginePlatformCalled := false
+	defaultPlatform := func(context.Context) (v1.Platform, error) {
+		enginePlatformCalled = true
+		return v1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, nil
+	}
+
+	// Defaults to the platform of the engine.
+	platform, err := enginePlatform(context.Background(), nil, defaultPlatform)
+	require.NoError(t, err)
+	assert.Equal(t, v1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, platform)
+	assert.True(t, enginePlatformCalled)
+
+	enginePlatformCalled = false
+	platform, err = enginePlatform(context.Background(), []string{"linux/arm/v7"}, defaultPlatform)
+	require.NoError(t, err)
+	assert.Equal(t, v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, platform)
+	assert.False(t, enginePlatformCalled)
+
+	_, err = enginePlatform(context.Background(), nil, func(context.Context) (v1.Platform, error) {
+		return v1.Platform{}, errors.New("engine unavailable")
+	})
+	require.ErrorContains(t, err, "engine unavailable")
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagebundle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/thediveo/enumflag/v2"
+
+	"github.com/mesosphere/mindthegap/containerd"
+	"github.com/mesosphere/mindthegap/docker/engine"
+	"github.com/mesosphere/mindthegap/images/httputils"
+)
+
+type importTarget enumflag.Flag
+
+const (
+	ContainerdTarget importTarget = iota
+	DockerTarget
+	PodmanTarget
+)
+
+var importTargets = map[importTarget][]string{
+	ContainerdTarget: {"containerd"},
+	DockerTarget:     {"docker"},
+	PodmanTarget:     {"podman"},
+}
+
+// importFunc imports the image srcImageName from the temporary registry serving the bundles, naming it destTag.
+// Images that cannot be imported into the target, such as OCI artifacts into Docker, are skipped.
+type importFunc func(ctx context.Context, srcImageName string, destTag name.Tag) (skipped bool, err error)
+
+func containerdImportFunc(importer *containerd.Importer, opts ...containerd.ImportOption) importFunc {
+	return func(ctx context.Context, srcImageName string, destTag name.Tag) (bool, error) {
+		return false, importer.ImportImage(ctx, srcImageName, destTag.Name(), opts...)
+	}
+}
+
+// engineImportFunc loads images into a Docker or Podman image store. These can only hold a single platform per image
+// tag, so only the image for the platform of the engine is loaded.
+func engineImportFunc(loader *engine.Loader, platform v1.Platform) (importFunc, error) {
+	srcTLSRoundTripper, err := httputils.InsecureTLSRoundTripper(remote.DefaultTransport)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring TLS for source registry: %w", err)
+	}
+
+	return func(ctx context.Context, srcImageName string, destTag name.Tag) (bool, error) {
+		ref, err := name.ParseReference(srcImageName, name.StrictValidation)
+		if err != nil {
+			return false, err
+		}
+
+		img, err := remote.Image(
+			ref,
+			remote.WithTransport(srcTLSRoundTripper),
+			remote.WithContext(ctx),
+			remote.WithPlatform(platform),
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to read image for platform %s from bundle: %w", platform, err)
+		}
+		manifest, err := img.Manifest()
+		if err != nil {
+			return false, fmt.Errorf("failed to read image manifest: %w", err)
+		}
+		if !manifest.Config.MediaType.IsConfig() {
+			return true, nil
+		}
+
+		return false, loader.LoadImage(ctx, destTag, img)
+	}, nil
+}
@@ -8,15 +8,17 @@ import (
 
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/importcmd/chartbundle"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/importcmd/imagebundle"
 )
 
 func NewCommand(out output.Output) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "import",
-		Short: "Import images from an image bundle into Containerd",
+		Short: "Import images and Helm charts from bundles",
 	}
 
 	cmd.AddCommand(imagebundle.NewCommand(out))
+	cmd.AddCommand(chartbundle.NewCommand(out))
 	return cmd
 }
@@ -0,0 +1,398 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/mholt/archives"
+	"github.com/spf13/cobra"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/cleanup"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
+	"github.com/mesosphere/mindthegap/config"
+)
+
+const (
+	imagesConfigFileName = "images.yaml"
+	chartsConfigFileName = "charts.yaml"
+)
+
+// tagLinkRegexp matches the file in the registry storage recording the digest a tag currently points to.
+var tagLinkRegexp = regexp.MustCompile(
+	`^docker/registry/v2/repositories/(.+)/_manifests/tags/([^/]+)/current/link$`,
+)
+
+func NewCommand(out output.Output) *cobra.Command {
+	var (
+		outputFile         string
+		overwrite          bool
+		decryptionKeyFiles []string
+		encryption         flags.Encryption
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bundle <bundle>...",
+		Short: "Merge bundles into a single bundle without network access",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recipients, err := encryption.ParseRecipients()
+			if err != nil {
+				return err
+			}
+
+			bundleFiles, err := utils.FilesWithGlobs(args)
+			if err != nil {
+				return err
+			}
+			bundleFiles, err = utils.ExpandBundleParts(out, bundleFiles)
+			if err != nil {
+				return err
+			}
+
+			if !overwrite {
+				out.StartOperation("Checking if output file already exists")
+				_, err := os.Stat(outputFile)
+				switch {
+				case err == nil:
+					out.EndOperationWithStatus(output.Failure())
+					return fmt.Errorf(
+						"%s already exists: specify --overwrite to overwrite existing file", outputFile,
+					)
+				case !errors.Is(err, os.ErrNotExist):
+					out.EndOperationWithStatus(output.Failure())
+					return fmt.Errorf("failed to check if output file %s already exists: %w", outputFile, err)
+				default:
+					out.EndOperationWithStatus(output.Success())
+				}
+			}
+
+			return mergeBundles(out, bundleFiles, decryptionKeyFiles, outputFile, recipients)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "Output file to write merged bundle to")
+	_ = cmd.MarkFlagRequired("output-file")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite output file if it already exists")
+	cmd.Flags().StringSliceVar(&decryptionKeyFiles, "decrypt-key", nil,
+		"File containing age private keys (AGE-SECRET-KEY-1...) or a passphrase to decrypt encrypted bundles with. "+
+			"Can be specified multiple times")
+	encryption.AddFlags(cmd)
+
+	return cmd
+}
+
+// mergeBundles merges the registry storage and configs of all bundles into a single bundle written to outputFile.
+// Blobs and manifests present in more than one bundle are only included once. Merging fails if a tag points to
+// different digests in different bundles. Encrypted bundles are decrypted with the keys read from
+// decryptionKeyFiles. The merged bundle is encrypted for the recipients if there are any.
+func mergeBundles(
+	out output.Output, bundleFiles, decryptionKeyFiles []string, outputFile string, recipients []age.Recipient,
+) error {
+	identities, err := archive.ParseDecryptionKeys(decryptionKeyFiles)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		if err := utils.WarnUnencryptedOutput(out, bundleFiles); err != nil {
+			return err
+		}
+	}
+
+	cleaner := cleanup.NewCleaner()
+	defer cleaner.Cleanup()
+
+	out.StartOperation("Creating temporary directory")
+	outputFileAbs, err := filepath.Abs(outputFile)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to determine where to create temporary directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(filepath.Dir(outputFileAbs), ".bundle-*")
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleaner.AddCleanupFn(func() { _ = os.RemoveAll(tempDir) })
+	configsDir, err := os.MkdirTemp("", ".bundle-configs-*")
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleaner.AddCleanupFn(func() { _ = os.RemoveAll(configsDir) })
+	out.EndOperationWithStatus(output.Success())
+
+	m := &merger{
+		dir:        tempDir,
+		configsDir: configsDir,
+		identities: identities,
+		tags:       map[string]taggedDigest{},
+		chunks:     map[string]bool{},
+	}
+	for _, bundleFile := range bundleFiles {
+		out.StartOperation(fmt.Sprintf("Merging bundle %s", bundleFile))
+		if err := m.mergeBundle(bundleFile); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+		out.EndOperationWithStatus(output.Success())
+	}
+
+	if len(m.conflicts) > 0 {
+		return fmt.Errorf(
+			"bundles contain tags that point to different digests:\n  %s",
+			strings.Join(m.conflicts, "\n  "),
+		)
+	}
+
+	out.StartOperation("Writing merged bundle configs")
+	if m.imagesCfg != nil {
+		if err := config.WriteSanitizedImagesConfigs(
+			filepath.Join(tempDir, imagesConfigFileName), *m.imagesCfg,
+		); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+	}
+	if m.chartsCfg != nil {
+		if err := config.WriteSanitizedHelmChartsConfig(
+			filepath.Join(tempDir, chartsConfigFileName), *m.chartsCfg,
+		); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	out.StartOperation(fmt.Sprintf("Archiving merged bundle to %s", outputFile))
+	if len(recipients) > 0 {
+		err = archive.ArchiveDirectoryEncrypted(tempDir, outputFile, recipients...)
+	} else {
+		err = archive.ArchiveDirectory(tempDir, outputFile)
+	}
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return fmt.Errorf("failed to create bundle tarball: %w", err)
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	return nil
+}
+
+type taggedDigest struct {
+	digest     string
+	bundleFile string
+}
+
+// merger extracts the contents of bundles into a single directory.
+type merger struct {
+	dir        string
+	configsDir string
+	// identities decrypt encrypted bundles.
+	identities []age.Identity
+
+	imagesCfg *config.ImagesConfig
+	chartsCfg *config.HelmChartsConfig
+
+	// tags records the digest of every tag and the first bundle that contained it, keyed by repository and tag.
+	tags      map[string]taggedDigest
+	conflicts []string
+
+	// chunks records whether the chunks of files split across the parts of a split bundle are extracted, or skipped
+	// because the file was already extracted from another bundle.
+	chunks map[string]bool
+
+	configsMerged int
+}
+
+func (m *merger) mergeBundle(bundleFile string) error {
+	encrypted, err := archive.IsEncrypted(bundleFile)
+	if err != nil {
+		return err
+	}
+	var f io.ReadCloser
+	if encrypted {
+		f, err = archive.OpenDecrypted(bundleFile, m.identities...)
+		if err != nil {
+			return err
+		}
+	} else {
+		f, err = os.Open(bundleFile)
+		if err != nil {
+			return fmt.Errorf("failed to open bundle %s: %w", bundleFile, err)
+		}
+	}
+	defer f.Close()
+
+	archiver, archiveStream, err := archives.Identify(context.Background(), bundleFile, f)
+	if err != nil {
+		return fmt.Errorf("failed to identify archive format of bundle %s: %w", bundleFile, err)
+	}
+
+	// Disallow tar.gz and tar.bz2 archives as noted in the docs for github.com/mholt/archives that
+	// traversing compressed tar archives is extremely slow and inefficient. Benchmarking confirms
+	// that this is indeed the case, so we don't support them.
+	ext := archiver.Extension()
+	if ext == ".tar.gz" || ext == ".tar.bz2" {
+		return fmt.Errorf("compressed tar archives (%s) are not supported", ext)
+	}
+
+	extractor, ok := archiver.(archives.Extractor)
+	if !ok {
+		return fmt.Errorf("bundle %s is not a valid archive", bundleFile)
+	}
+
+	err = extractor.Extract(
+		context.Background(),
+		archiveStream,
+		func(ctx context.Context, fi archives.FileInfo) error {
+			if fi.IsDir() {
+				return nil
+			}
+
+			name := path.Clean(fi.NameInArchive)
+			if !fs.ValidPath(name) {
+				return fmt.Errorf("invalid file name %q", fi.NameInArchive)
+			}
+			// Incomplete uploads are never referenced by manifests so there is no need to include them.
+			if strings.Contains(name, "/_uploads/") {
+				return nil
+			}
+
+			r, err := fi.Open()
+			if err != nil {
+				return fmt.Errorf("failed to open %s: %w", name, err)
+			}
+			defer r.Close()
+
+			return m.mergeFile(bundleFile, name, r)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to merge bundle %s: %w", bundleFile, err)
+	}
+
+	return nil
+}
+
+func (m *merger) mergeFile(bundleFile, name string, r io.Reader) error {
+	switch name {
+	case imagesConfigFileName, chartsConfigFileName:
+		return m.mergeConfig(name, r)
+	}
+
+	if chunkedName, i, ok := archive.ParseChunkName(name); ok {
+		return m.mergeChunk(chunkedName, i, r)
+	}
+
+	if matches := tagLinkRegexp.FindStringSubmatch(name); matches != nil {
+		digest, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		tag := matches[1] + ":" + matches[2]
+		existing, ok := m.tags[tag]
+		if !ok {
+			m.tags[tag] = taggedDigest{digest: string(digest), bundleFile: bundleFile}
+			return writeFile(filepath.Join(m.dir, name), strings.NewReader(string(digest)), false)
+		}
+		if existing.digest != string(digest) {
+			m.conflicts = append(m.conflicts, fmt.Sprintf(
+				"%s is %s in %s and %s in %s",
+				tag, existing.digest, existing.bundleFile, digest, bundleFile,
+			))
+		}
+		return nil
+	}
+
+	// All other files in the registry storage are content addressed, so files already extracted from another
+	// bundle have the same content.
+	target := filepath.Join(m.dir, name)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+	return writeFile(target, r, false)
+}
+
+// mergeChunk appends the chunk i of a file split across the parts of a split bundle to the file, so that the merged
+// bundle contains the whole file. Chunks are read in order as parts are listed in order in the parts index.
+func (m *merger) mergeChunk(name string, i int, r io.Reader) error {
+	target := filepath.Join(m.dir, name)
+	if i == 0 {
+		_, err := os.Stat(target)
+		m.chunks[name] = err != nil
+		if err == nil {
+			return nil
+		}
+		return writeFile(target, r, false)
+	}
+
+	extract, ok := m.chunks[name]
+	if !ok {
+		return fmt.Errorf("chunk %d of %s found before its first chunk: parts must be merged in order", i, name)
+	}
+	if !extract {
+		return nil
+	}
+	return writeFile(target, r, true)
+}
+
+func (m *merger) mergeConfig(name string, r io.Reader) error {
+	m.configsMerged++
+	configFile := filepath.Join(m.configsDir, fmt.Sprintf("%d-%s", m.configsMerged, name))
+	if err := writeFile(configFile, r, false); err != nil {
+		return err
+	}
+
+	if name == imagesConfigFileName {
+		cfg, err := config.ParseImagesConfigFile(configFile)
+		if err != nil {
+			return err
+		}
+		m.imagesCfg = m.imagesCfg.Merge(cfg)
+		return nil
+	}
+
+	cfg, err := config.ParseHelmChartsConfigFile(configFile)
+	if err != nil {
+		return err
+	}
+	m.chartsCfg = m.chartsCfg.Merge(cfg)
+	return nil
+}
+
+func writeFile(target string, r io.Reader, appendTo bool) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", target, err)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendTo {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(target, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return f.Close()
+}
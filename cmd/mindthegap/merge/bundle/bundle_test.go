@@ -0,0 +1,164 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/archive/archivetest"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+func newTestImage(t *testing.T, size int64) v1.Image {
+	t.Helper()
+	img, err := random.Image(size, 2)
+	require.NoError(t, err)
+	return img
+}
+
+// writeTestBundle creates a bundle containing the images, keyed by repository and tag, from example.com. If
+// maxPartSize is not zero, the bundle is split into parts and the path of the parts index is returned.
+func writeTestBundle(t *testing.T, imgs map[string]v1.Image, maxPartSize int64) string {
+	t.Helper()
+
+	images := make(map[string]remote.Taggable, len(imgs))
+	for ref, img := range imgs {
+		images["example.com/"+ref] = img
+	}
+	if maxPartSize == 0 {
+		return bundletest.WriteBundle(t, images, config.HelmChartsConfig{})
+	}
+
+	bundleFile := filepath.Join(t.TempDir(), "bundle.tar")
+	_, err := archive.ArchiveDirectoryInParts(
+		bundletest.WriteBundleDir(t, images, config.HelmChartsConfig{}), bundleFile, maxPartSize,
+	)
+	require.NoError(t, err)
+	return archive.PartsIndexFile(bundleFile)
+}
+
+func TestMergeBundles(t *testing.T) {
+	shared := newTestImage(t, 64)
+	app := newTestImage(t, 64)
+	// Layers larger than a part are split into chunks across parts.
+	large := newTestImage(t, 3<<19)
+
+	bundleA := writeTestBundle(t, map[string]v1.Image{"team-a/app:v1": app, "shared:v1": shared}, 0)
+	bundleB := writeTestBundle(
+		t, map[string]v1.Image{"team-b/large:v1": large, "shared:v1": shared}, archive.MinPartSize,
+	)
+
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+	bundleFiles, err := archive.ExpandPartsIndexes([]string{bundleA, bundleB})
+	require.NoError(t, err)
+	merged := filepath.Join(t.TempDir(), "merged.tar")
+	require.NoError(t, mergeBundles(out, bundleFiles, nil, merged, nil))
+
+	imagesCfg, err := config.ParseImagesConfigFile(extractFile(t, merged, "images.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, config.ImagesConfig{"example.com": {Images: map[string][]string{
+		"team-a/app":   {"v1"},
+		"team-b/large": {"v1"},
+		"shared":       {"v1"},
+	}}}, imagesCfg)
+
+	storage, err := registry.ArchiveStorage("", nil, merged)
+	require.NoError(t, err)
+	reg := bundletest.StartRegistry(t, storage)
+	imgs := map[string]v1.Image{"team-a/app:v1": app, "team-b/large:v1": large, "shared:v1": shared}
+	for ref, img := range imgs {
+		tag, err := name.NewTag(reg.Address() + "/" + ref)
+		require.NoError(t, err)
+		pulled, err := remote.Image(tag)
+		require.NoError(t, err, ref)
+		assert.Equal(t, bundletest.Digest(t, img), bundletest.Digest(t, pulled), ref)
+
+		layers, err := pulled.Layers()
+		require.NoError(t, err)
+		for _, l := range layers {
+			// Layers are verified against their digest when read completely.
+			rc, err := l.Compressed()
+			require.NoError(t, err)
+			_, err = io.Copy(io.Discard, rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+		}
+	}
+}
+
+func TestMergeBundlesConflictingTags(t *testing.T) {
+	bundleA := writeTestBundle(t, map[string]v1.Image{"app:v1": newTestImage(t, 64)}, 0)
+	bundleB := writeTestBundle(t, map[string]v1.Image{"app:v1": newTestImage(t, 64)}, 0)
+
+	out := output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0)
+	err := mergeBundles(out, []string{bundleA, bundleB}, nil, filepath.Join(t.TempDir(), "merged.tar"), nil)
+	require.ErrorContains(t, err, "bundles contain tags that point to different digests")
+	require.ErrorContains(t, err, "app:v1 is sha256:")
+}
+
+func TestMergeEncryptedBundles(t *testing.T) {
+	app := newTestImage(t, 64)
+	other := newTestImage(t, 64)
+	bundleA := writeTestBundle(t, map[string]v1.Image{"app:v1": app}, 0)
+	bundleB, keyFile := archivetest.EncryptBundle(t, writeTestBundle(t, map[string]v1.Image{"other:v1": other}, 0))
+
+	buf := &bytes.Buffer{}
+	out := output.NewNonInteractiveShell(buf, buf, 0)
+	merged := filepath.Join(t.TempDir(), "merged.tar")
+	require.ErrorIs(
+		t, mergeBundles(out, []string{bundleA, bundleB}, nil, merged, nil), archive.ErrNoDecryptionKeys,
+	)
+
+	wantImagesCfg := config.ImagesConfig{"example.com": {Images: map[string][]string{
+		"app":   {"v1"},
+		"other": {"v1"},
+	}}}
+
+	buf.Reset()
+	require.NoError(t, mergeBundles(out, []string{bundleA, bundleB}, []string{keyFile}, merged, nil))
+	assert.Contains(t, buf.String(), "Bundle "+bundleB+" is encrypted, but the output bundle is not")
+	imagesCfg, err := config.ParseImagesConfigFile(extractFile(t, merged, "images.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, wantImagesCfg, imagesCfg)
+
+	// The merged bundle can be encrypted for other keys than the bundles it is merged from.
+	identity, mergedKeyFile := archivetest.NewKeyFile(t)
+	encryptedMerged := filepath.Join(t.TempDir(), "merged.tar")
+	buf.Reset()
+	require.NoError(t, mergeBundles(
+		out, []string{bundleA, bundleB}, []string{keyFile}, encryptedMerged, []age.Recipient{identity.Recipient()},
+	))
+	assert.NotContains(t, buf.String(), "output bundle is not")
+	encrypted, err := archive.IsEncrypted(encryptedMerged)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+	identities, err := archive.ParseDecryptionKeys([]string{mergedKeyFile})
+	require.NoError(t, err)
+	imagesCfg, err = config.ParseImagesConfigFile(extractFile(t, encryptedMerged, "images.yaml", identities...))
+	require.NoError(t, err)
+	assert.Equal(t, wantImagesCfg, imagesCfg)
+}
+
+func extractFile(t *testing.T, bundleFile, fileName string, identities ...age.Identity) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, archive.ExtractFileToDirectory(bundleFile, dir, fileName, identities...))
+	return filepath.Join(dir, fileName)
+}
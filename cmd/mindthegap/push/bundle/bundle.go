@@ -11,10 +11,12 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	tarpath "path"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/containers/image/v5/docker/reference"
 	"github.com/containers/image/v5/types"
@@ -30,15 +32,18 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/thediveo/enumflag/v2"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 	"github.com/mesosphere/dkp-cli-runtime/core/term"
 
 	"github.com/mesosphere/mindthegap/cleanup"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/push/report"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
 	"github.com/mesosphere/mindthegap/config"
 	"github.com/mesosphere/mindthegap/docker/ecr"
+	"github.com/mesosphere/mindthegap/docker/harbor"
 	"github.com/mesosphere/mindthegap/docker/registry"
 	"github.com/mesosphere/mindthegap/images"
 	"github.com/mesosphere/mindthegap/images/authnhelpers"
@@ -63,18 +68,39 @@ var onExistingTagModes = map[onExistingTagMode][]string{
 	MergeWithOverwrite: {"merge-with-overwrite"},
 }
 
+const (
+	// defaultRetries and defaultRetryBackoff match the go-containerregistry defaults.
+	defaultRetries      = 2
+	defaultRetryBackoff = time.Second
+)
+
 func NewCommand(out output.Output, bundleCmdName string) *cobra.Command {
 	var (
 		bundleFiles                   []string
-		destRegistryURI               flags.RegistryURI
+		decryptionKeyFiles            []string
+		destRegistryURIs              flags.RegistryURIs
+		destinationsFile              string
 		destRegistryCACertificateFile string
 		destRegistrySkipTLSVerify     bool
 		destRegistryUsername          string
 		destRegistryPassword          string
+		registryAuthFile              string
 		ecrLifecyclePolicy            string
+		ecrRepositoryTemplate         string
+		ecrRoleARN                    string
 		onExistingTag                 = Overwrite
 		imagePushConcurrency          int
 		forceOCIMediaTypes            bool
+		maxBandwidth                  flags.Bandwidth
+		retries                       int
+		retryBackoff                  time.Duration
+		continueOnError               bool
+		reportFile                    string
+		harborCreateProjects          bool
+		harborProjectPublic           bool
+		harborProjectStorageQuota     string
+		harborProjectAutoScan         bool
+		prePushHook                   string
 	)
 
 	cmd := &cobra.Command{
@@ -85,45 +111,102 @@ func NewCommand(out output.Output, bundleCmdName string) *cobra.Command {
 				return err
 			}
 
-			if err := flags.ValidateFlagsThatRequireValues(cmd, bundleCmdName, "to-registry"); err != nil {
+			requiredFlagsWithValues := []string{bundleCmdName}
+			if destinationsFile == "" {
+				requiredFlagsWithValues = append(requiredFlagsWithValues, "to-registry")
+			}
+			if err := flags.ValidateFlagsThatRequireValues(cmd, requiredFlagsWithValues...); err != nil {
 				return err
 			}
 
+			if retries < 0 {
+				return fmt.Errorf("--retries must not be negative")
+			}
+
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			var destinations []destinationOpts
+			if destinationsFile != "" {
+				var err error
+				destinations, err = parseDestinationsFile(destinationsFile)
+				if err != nil {
+					return err
+				}
+			} else {
+				// Harbor project creation is auto-detected unless explicitly enabled or disabled.
+				var createHarborProjects *bool
+				if cmd.Flags().Changed("harbor-create-projects") {
+					createHarborProjects = &harborCreateProjects
+				}
+				var harborStorageQuota int64
+				if harborProjectStorageQuota != "" {
+					var err error
+					harborStorageQuota, err = flags.ParseByteSize(harborProjectStorageQuota)
+					if err != nil {
+						return fmt.Errorf("invalid --harbor-project-storage-quota: %w", err)
+					}
+				}
+
+				// All registries specified via --to-registry share the same TLS and credentials configuration.
+				for _, destRegistryURI := range destRegistryURIs {
+					destinations = append(destinations, destinationOpts{
+						registryURI:               destRegistryURI,
+						registryCACertificateFile: destRegistryCACertificateFile,
+						registrySkipTLSVerify:     destRegistrySkipTLSVerify,
+						registryUsername:          destRegistryUsername,
+						registryPassword:          destRegistryPassword,
+						registryAuthFile:          registryAuthFile,
+						ecrLifecyclePolicy:        ecrLifecyclePolicy,
+						ecrRepositoryTemplate:     ecrRepositoryTemplate,
+						ecrRoleARN:                ecrRoleARN,
+						harborCreateProjects:      createHarborProjects,
+						harborProjectOpts: harbor.ProjectOptions{
+							Public:            harborProjectPublic,
+							StorageQuotaBytes: harborStorageQuota,
+							AutoScan:          harborProjectAutoScan,
+						},
+						prePushHook: prePushHook,
+					})
+				}
+			}
+
 			// Create configuration from command flags
-			cfg, err := NewPushBundleOpts(bundleFiles, &destRegistryURI)
+			cfg, err := newPushBundleOptsForDestinations(bundleFiles, destinations)
 			if err != nil {
-				return fmt.Errorf("invalid configuration: %w", err)
-			}
-			// Set TLS configuration (mutually exclusive options)
-			if err := cfg.WithRegistryCACertificateFile(destRegistryCACertificateFile); err != nil {
-				return fmt.Errorf("invalid TLS configuration: %w", err)
-			}
-			if err := cfg.WithRegistrySkipTLSVerify(destRegistrySkipTLSVerify); err != nil {
-				return fmt.Errorf("invalid TLS configuration: %w", err)
-			}
-			// Set credentials (both must be provided together)
-			if err := cfg.WithRegistryCredentials(destRegistryUsername, destRegistryPassword); err != nil {
-				return fmt.Errorf("invalid credentials: %w", err)
+				return err
 			}
 			// Set optional configuration
-			cfg.WithECRLifecyclePolicy(ecrLifecyclePolicy).
-				WithOnExistingTag(onExistingTag).
+			cfg.WithOnExistingTag(onExistingTag).
 				WithImagePushConcurrency(imagePushConcurrency).
-				WithForceOCIMediaTypes(forceOCIMediaTypes)
+				WithForceOCIMediaTypes(forceOCIMediaTypes).
+				WithMaxBandwidth(maxBandwidth.BytesPerSecond()).
+				WithRetries(retries, retryBackoff).
+				WithContinueOnError(continueOnError).
+				WithReportFile(reportFile).
+				WithDecryptionKeys(decryptionKeyFiles)
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
 
-			return PushBundles(cfg, out)
+			return PushBundles(ctx, cfg, out)
 		},
 	}
 
 	cmd.Flags().StringSliceVar(&bundleFiles, bundleCmdName, nil,
-		"Tarball containing list of images to push. Can also be a glob pattern.")
+		"Tarball containing list of images to push, or the .parts.yaml index of a split bundle. "+
+			"Can also be a glob pattern.")
 	_ = cmd.MarkFlagRequired(bundleCmdName)
-	cmd.Flags().Var(&destRegistryURI, "to-registry", "Registry to push images to. "+
-		"TLS verification will be skipped when using an http:// registry.")
-	_ = cmd.MarkFlagRequired("to-registry")
+	cmd.Flags().StringSliceVar(&decryptionKeyFiles, "decrypt-key", nil,
+		"File containing age private keys (AGE-SECRET-KEY-1...) or a passphrase to decrypt encrypted bundles with. "+
+			"Can be specified multiple times")
+	cmd.Flags().Var(&destRegistryURIs, "to-registry", "Registry to push images to. "+
+		"TLS verification will be skipped when using an http:// registry. "+
+		"Can be specified multiple times to push to multiple registries in a single run.")
+	cmd.Flags().StringVar(&destinationsFile, "destinations-file", "",
+		"YAML file containing registries to push images to, each with its own TLS, credentials and "+
+			"ECR configuration")
+	cmd.MarkFlagsOneRequired("to-registry", "destinations-file")
 	cmd.Flags().StringVar(&destRegistryCACertificateFile, "to-registry-ca-cert-file", "",
 		"CA certificate file used to verify TLS verification of registry to push images to")
 	cmd.Flags().BoolVar(&destRegistrySkipTLSVerify, "to-registry-insecure-skip-tls-verify", false,
@@ -140,9 +223,30 @@ func NewCommand(out output.Output, bundleCmdName string) *cobra.Command {
 		"to-registry-username",
 		"to-registry-password",
 	)
+	cmd.Flags().StringVar(&registryAuthFile, "registry-auth-file", "",
+		"Registry auth file to read destination registry credentials from: a docker config.json, containers "+
+			"auth.json or Kubernetes dockerconfigjson secret, including credential helpers")
 	cmd.Flags().StringVar(&ecrLifecyclePolicy, "ecr-lifecycle-policy-file", "",
 		"File containing ECR lifecycle policy for newly created repositories "+
 			"(only applies if target registry is hosted on ECR, ignored otherwise)")
+	cmd.Flags().StringVar(&ecrRepositoryTemplate, "ecr-repository-template-file", "",
+		"YAML file containing the configuration of newly created ECR repositories, such as encryption, tag "+
+			"mutability, repository policy and tags (only applies if target registry is hosted on ECR, ignored otherwise)")
+	cmd.Flags().StringVar(&ecrRoleARN, "ecr-role-arn", "",
+		"ARN of an IAM role to assume to create repositories and retrieve credentials, e.g. to push to the "+
+			"registry of another AWS account (only applies if target registry is hosted on ECR, ignored otherwise)")
+	cmd.Flags().BoolVar(&harborCreateProjects, "harbor-create-projects", false,
+		"Create missing projects before pushing into them (only applies if target registry is Harbor, "+
+			"enabled automatically if the Harbor API is detected unless explicitly set)")
+	cmd.Flags().BoolVar(&harborProjectPublic, "harbor-project-public", false,
+		"Make Harbor projects created by --harbor-create-projects public")
+	cmd.Flags().StringVar(&harborProjectStorageQuota, "harbor-project-storage-quota", "",
+		"Storage quota of Harbor projects created by --harbor-create-projects, e.g. 10GiB (default unlimited)")
+	cmd.Flags().BoolVar(&harborProjectAutoScan, "harbor-project-auto-scan", false,
+		"Scan images on push in Harbor projects created by --harbor-create-projects")
+	cmd.Flags().StringVar(&prePushHook, "pre-push-hook", "",
+		"Command to run once per destination repository before pushing into it. The repository and tags are "+
+			"passed as JSON on stdin. A non-zero exit status fails pushing into the repository")
 
 	cmd.Flags().Var(
 		enumflag.New(&onExistingTag, "string", onExistingTagModes, enumflag.EnumCaseSensitive),
@@ -150,11 +254,44 @@ func NewCommand(out output.Output, bundleCmdName string) *cobra.Command {
 		`how to handle existing tags: one of "overwrite", "error", or "skip"`,
 	)
 	cmd.Flags().
-		IntVar(&imagePushConcurrency, "image-push-concurrency", 1, "Image push concurrency")
+		IntVar(&imagePushConcurrency, "image-push-concurrency", 1, "Image and Helm chart push concurrency")
 
 	cmd.Flags().
 		BoolVar(&forceOCIMediaTypes, "force-oci-media-types", false, "force OCI media types")
 
+	cmd.Flags().Var(&maxBandwidth, "max-bandwidth",
+		"Maximum bandwidth to use across all concurrent pushes, e.g. 50MiB/s (default unlimited)")
+
+	cmd.Flags().IntVar(&retries, "retries", defaultRetries,
+		"Number of times to retry pushing each blob and manifest on transient errors")
+	cmd.Flags().DurationVar(&retryBackoff, "retry-backoff", defaultRetryBackoff,
+		"Initial backoff between retries, tripled after each retry")
+	cmd.Flags().BoolVar(&continueOnError, "continue-on-error", false,
+		"Continue pushing remaining images and charts if pushing any fails, "+
+			"reporting all failures at the end of the run")
+	cmd.Flags().StringVar(&reportFile, "report", "",
+		"File to write a JSON report of all pushed references, including their digests, to")
+
+	// The destinations file configures everything about each destination registry.
+	for _, f := range []string{
+		"to-registry",
+		"to-registry-ca-cert-file",
+		"to-registry-insecure-skip-tls-verify",
+		"to-registry-username",
+		"to-registry-password",
+		"registry-auth-file",
+		"ecr-lifecycle-policy-file",
+		"ecr-repository-template-file",
+		"ecr-role-arn",
+		"harbor-create-projects",
+		"harbor-project-public",
+		"harbor-project-storage-quota",
+		"harbor-project-auto-scan",
+		"pre-push-hook",
+	} {
+		cmd.MarkFlagsMutuallyExclusive(f, "destinations-file")
+	}
+
 	return cmd
 }
 
@@ -163,6 +300,7 @@ type prePushFunc func(destRepositoryName name.Repository, imageTags ...string) e
 type pushConfig struct {
 	forceOCIMediaTypes bool
 	onExistingTag      onExistingTagMode
+	reportEntry        *report.Entry
 }
 
 type pushOpt func(*pushConfig)
@@ -179,11 +317,20 @@ func withOnExistingTagMode(mode onExistingTagMode) pushOpt {
 	}
 }
 
+// withReportEntry records the result of the push in entry.
+func withReportEntry(entry *report.Entry) pushOpt {
+	return func(cfg *pushConfig) {
+		cfg.reportEntry = entry
+	}
+}
+
 // pushBundleOpts holds all configuration needed for pushing bundles.
 // Use NewPushBundleOpts to create a properly validated instance.
 type pushBundleOpts struct {
 	// Bundle files to process
 	bundleFiles []string
+	// Files containing the keys to decrypt encrypted bundles with
+	decryptionKeyFiles []string
 
 	// Destination registry configuration
 	registryURI               *flags.RegistryURI
@@ -191,14 +338,34 @@ type pushBundleOpts struct {
 	registrySkipTLSVerify     bool
 	registryUsername          string
 	registryPassword          string
+	registryAuthFile          string
 
 	// ECR specific configuration
-	ecrLifecyclePolicy string
+	ecrLifecyclePolicy    string
+	ecrRepositoryTemplate string
+	ecrRoleARN            string
+
+	// Harbor specific configuration
+	harborCreateProjects *bool
+	harborProjectOpts    harbor.ProjectOptions
+
+	// Command run once per destination repository before pushing into it
+	prePushHook string
+
+	// Further registries to push to in addition to the registry configured above
+	additionalDestinations []destinationOpts
 
 	// Push behavior configuration
 	onExistingTag        onExistingTagMode
 	imagePushConcurrency int
 	forceOCIMediaTypes   bool
+	maxBandwidth         int64
+	retries              int
+	retryBackoff         time.Duration
+	continueOnError      bool
+
+	// File to write the push report to
+	reportFile string
 }
 
 // NewPushBundleOpts creates a new pushBundleOpts with required fields.
@@ -215,11 +382,53 @@ func NewPushBundleOpts(bundleFiles []string, registryURI *flags.RegistryURI) (*p
 	return &pushBundleOpts{
 		bundleFiles:          bundleFiles,
 		registryURI:          registryURI,
-		onExistingTag:        Overwrite, // default
-		imagePushConcurrency: 1,         // default
+		onExistingTag:        Overwrite,           // default
+		imagePushConcurrency: 1,                   // default
+		retries:              defaultRetries,      // default
+		retryBackoff:         defaultRetryBackoff, // default
 	}, nil
 }
 
+// newPushBundleOptsForDestinations creates a new pushBundleOpts pushing to all the specified destinations.
+// The first destination is used as the primary destination registry.
+func newPushBundleOptsForDestinations(
+	bundleFiles []string,
+	destinations []destinationOpts,
+) (*pushBundleOpts, error) {
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("invalid configuration: at least one destination registry is required")
+	}
+
+	primary := destinations[0]
+	cfg, err := NewPushBundleOpts(bundleFiles, primary.registryURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	// Set TLS configuration (mutually exclusive options)
+	if err := cfg.WithRegistryCACertificateFile(primary.registryCACertificateFile); err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	if err := cfg.WithRegistrySkipTLSVerify(primary.registrySkipTLSVerify); err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	// Set credentials (both must be provided together)
+	if err := cfg.WithRegistryCredentials(primary.registryUsername, primary.registryPassword); err != nil {
+		return nil, fmt.Errorf("invalid credentials: %w", err)
+	}
+	cfg.WithRegistryAuthFile(primary.registryAuthFile)
+	cfg.WithECRLifecyclePolicy(primary.ecrLifecyclePolicy)
+	cfg.WithECRRepositoryTemplate(primary.ecrRepositoryTemplate)
+	cfg.WithECRRoleARN(primary.ecrRoleARN)
+	cfg.WithHarborProjects(primary.harborCreateProjects, primary.harborProjectOpts)
+	cfg.WithPrePushHook(primary.prePushHook)
+
+	if err := cfg.WithAdditionalDestinations(destinations[1:]...); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
 // WithRegistryCredentials sets the registry credentials.
 // Both username and password must be provided together.
 func (c *pushBundleOpts) WithRegistryCredentials(username, password string) error {
@@ -231,6 +440,12 @@ func (c *pushBundleOpts) WithRegistryCredentials(username, password string) erro
 	return nil
 }
 
+// WithRegistryAuthFile sets the registry auth file to read registry credentials from.
+func (c *pushBundleOpts) WithRegistryAuthFile(authFile string) *pushBundleOpts {
+	c.registryAuthFile = authFile
+	return c
+}
+
 // WithRegistryCACertificateFile sets the CA certificate file for TLS verification.
 // This option is mutually exclusive with WithRegistrySkipTLSVerify(true).
 func (c *pushBundleOpts) WithRegistryCACertificateFile(caCertFile string) error {
@@ -257,6 +472,32 @@ func (c *pushBundleOpts) WithECRLifecyclePolicy(policyFile string) *pushBundleOp
 	return c
 }
 
+// WithECRRepositoryTemplate sets the ECR repository template file.
+func (c *pushBundleOpts) WithECRRepositoryTemplate(templateFile string) *pushBundleOpts {
+	c.ecrRepositoryTemplate = templateFile
+	return c
+}
+
+// WithECRRoleARN sets the IAM role to assume to manage ECR repositories and retrieve ECR credentials.
+func (c *pushBundleOpts) WithECRRoleARN(roleARN string) *pushBundleOpts {
+	c.ecrRoleARN = roleARN
+	return c
+}
+
+// WithHarborProjects configures creating missing Harbor projects. If createProjects is nil, projects are created
+// if the registry is detected to be Harbor.
+func (c *pushBundleOpts) WithHarborProjects(createProjects *bool, opts harbor.ProjectOptions) *pushBundleOpts {
+	c.harborCreateProjects = createProjects
+	c.harborProjectOpts = opts
+	return c
+}
+
+// WithPrePushHook sets the command to run once per destination repository before pushing into it.
+func (c *pushBundleOpts) WithPrePushHook(command string) *pushBundleOpts {
+	c.prePushHook = command
+	return c
+}
+
 // WithOnExistingTag sets the behavior for handling existing tags.
 func (c *pushBundleOpts) WithOnExistingTag(mode onExistingTagMode) *pushBundleOpts {
 	c.onExistingTag = mode
@@ -275,8 +516,74 @@ func (c *pushBundleOpts) WithForceOCIMediaTypes(force bool) *pushBundleOpts {
 	return c
 }
 
+// WithMaxBandwidth sets the maximum bandwidth in bytes per second shared by all pushes. A value of 0
+// means unlimited.
+func (c *pushBundleOpts) WithMaxBandwidth(bytesPerSecond int64) *pushBundleOpts {
+	c.maxBandwidth = bytesPerSecond
+	return c
+}
+
+// WithRetries sets the number of retries for each blob and manifest on transient errors, and the initial
+// backoff between retries.
+func (c *pushBundleOpts) WithRetries(retries int, backoff time.Duration) *pushBundleOpts {
+	c.retries = retries
+	c.retryBackoff = backoff
+	return c
+}
+
+// WithContinueOnError sets whether to continue pushing after a failure, reporting all failures at the end.
+func (c *pushBundleOpts) WithContinueOnError(continueOnError bool) *pushBundleOpts {
+	c.continueOnError = continueOnError
+	return c
+}
+
+// WithReportFile sets the file to write a JSON report of all pushed references to.
+func (c *pushBundleOpts) WithReportFile(reportFile string) *pushBundleOpts {
+	c.reportFile = reportFile
+	return c
+}
+
+// WithDecryptionKeys sets the files containing age private keys or passphrases to decrypt encrypted bundles with.
+func (c *pushBundleOpts) WithDecryptionKeys(keyFiles []string) *pushBundleOpts {
+	c.decryptionKeyFiles = keyFiles
+	return c
+}
+
+// WithAdditionalDestinations adds registries that bundles are pushed to alongside the primary destination
+// registry. Each destination has its own TLS, credentials and ECR configuration.
+func (c *pushBundleOpts) WithAdditionalDestinations(destinations ...destinationOpts) error {
+	for _, d := range destinations {
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("invalid destination %s: %w", d.registryURI, err)
+		}
+	}
+	c.additionalDestinations = append(c.additionalDestinations, destinations...)
+	return nil
+}
+
+// destinations returns all the registries to push to, starting with the primary destination registry.
+func (c *pushBundleOpts) destinations() []destinationOpts {
+	return append(
+		[]destinationOpts{{
+			registryURI:               c.registryURI,
+			registryCACertificateFile: c.registryCACertificateFile,
+			registrySkipTLSVerify:     c.registrySkipTLSVerify,
+			registryUsername:          c.registryUsername,
+			registryPassword:          c.registryPassword,
+			registryAuthFile:          c.registryAuthFile,
+			ecrLifecyclePolicy:        c.ecrLifecyclePolicy,
+			ecrRepositoryTemplate:     c.ecrRepositoryTemplate,
+			ecrRoleARN:                c.ecrRoleARN,
+			harborCreateProjects:      c.harborCreateProjects,
+			harborProjectOpts:         c.harborProjectOpts,
+			prePushHook:               c.prePushHook,
+		}},
+		c.additionalDestinations...,
+	)
+}
+
 // PushBundles pushes both images and charts from bundle files to the destination registry.
-func PushBundles(cfg *pushBundleOpts, out output.Output) error {
+func PushBundles(ctx context.Context, cfg *pushBundleOpts, out output.Output) error {
 	cleaner := cleanup.NewCleaner()
 	defer cleaner.Cleanup()
 
@@ -293,16 +600,20 @@ func PushBundles(cfg *pushBundleOpts, out output.Output) error {
 	if err != nil {
 		return err
 	}
+	bundleFiles, err = utils.ExpandBundleParts(out, bundleFiles)
+	if err != nil {
+		return err
+	}
 	if err := rejectImageArchives(bundleFiles); err != nil {
 		return err
 	}
-	imagesCfg, chartsCfg, err := utils.ExtractConfigs(tempDir, out, bundleFiles...)
+	imagesCfg, chartsCfg, err := utils.ExtractConfigs(tempDir, out, cfg.decryptionKeyFiles, bundleFiles...)
 	if err != nil {
 		return err
 	}
 
 	out.StartOperation("Starting temporary Docker registry")
-	storage, err := registry.ArchiveStorage("", bundleFiles...)
+	storage, err := registry.ArchiveStorage("", cfg.decryptionKeyFiles, bundleFiles...)
 	if err != nil {
 		out.EndOperationWithStatus(output.Failure())
 		return fmt.Errorf("failed to create storage for Docker registry from supplied bundles: %w", err)
@@ -334,70 +645,25 @@ func PushBundles(cfg *pushBundleOpts, out output.Output) error {
 		remote.WithUserAgent(utils.Useragent()),
 	}
 
-	destTLSRoundTripper, err := httputils.TLSConfiguredRoundTripper(
-		remote.DefaultTransport,
-		cfg.registryURI.Host(),
-		flags.SkipTLSVerify(cfg.registrySkipTLSVerify, cfg.registryURI),
-		cfg.registryCACertificateFile,
-	)
-	if err != nil {
-		return fmt.Errorf("error configuring TLS for destination registry: %w", err)
-	}
-	destRemoteOpts := make([]remote.Option, 0, 4)
-	destRemoteOpts = append(destRemoteOpts,
-		remote.WithTransport(destTLSRoundTripper),
-		remote.WithUserAgent(utils.Useragent()),
-	)
-
-	var destNameOpts []name.Option
-	if flags.SkipTLSVerify(cfg.registrySkipTLSVerify, cfg.registryURI) {
-		destNameOpts = append(destNameOpts, name.Insecure)
-	}
+	// A single limiter is shared by all destinations so that the bandwidth limit applies to the total of
+	// all concurrent pushes.
+	bandwidthLimiter := httputils.NewBandwidthLimiter(cfg.maxBandwidth)
+	harborHosts := harborDetection{}
 
-	// Determine type of destination registry.
-	var prePushFuncs []prePushFunc
-	if ecr.IsECRRegistry(cfg.registryURI.Host()) {
-		ecrClient, err := ecr.ClientForRegistry(cfg.registryURI.Host())
+	destinations := make([]pushDestination, 0, len(cfg.additionalDestinations)+1)
+	for _, d := range cfg.destinations() {
+		dest, err := newPushDestination(ctx, d, bandwidthLimiter, harborHosts, out)
 		if err != nil {
 			return err
 		}
-
-		prePushFuncs = append(
-			prePushFuncs,
-			ecr.EnsureRepositoryExistsFunc(ecrClient, cfg.ecrLifecyclePolicy),
-		)
-
-		// If a password hasn't been specified, then try to retrieve a token.
-		if cfg.registryPassword == "" {
-			out.StartOperation("Retrieving ECR credentials")
-			cfg.registryUsername, cfg.registryPassword, err = ecr.RetrieveUsernameAndToken(ecrClient)
-			if err != nil {
-				out.EndOperationWithStatus(output.Failure())
-				return fmt.Errorf(
-					"failed to retrieve ECR credentials: %w\n\nPlease ensure you have authenticated to AWS and try again",
-					err,
-				)
-			}
-			out.EndOperationWithStatus(output.Success())
-		}
-	}
-
-	var keychain authn.Keychain = authn.DefaultKeychain
-	if cfg.registryUsername != "" && cfg.registryPassword != "" {
-		keychain = authn.NewMultiKeychain(
-			authn.NewKeychainFromHelper(
-				authnhelpers.NewStaticHelper(
-					cfg.registryURI.Host(),
-					&types.DockerAuthConfig{
-						Username: cfg.registryUsername,
-						Password: cfg.registryPassword,
-					},
-				),
-			),
-			keychain,
-		)
+		dest.remoteOpts = append(dest.remoteOpts, remote.WithRetryBackoff(remote.Backoff{
+			Duration: cfg.retryBackoff,
+			Factor:   3.0,
+			Jitter:   0.1,
+			Steps:    cfg.retries + 1,
+		}))
+		destinations = append(destinations, dest)
 	}
-	destRemoteOpts = append(destRemoteOpts, remote.WithAuthFromKeychain(keychain))
 
 	srcRegistry, err := name.NewRegistry(
 		reg.Address(),
@@ -407,26 +673,30 @@ func PushBundles(cfg *pushBundleOpts, out output.Output) error {
 	if err != nil {
 		return err
 	}
-	destRegistry, err := name.NewRegistry(
-		cfg.registryURI.Host(),
-		append(destNameOpts, name.StrictValidation)...)
-	if err != nil {
-		return err
+
+	var failures *pushFailures
+	if cfg.continueOnError {
+		failures = newPushFailures()
+	}
+
+	var pushReport *report.Report
+	if cfg.reportFile != "" {
+		pushReport = report.New()
 	}
 
 	if imagesCfg != nil {
 		err = pushImages(
+			ctx,
 			*imagesCfg,
 			srcRegistry,
 			sourceRemoteOpts,
-			destRegistry,
-			cfg.registryURI.Path(),
-			destRemoteOpts,
+			destinations,
 			cfg.onExistingTag,
 			cfg.imagePushConcurrency,
 			out,
 			cfg.forceOCIMediaTypes,
-			prePushFuncs...,
+			failures,
+			pushReport,
 		)
 		if err != nil {
 			return err
@@ -443,21 +713,37 @@ func PushBundles(cfg *pushBundleOpts, out output.Output) error {
 
 	if chartsCfg != nil {
 		err = pushOCIArtifacts(
+			ctx,
 			*chartsCfg,
 			chartsSrcRegistry,
 			"/charts",
 			sourceRemoteOpts,
-			destRegistry,
-			cfg.registryURI.Path(),
-			destRemoteOpts,
+			destinations,
+			cfg.onExistingTag,
+			cfg.imagePushConcurrency,
 			out,
-			prePushFuncs...,
+			failures,
+			pushReport,
 		)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Write the report even if some pushes failed so that successfully pushed references are recorded.
+	if cfg.reportFile != "" {
+		out.StartOperation(fmt.Sprintf("Writing push report to %s", cfg.reportFile))
+		if err := pushReport.WriteFile(cfg.reportFile); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+		out.EndOperationWithStatus(output.Success())
+	}
+
+	if err := failures.err(); err != nil {
+		return err
+	}
+
 	// Check if the registry goroutine encountered any errors
 	select {
 	case err := <-registryErrCh:
@@ -469,6 +755,311 @@ func PushBundles(cfg *pushBundleOpts, out output.Output) error {
 	return nil
 }
 
+// pushDestination is a fully configured registry that bundle contents are pushed to.
+type pushDestination struct {
+	registry     name.Registry
+	path         string
+	remoteOpts   []remote.Option
+	prePushFuncs []prePushFunc
+}
+
+// repository returns the repository in the destination registry for the given repository name,
+// taking the destination registry path into account.
+func (d pushDestination) repository(repositoryName string) name.Repository {
+	return d.registry.Repo(strings.TrimLeft(d.path, "/"), repositoryName)
+}
+
+func newPushDestination(
+	ctx context.Context,
+	d destinationOpts,
+	bandwidthLimiter *rate.Limiter,
+	harborHosts harborDetection,
+	out output.Output,
+) (pushDestination, error) {
+	destTLSRoundTripper, err := httputils.TLSConfiguredRoundTripper(
+		remote.DefaultTransport,
+		d.registryURI.Host(),
+		flags.SkipTLSVerify(d.registrySkipTLSVerify, d.registryURI),
+		d.registryCACertificateFile,
+	)
+	if err != nil {
+		return pushDestination{}, fmt.Errorf(
+			"error configuring TLS for destination registry %s: %w", d.registryURI.Host(), err,
+		)
+	}
+	destRemoteOpts := make([]remote.Option, 0, 4)
+	destRemoteOpts = append(destRemoteOpts,
+		remote.WithTransport(httputils.BandwidthLimitedRoundTripper(destTLSRoundTripper, bandwidthLimiter)),
+		remote.WithUserAgent(utils.Useragent()),
+	)
+
+	var destNameOpts []name.Option
+	if flags.SkipTLSVerify(d.registrySkipTLSVerify, d.registryURI) {
+		destNameOpts = append(destNameOpts, name.Insecure)
+	}
+
+	// Determine type of destination registry.
+	var (
+		prePushFuncs []prePushFunc
+		keychain     authn.Keychain = authn.DefaultKeychain
+	)
+	ecrPrePushFunc, ecrKeychain, err := ecrDestination(d)
+	if err != nil {
+		return pushDestination{}, err
+	}
+	if ecrPrePushFunc != nil {
+		prePushFuncs = append(prePushFuncs, ecrPrePushFunc)
+	}
+
+	var authFileKeychain authn.Keychain
+	if d.registryAuthFile != "" {
+		authFileKeychain, err = authnhelpers.NewAuthFileKeychain(d.registryAuthFile)
+		if err != nil {
+			return pushDestination{}, err
+		}
+	}
+
+	// If credentials haven't been specified, then use ECR authorization tokens. Tokens are refreshed before
+	// they expire so that pushes taking longer than the validity of a single token succeed.
+	if ecrKeychain != nil && d.registryPassword == "" &&
+		!keychainHasCredentials(authFileKeychain, d.registryURI.Host()) {
+		out.StartOperation("Retrieving ECR credentials")
+		// Retrieve the initial token upfront to fail early if not authenticated to AWS.
+		if err := verifyKeychain(ecrKeychain, d.registryURI.Host()); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return pushDestination{}, fmt.Errorf(
+				"failed to retrieve ECR credentials: %w\n\nPlease ensure you have authenticated to AWS and try again",
+				err,
+			)
+		}
+		out.EndOperationWithStatus(output.Success())
+		keychain = authn.NewMultiKeychain(ecrKeychain, keychain)
+	}
+
+	if authFileKeychain != nil {
+		keychain = authn.NewMultiKeychain(authFileKeychain, keychain)
+	}
+
+	if d.registryUsername != "" && d.registryPassword != "" {
+		keychain = authn.NewMultiKeychain(
+			authn.NewKeychainFromHelper(
+				authnhelpers.NewStaticHelper(
+					d.registryURI.Host(),
+					&types.DockerAuthConfig{
+						Username: d.registryUsername,
+						Password: d.registryPassword,
+					},
+				),
+			),
+			keychain,
+		)
+	}
+	destRemoteOpts = append(destRemoteOpts, remote.WithAuthFromKeychain(keychain))
+
+	destRegistry, err := name.NewRegistry(
+		d.registryURI.Host(),
+		append(destNameOpts, name.StrictValidation)...)
+	if err != nil {
+		return pushDestination{}, err
+	}
+
+	if ecrPrePushFunc == nil {
+		harborPrePushFunc, err := harborPrePushFunc(
+			ctx, d, destRegistry, destTLSRoundTripper, keychain, harborHosts, out,
+		)
+		if err != nil {
+			return pushDestination{}, err
+		}
+		if harborPrePushFunc != nil {
+			prePushFuncs = append(prePushFuncs, harborPrePushFunc)
+		}
+	}
+
+	// Run the user-specified hook last so that it can rely on the repository existing.
+	if d.prePushHook != "" {
+		hookFunc, err := prePushHookFunc(ctx, d.prePushHook, out)
+		if err != nil {
+			return pushDestination{}, err
+		}
+		prePushFuncs = append(prePushFuncs, hookFunc)
+	}
+
+	return pushDestination{
+		registry:     destRegistry,
+		path:         d.registryURI.Path(),
+		remoteOpts:   destRemoteOpts,
+		prePushFuncs: prePushFuncs,
+	}, nil
+}
+
+// ecrDestination returns a prePushFunc creating missing repositories and a keychain providing authorization
+// tokens if the destination is a private ECR registry or ECR Public. Returns nils for other registries.
+func ecrDestination(d destinationOpts) (prePushFunc, authn.Keychain, error) {
+	registryAddress := d.registryURI.Host()
+	if !ecr.IsECRRegistry(registryAddress) && !ecr.IsECRPublicRegistry(registryAddress) {
+		return nil, nil, nil
+	}
+
+	repositoryTemplate, err := ecrRepositoryTemplate(d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ecr.IsECRPublicRegistry(registryAddress) {
+		client, err := ecr.PublicClient(ecr.WithRoleARN(d.ecrRoleARN))
+		if err != nil {
+			return nil, nil, err
+		}
+		ensureRepositoryExists, err := ecr.EnsurePublicRepositoryExistsFunc(client, repositoryTemplate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid configuration for ECR Public destination: %w", err)
+		}
+		return ensureRepositoryExists, ecr.NewPublicKeychain(client), nil
+	}
+
+	ecrClient, err := ecr.ClientForRegistry(registryAddress, ecr.WithRoleARN(d.ecrRoleARN))
+	if err != nil {
+		return nil, nil, err
+	}
+	return ecr.EnsureRepositoryExistsFunc(ecrClient, repositoryTemplate),
+		ecr.NewKeychain(ecrClient, registryAddress),
+		nil
+}
+
+// verifyKeychain checks that credentials for the registry can be retrieved from the keychain.
+func verifyKeychain(keychain authn.Keychain, registryAddress string) error {
+	reg, err := name.NewRegistry(registryAddress)
+	if err != nil {
+		return err
+	}
+	authenticator, err := keychain.Resolve(reg)
+	if err != nil {
+		return err
+	}
+	_, err = authenticator.Authorization()
+	return err
+}
+
+// keychainHasCredentials returns true if the keychain provides non-anonymous credentials for the registry.
+func keychainHasCredentials(keychain authn.Keychain, registryAddress string) bool {
+	if keychain == nil {
+		return false
+	}
+	reg, err := name.NewRegistry(registryAddress)
+	if err != nil {
+		return false
+	}
+	authenticator, err := keychain.Resolve(reg)
+	return err == nil && authenticator != authn.Anonymous
+}
+
+// ecrRepositoryTemplate returns the template to create ECR repositories from, combining the repository template
+// file and lifecycle policy file of the destination.
+func ecrRepositoryTemplate(d destinationOpts) (*ecr.RepositoryTemplate, error) {
+	if d.ecrRepositoryTemplate == "" {
+		return ecr.NewRepositoryTemplate(d.ecrLifecyclePolicy)
+	}
+
+	tmpl, err := ecr.LoadRepositoryTemplateFile(d.ecrRepositoryTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.WithLifecyclePolicyFile(d.ecrLifecyclePolicy); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// harborDetection records for each registry host whether it was detected to be Harbor, so that every host is only
+// probed once no matter how many destinations push into it.
+type harborDetection map[string]bool
+
+// harborPrePushFunc returns a prePushFunc creating missing Harbor projects if enabled for the destination, or if
+// not explicitly configured and the destination registry is detected to be Harbor. Returns nil if projects should
+// not be created.
+func harborPrePushFunc(
+	ctx context.Context,
+	d destinationOpts,
+	destRegistry name.Registry,
+	rt http.RoundTripper,
+	keychain authn.Keychain,
+	harborHosts harborDetection,
+	out output.Output,
+) (prePushFunc, error) {
+	if d.harborCreateProjects != nil && !*d.harborCreateProjects {
+		return nil, nil
+	}
+
+	// Credentials are retrieved from the authenticator for every authenticated Harbor API call, so they are never
+	// retrieved for registries that are not Harbor.
+	authenticator, err := keychain.Resolve(destRegistry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve credentials for %s: %w", destRegistry, err)
+	}
+
+	scheme := "https"
+	if d.registryURI.Scheme() == "http" {
+		scheme = "http"
+	}
+	harborClient := harbor.NewClient(scheme, d.registryURI.Host(), rt, authenticator)
+
+	if d.harborCreateProjects == nil {
+		isHarbor, probed := harborHosts[d.registryURI.Host()]
+		if !probed {
+			isHarbor, err = harborClient.IsHarbor(ctx)
+			if err != nil {
+				out.V(4).Infof("Failed to detect if %s is Harbor: %v", destRegistry, err)
+			}
+			harborHosts[d.registryURI.Host()] = isHarbor
+			if isHarbor {
+				out.V(2).Infof("Detected Harbor registry %s, missing projects will be created", destRegistry)
+			}
+		}
+		if !isHarbor {
+			return nil, nil
+		}
+	}
+
+	return harbor.EnsureProjectExistsFunc(ctx, harborClient, d.harborProjectOpts), nil
+}
+
+// preparedPushDestination is a destination repository that has been prepared for pushing into via
+// prepareDestRepositoryOnce.
+type preparedPushDestination struct {
+	repository name.Repository
+	remoteOpts []remote.Option
+	prepare    func() (map[string]struct{}, error)
+}
+
+// prepareDestinations returns the destination repository for repositoryName in each of the destinations,
+// prepared for pushing tags into. remoteOpts are appended to each destination's remote options.
+func prepareDestinations(
+	ctx context.Context,
+	destinations []pushDestination,
+	repositoryName string,
+	tags []string,
+	onExistingTag onExistingTagMode,
+	remoteOpts ...remote.Option,
+) ([]preparedPushDestination, error) {
+	prepared := make([]preparedPushDestination, 0, len(destinations))
+	for _, dest := range destinations {
+		puller, err := remote.NewPuller(dest.remoteOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		destRepository := dest.repository(repositoryName)
+		prepared = append(prepared, preparedPushDestination{
+			repository: destRepository,
+			remoteOpts: append(slices.Clone(dest.remoteOpts), remoteOpts...),
+			prepare: prepareDestRepositoryOnce(
+				ctx, destRepository, tags, onExistingTag, puller, dest.prePushFuncs...,
+			),
+		})
+	}
+	return prepared, nil
+}
+
 type pushFunc func(
 	srcImage name.Reference,
 	sourceRemoteOpts []remote.Option,
@@ -478,58 +1069,29 @@ type pushFunc func(
 ) error
 
 func pushImages(
+	ctx context.Context,
 	cfg config.ImagesConfig,
 	sourceRegistry name.Registry, sourceRemoteOpts []remote.Option,
-	destRegistry name.Registry, destRegistryPath string, destRemoteOpts []remote.Option,
+	destinations []pushDestination,
 	onExistingTag onExistingTagMode,
 	imagePushConcurrency int,
 	out output.Output,
 	forceOCIMediaTypes bool,
-	prePushFuncs ...prePushFunc,
+	failures *pushFailures,
+	pushReport *report.Report,
 ) error {
-	puller, err := remote.NewPuller(destRemoteOpts...)
-	if err != nil {
-		return err
-	}
-
 	// Sort registries for deterministic ordering.
 	regNames := cfg.SortedRegistryNames()
 
-	eg, egCtx := errgroup.WithContext(context.Background())
+	eg, egCtx := errgroup.WithContext(ctx)
 	eg.SetLimit(imagePushConcurrency)
 
 	sourceRemoteOpts = append(sourceRemoteOpts, remote.WithContext(egCtx))
-	destRemoteOpts = append(destRemoteOpts, remote.WithContext(egCtx))
-
-	// Either use a gauge for interactive TTY or line per image for non-TTY.
-	isTTY := term.IsSmartTerminal(os.Stderr)
-	type completePushFunc func(image, tag string) error
-	var completePush completePushFunc
-	if isTTY {
-		pushGauge := &output.ProgressGauge{}
-		pushGauge.SetCapacity(cfg.TotalImages())
-		pushGauge.SetStatus("Pushing bundled images")
-		completePush = func(_, _ string) error {
-			pushGauge.Inc()
 
-			return nil
-		}
-		out.StartOperationWithProgress(pushGauge)
-	} else {
-		// Use an output writer mutex to ensure the output is not interleaved.
-		var outputWriterMutex sync.RWMutex
-		currentImageIdx := 0
-		completePush = func(image, tag string) error {
-			outputWriterMutex.Lock()
-			defer outputWriterMutex.Unlock()
-			currentImageIdx++
-			out.StartOperation(fmt.Sprintf("[%d/%d] Pushing %s:%s", currentImageIdx, cfg.TotalImages(), image, tag))
-			// Use the deprecated EndOperation instead of EndOperationWithStatus to ensure the correct INF prefix
-			// is printed in the output. This needs to be fixed upstream, but this is ok for now.
-			out.EndOperation(true) //nolint:staticcheck // Needs to be fixed upstream.
-			return nil
-		}
-	}
+	failuresBefore := failures.count()
+	completePush := startPushProgress(
+		out, cfg.TotalImages()*len(destinations), "Pushing bundled images",
+	)
 
 	for _, registryName := range regNames {
 		registryConfig := cfg[registryName]
@@ -546,104 +1108,209 @@ func pushImages(
 			}
 
 			srcRepository := sourceRegistry.Repo(imageName)
-			destRepository := destRegistry.Repo(strings.TrimLeft(destRegistryPath, "/"), imageName)
 
 			imageTags := registryConfig.Images[imageName]
 
-			var (
-				imageTagPrePushSync sync.Once
-				imageTagPrePushErr  error
-				existingImageTags   map[string]struct{}
+			destRepositories, err := prepareDestinations(
+				egCtx, destinations, imageName, imageTags, onExistingTag, remote.WithContext(egCtx),
 			)
+			if err != nil {
+				return err
+			}
 
+			// Fan out each tag to every destination.
 			for _, imageTag := range imageTags {
-				eg.Go(func() error {
-					imageTagPrePushSync.Do(func() {
-						for _, prePush := range prePushFuncs {
-							if err := prePush(destRepository, imageTags...); err != nil {
-								imageTagPrePushErr = fmt.Errorf("pre-push func failed: %w", err)
-							}
+				for _, dest := range destRepositories {
+					eg.Go(func() error {
+						if err := egCtx.Err(); err != nil {
+							return err
+						}
+
+						srcImage := srcRepository.Tag(imageTag)
+						destImage := dest.repository.Tag(imageTag)
+
+						existingImageTags, err := dest.prepare()
+						if err != nil {
+							return failures.record(destImage, err)
 						}
 
-						existingImageTags, imageTagPrePushErr = getExistingImages(
-							context.Background(),
+						pushFn := pushFuncForExistingTag(
 							onExistingTag,
-							puller,
-							destRepository,
+							existingImageTags,
+							imageTag,
+							fmt.Errorf(
+								"failed to push image %s:%s to %s: image tag already exists in destination registry",
+								originImage,
+								imageTag,
+								dest.repository,
+							),
 						)
-					})
 
-					if imageTagPrePushErr != nil {
-						return imageTagPrePushErr
-					}
-
-					srcImage := srcRepository.Tag(imageTag)
-					destImage := destRepository.Tag(imageTag)
-
-					var pushFn pushFunc = pushTag
-
-					switch onExistingTag {
-					case Overwrite, MergeWithRetain, MergeWithOverwrite:
-						// Nothing to do here, pushFn is already set to pushTag above.
-					case Skip:
-						// If tag exists already then do nothing.
-						if _, exists := existingImageTags[imageTag]; exists {
-							pushFn = func(
-								_ name.Reference, _ []remote.Option, _ name.Reference, _ []remote.Option, _ ...pushOpt,
-							) error {
-								return nil
-							}
+						opts := []pushOpt{withOnExistingTagMode(onExistingTag)}
+						if forceOCIMediaTypes {
+							opts = append(opts, withForceOCIMediaTypes(forceOCIMediaTypes))
 						}
-					case Error:
-						if _, exists := existingImageTags[imageTag]; exists {
-							pushFn = func(
-								_ name.Reference, _ []remote.Option, _ name.Reference, _ []remote.Option, _ ...pushOpt,
-							) error {
-								return fmt.Errorf(
-									"failed to push image %s:%s to %s: image tag already exists in destination registry",
-									originImage,
-									imageTag,
-									destRepository,
-								)
-							}
+						var reportEntry *report.Entry
+						if pushReport != nil {
+							reportEntry = report.NewEntry(fmt.Sprintf("%s:%s", originImage, imageTag), destImage)
+							opts = append(opts, withReportEntry(reportEntry))
 						}
-					}
 
-					opts := []pushOpt{withOnExistingTagMode(onExistingTag)}
-					if forceOCIMediaTypes {
-						opts = append(opts, withForceOCIMediaTypes(forceOCIMediaTypes))
-					}
+						if err := pushFn(srcImage, sourceRemoteOpts, destImage, dest.remoteOpts, opts...); err != nil {
+							return failures.record(destImage, fmt.Errorf(
+								"failed to push image %s:%s to %s: %w",
+								originImage,
+								imageTag,
+								dest.repository,
+								err,
+							))
+						}
 
-					if err := pushFn(srcImage, sourceRemoteOpts, destImage, destRemoteOpts, opts...); err != nil {
-						return fmt.Errorf(
-							"failed to push image %s:%s to %s: %w",
-							originImage,
-							imageTag,
-							destRepository,
-							err,
-						)
-					}
+						if reportEntry != nil {
+							pushReport.Add(*reportEntry)
+						}
 
-					if err := completePush(originImage.Name(), imageTag); err != nil {
-						return err
-					}
+						completePush(pushDisplayName(originImage.Name(), imageTag, destImage, len(destinations)))
 
-					return nil
-				})
+						return nil
+					})
+				}
 			}
 		}
 	}
 
-	if err := eg.Wait(); err != nil {
+	waitErr := eg.Wait()
+	// Once interrupted, the remaining pushes fail one by one, so the interruption is returned instead.
+	if err := ctx.Err(); err != nil {
 		out.EndOperationWithStatus(output.Failure())
 		return err
 	}
+	if waitErr != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return waitErr
+	}
+
+	if failures.count() > failuresBefore {
+		out.EndOperationWithStatus(output.Failure())
+		return nil
+	}
 
 	out.EndOperationWithStatus(output.Success())
 
 	return nil
 }
 
+// pushDisplayName returns the name to output for a pushed tag. The destination is only included when
+// pushing to more than one destination registry.
+func pushDisplayName(name, tag string, dest fmt.Stringer, numDestinations int) string {
+	if numDestinations > 1 {
+		return fmt.Sprintf("%s:%s to %s", name, tag, dest)
+	}
+	return fmt.Sprintf("%s:%s", name, tag)
+}
+
+// completePushFunc records that a single tag has been pushed.
+type completePushFunc func(displayName string)
+
+// startPushProgress starts an output operation for pushing total tags. Either use a gauge for interactive
+// TTY or line per tag for non-TTY. The caller is responsible for ending the operation.
+func startPushProgress(out output.Output, total int, status string) completePushFunc {
+	if term.IsSmartTerminal(os.Stderr) {
+		pushGauge := &output.ProgressGauge{}
+		pushGauge.SetCapacity(total)
+		pushGauge.SetStatus(status)
+		out.StartOperationWithProgress(pushGauge)
+		return func(_ string) {
+			pushGauge.Inc()
+		}
+	}
+
+	// Use an output writer mutex to ensure the output is not interleaved.
+	var outputWriterMutex sync.Mutex
+	currentIdx := 0
+	return func(displayName string) {
+		outputWriterMutex.Lock()
+		defer outputWriterMutex.Unlock()
+		currentIdx++
+		out.StartOperation(fmt.Sprintf("[%d/%d] Pushing %s", currentIdx, total, displayName))
+		// Use the deprecated EndOperation instead of EndOperationWithStatus to ensure the correct INF prefix
+		// is printed in the output. This needs to be fixed upstream, but this is ok for now.
+		out.EndOperation(true) //nolint:staticcheck // Needs to be fixed upstream.
+	}
+}
+
+// prepareDestRepositoryOnce returns a function that runs the pre-push funcs for the destination repository
+// and lists its existing tags exactly once, no matter how many concurrent pushes into the repository call it.
+func prepareDestRepositoryOnce(
+	ctx context.Context,
+	destRepository name.Repository,
+	tags []string,
+	onExistingTag onExistingTagMode,
+	puller *remote.Puller,
+	prePushFuncs ...prePushFunc,
+) func() (map[string]struct{}, error) {
+	return sync.OnceValues(func() (map[string]struct{}, error) {
+		for _, prePush := range prePushFuncs {
+			if err := prePush(destRepository, tags...); err != nil {
+				return nil, fmt.Errorf("pre-push func failed: %w", err)
+			}
+		}
+
+		return getExistingImages(
+			ctx,
+			onExistingTag,
+			puller,
+			destRepository,
+		)
+	})
+}
+
+// pushFuncForExistingTag returns the pushFunc to use for tag depending on the existing tag mode and whether
+// the tag already exists in the destination repository. existsErr is returned when the tag exists and the
+// mode is Error.
+func pushFuncForExistingTag(
+	onExistingTag onExistingTagMode,
+	existingTags map[string]struct{},
+	tag string,
+	existsErr error,
+) pushFunc {
+	if _, exists := existingTags[tag]; !exists {
+		return pushTag
+	}
+
+	switch onExistingTag {
+	case Skip:
+		// If tag exists already then do nothing other than reporting the existing tag.
+		return func(
+			_ name.Reference, _ []remote.Option, destImage name.Reference, destRemoteOpts []remote.Option,
+			pushOpts ...pushOpt,
+		) error {
+			var pushCfg pushConfig
+			for _, opt := range pushOpts {
+				opt(&pushCfg)
+			}
+			if pushCfg.reportEntry == nil {
+				return nil
+			}
+
+			desc, err := remote.Get(destImage, destRemoteOpts...)
+			if err != nil {
+				return fmt.Errorf("failed to get existing tag: %w", err)
+			}
+			pushCfg.reportEntry.Action = report.Skipped
+			return pushCfg.reportEntry.SetDescriptor(desc)
+		}
+	case Error:
+		return func(
+			_ name.Reference, _ []remote.Option, _ name.Reference, _ []remote.Option, _ ...pushOpt,
+		) error {
+			return existsErr
+		}
+	default:
+		return pushTag
+	}
+}
+
 func pushTag(
 	srcImage name.Reference,
 	sourceRemoteOpts []remote.Option,
@@ -666,7 +1333,13 @@ func pushTag(
 		if err != nil {
 			return err
 		}
-		return remote.Write(destImage, image, destRemoteOpts...)
+		if err := remote.Write(destImage, image, destRemoteOpts...); err != nil {
+			return err
+		}
+		if pushCfg.reportEntry != nil {
+			return pushCfg.reportEntry.SetImage(image)
+		}
+		return nil
 	}
 
 	idx, err := desc.ImageIndex()
@@ -683,6 +1356,13 @@ func pushTag(
 		if err != nil {
 			return fmt.Errorf("failed to fetch existing index: %w", err)
 		}
+		existingIdxManifest, err := existingIdx.IndexManifest()
+		if err != nil {
+			return fmt.Errorf("failed to read existing index manifest: %w", err)
+		}
+		if pushCfg.reportEntry != nil && len(existingIdxManifest.Manifests) > 0 {
+			pushCfg.reportEntry.Action = report.Merged
+		}
 
 		mergeFromIndex, mergeToIndex := idx, existingIdx
 		if pushCfg.onExistingTag == MergeWithRetain {
@@ -702,19 +1382,39 @@ func pushTag(
 		}
 	}
 
-	return remote.WriteIndex(destImage, idx, destRemoteOpts...)
+	if err := remote.WriteIndex(destImage, idx, destRemoteOpts...); err != nil {
+		return err
+	}
+	if pushCfg.reportEntry != nil {
+		return pushCfg.reportEntry.SetIndex(idx)
+	}
+	return nil
 }
 
 func pushOCIArtifacts(
+	ctx context.Context,
 	cfg config.HelmChartsConfig,
 	sourceRegistry name.Registry, sourceRegistryPath string, sourceRemoteOpts []remote.Option,
-	destRegistry name.Registry, destRegistryPath string, destRemoteOpts []remote.Option,
+	destinations []pushDestination,
+	onExistingTag onExistingTagMode,
+	pushConcurrency int,
 	out output.Output,
-	prePushFuncs ...prePushFunc,
+	failures *pushFailures,
+	pushReport *report.Report,
 ) error {
 	// Sort repositories for deterministic ordering.
 	repoNames := cfg.SortedRepositoryNames()
 
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(pushConcurrency)
+
+	sourceRemoteOpts = append(sourceRemoteOpts, remote.WithContext(egCtx))
+
+	failuresBefore := failures.count()
+	completePush := startPushProgress(
+		out, cfg.TotalCharts()*len(destinations), "Pushing bundled Helm charts",
+	)
+
 	for _, repoName := range repoNames {
 		repoConfig := cfg.Repositories[repoName]
 
@@ -726,43 +1426,96 @@ func pushOCIArtifacts(
 				strings.TrimLeft(sourceRegistryPath, "/"),
 				chartName,
 			)
-			destRepository := destRegistry.Repo(strings.TrimLeft(destRegistryPath, "/"), chartName)
 
 			chartVersions := repoConfig.Charts[chartName]
 
-			for _, prePush := range prePushFuncs {
-				if err := prePush(destRepository, chartVersions...); err != nil {
-					return fmt.Errorf("pre-push func failed: %w", err)
-				}
+			destRepositories, err := prepareDestinations(
+				egCtx, destinations, chartName, chartVersions, onExistingTag, remote.WithContext(egCtx),
+			)
+			if err != nil {
+				return err
 			}
 
+			// Fan out each chart version to every destination.
 			for _, chartVersion := range chartVersions {
-				destChart := destRepository.Tag(chartVersion)
+				for _, dest := range destRepositories {
+					eg.Go(func() error {
+						if err := egCtx.Err(); err != nil {
+							return err
+						}
 
-				out.StartOperation(
-					fmt.Sprintf("Copying %s:%s (from bundle) to %s",
-						chartName, chartVersion,
-						destChart.Name(),
-					),
-				)
+						srcChart := srcRepository.Tag(chartVersion)
+						destChart := dest.repository.Tag(chartVersion)
 
-				srcChart := srcRepository.Tag(chartVersion)
-				src, err := remote.Image(srcChart, sourceRemoteOpts...)
-				if err != nil {
-					out.EndOperationWithStatus(output.Failure())
-					return err
-				}
+						existingChartVersions, err := dest.prepare()
+						if err != nil {
+							return failures.record(destChart, err)
+						}
 
-				if err := remote.Write(destChart, src, destRemoteOpts...); err != nil {
-					out.EndOperationWithStatus(output.Failure())
-					return err
-				}
+						pushFn := pushFuncForExistingTag(
+							onExistingTag,
+							existingChartVersions,
+							chartVersion,
+							fmt.Errorf(
+								"failed to push chart %s:%s to %s: chart version already exists in destination registry",
+								chartName,
+								chartVersion,
+								dest.repository,
+							),
+						)
+
+						var (
+							opts        []pushOpt
+							reportEntry *report.Entry
+						)
+						if pushReport != nil {
+							reportEntry = report.NewEntry(fmt.Sprintf("%s:%s", chartName, chartVersion), destChart)
+							opts = append(opts, withReportEntry(reportEntry))
+						}
 
-				out.EndOperationWithStatus(output.Success())
+						// Charts are single manifests so merging indexes does not apply: the merge modes
+						// behave the same as overwrite.
+						if err := pushFn(srcChart, sourceRemoteOpts, destChart, dest.remoteOpts, opts...); err != nil {
+							return failures.record(destChart, fmt.Errorf(
+								"failed to push chart %s:%s to %s: %w",
+								chartName,
+								chartVersion,
+								dest.repository,
+								err,
+							))
+						}
+
+						if reportEntry != nil {
+							pushReport.Add(*reportEntry)
+						}
+
+						completePush(pushDisplayName(chartName, chartVersion, destChart, len(destinations)))
+
+						return nil
+					})
+				}
 			}
 		}
 	}
 
+	waitErr := eg.Wait()
+	// Once interrupted, the remaining pushes fail one by one, so the interruption is returned instead.
+	if err := ctx.Err(); err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return err
+	}
+	if waitErr != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return waitErr
+	}
+
+	if failures.count() > failuresBefore {
+		out.EndOperationWithStatus(output.Failure())
+		return nil
+	}
+
+	out.EndOperationWithStatus(output.Success())
+
 	return nil
 }
 
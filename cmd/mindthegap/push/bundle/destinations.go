@@ -0,0 +1,164 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/docker/harbor"
+)
+
+// destinationOpts holds the configuration for a single registry that bundles are pushed to.
+type destinationOpts struct {
+	registryURI               *flags.RegistryURI
+	registryCACertificateFile string
+	registrySkipTLSVerify     bool
+	registryUsername          string
+	registryPassword          string
+	registryAuthFile          string
+	ecrLifecyclePolicy        string
+	ecrRepositoryTemplate     string
+	// ecrRoleARN is an IAM role assumed to manage ECR repositories and retrieve ECR credentials.
+	ecrRoleARN string
+
+	// harborCreateProjects enables or disables creating missing Harbor projects. If nil, projects are created
+	// if the registry is detected to be Harbor.
+	harborCreateProjects *bool
+	harborProjectOpts    harbor.ProjectOptions
+
+	// prePushHook is a command run once per destination repository before pushing into it.
+	prePushHook string
+}
+
+func (d destinationOpts) validate() error {
+	if d.registryURI == nil || d.registryURI.Host() == "" {
+		return fmt.Errorf("registry URI is required")
+	}
+	if d.registryCACertificateFile != "" && d.registrySkipTLSVerify {
+		return fmt.Errorf("cannot specify both CA certificate and skip TLS verify")
+	}
+	if (d.registryUsername == "") != (d.registryPassword == "") {
+		return fmt.Errorf("both username and password must be provided together")
+	}
+	return nil
+}
+
+// destinationsFile is the structure of the file passed via --destinations-file.
+type destinationsFile struct {
+	Destinations []destinationConfig `yaml:"destinations"`
+}
+
+// destinationConfig contains information about a single destination registry, read from the
+// destinations file.
+type destinationConfig struct {
+	// Registry is the registry to push to, optionally including a path prefix and scheme.
+	Registry string `yaml:"registry"`
+	// CACertFile is the CA certificate file used to verify TLS of the registry.
+	CACertFile string `yaml:"caCertFile,omitempty"`
+	// InsecureSkipTLSVerify skips TLS verification of the registry.
+	InsecureSkipTLSVerify bool `yaml:"insecureSkipTLSVerify,omitempty"`
+	// Username and password used to authenticate with the registry.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// RegistryAuthFile is a docker config.json, containers auth.json or Kubernetes secret to read credentials from.
+	RegistryAuthFile string `yaml:"registryAuthFile,omitempty"`
+	// ECRLifecyclePolicyFile contains the ECR lifecycle policy for newly created repositories.
+	ECRLifecyclePolicyFile string `yaml:"ecrLifecyclePolicyFile,omitempty"`
+	// ECRRepositoryTemplateFile contains the configuration of newly created ECR repositories.
+	ECRRepositoryTemplateFile string `yaml:"ecrRepositoryTemplateFile,omitempty"`
+	// ECRRoleARN is an IAM role assumed to manage ECR repositories and retrieve ECR credentials, e.g. to push to
+	// the registry of another AWS account.
+	ECRRoleARN string `yaml:"ecrRoleARN,omitempty"`
+	// Harbor configures how missing Harbor projects are created.
+	Harbor harborConfig `yaml:"harbor,omitempty"`
+	// PrePushHook is a command run once per destination repository before pushing into it.
+	PrePushHook string `yaml:"prePushHook,omitempty"`
+}
+
+// harborConfig contains the configuration of projects created in Harbor registries.
+type harborConfig struct {
+	// CreateProjects enables or disables creating missing projects. If unset, projects are created if the
+	// registry is detected to be Harbor.
+	CreateProjects *bool `yaml:"createProjects,omitempty"`
+	// PublicProjects makes created projects public.
+	PublicProjects bool `yaml:"publicProjects,omitempty"`
+	// ProjectStorageQuota is the storage quota of created projects, e.g. 10GiB. Unlimited if unset.
+	ProjectStorageQuota string `yaml:"projectStorageQuota,omitempty"`
+	// AutoScan enables vulnerability scanning on push for created projects.
+	AutoScan bool `yaml:"autoScan,omitempty"`
+}
+
+// parseDestinationsFile reads the destination registries from the given file. Relative file paths
+// in the destinations file are resolved relative to the directory containing the file.
+func parseDestinationsFile(destinationsFileName string) ([]destinationOpts, error) {
+	f, err := os.Open(destinationsFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destinations file: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		cfg destinationsFile
+		dec = yaml.NewDecoder(f)
+	)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse destinations file: %w", err)
+	}
+	if len(cfg.Destinations) == 0 {
+		return nil, fmt.Errorf("destinations file %s does not contain any destinations", destinationsFileName)
+	}
+
+	baseDir := filepath.Dir(destinationsFileName)
+	resolvePath := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(baseDir, p)
+	}
+
+	destinations := make([]destinationOpts, 0, len(cfg.Destinations))
+	for i, d := range cfg.Destinations {
+		registryURI, err := flags.NewRegistryURI(d.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry for destination %d: %w", i, err)
+		}
+		var storageQuota int64
+		if d.Harbor.ProjectStorageQuota != "" {
+			storageQuota, err = flags.ParseByteSize(d.Harbor.ProjectStorageQuota)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Harbor project storage quota for destination %d: %w", i, err)
+			}
+		}
+		dest := destinationOpts{
+			registryURI:               registryURI,
+			registryCACertificateFile: resolvePath(d.CACertFile),
+			registrySkipTLSVerify:     d.InsecureSkipTLSVerify,
+			registryUsername:          d.Username,
+			registryPassword:          d.Password,
+			registryAuthFile:          resolvePath(d.RegistryAuthFile),
+			ecrLifecyclePolicy:        resolvePath(d.ECRLifecyclePolicyFile),
+			ecrRepositoryTemplate:     resolvePath(d.ECRRepositoryTemplateFile),
+			ecrRoleARN:                d.ECRRoleARN,
+			harborCreateProjects:      d.Harbor.CreateProjects,
+			harborProjectOpts: harbor.ProjectOptions{
+				Public:            d.Harbor.PublicProjects,
+				StorageQuotaBytes: storageQuota,
+				AutoScan:          d.Harbor.AutoScan,
+			},
+			prePushHook: d.PrePushHook,
+		}
+		if err := dest.validate(); err != nil {
+			return nil, fmt.Errorf("invalid destination %d (%s): %w", i, d.Registry, err)
+		}
+		destinations = append(destinations, dest)
+	}
+
+	return destinations, nil
+}
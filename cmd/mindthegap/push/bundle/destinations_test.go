@@ -0,0 +1,138 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/mindthegap/docker/harbor"
+)
+
+func TestParseDestinationsFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		check   func(t *testing.T, dir string, destinations []destinationOpts)
+		wantErr string
+	}{{
+		name: "multiple destinations",
+		content: `
+destinations:
+  - registry: https://primary.example.com/mirror
+    caCertFile: certs/primary.crt
+    username: user
+    password: pass
+  - registry: http://dr.example.com:5000
+    registryAuthFile: auth/config.json
+    ecrLifecyclePolicyFile: /abs/policy.json
+`,
+		check: func(t *testing.T, dir string, destinations []destinationOpts) {
+			t.Helper()
+			require.Len(t, destinations, 2)
+			assert.Equal(t, "primary.example.com", destinations[0].registryURI.Host())
+			assert.Equal(t, "/mirror", destinations[0].registryURI.Path())
+			assert.Equal(t, filepath.Join(dir, "certs", "primary.crt"), destinations[0].registryCACertificateFile)
+			assert.Equal(t, "user", destinations[0].registryUsername)
+			assert.Equal(t, "pass", destinations[0].registryPassword)
+			assert.Equal(t, "dr.example.com:5000", destinations[1].registryURI.Host())
+			assert.Equal(t, filepath.Join(dir, "auth", "config.json"), destinations[1].registryAuthFile)
+			assert.Equal(t, "/abs/policy.json", destinations[1].ecrLifecyclePolicy)
+		},
+	}, {
+		name: "harbor projects",
+		content: `
+destinations:
+  - registry: https://harbor.example.com/mirror
+    harbor:
+      createProjects: true
+      publicProjects: true
+      projectStorageQuota: 10GiB
+      autoScan: true
+  - registry: https://other.example.com
+`,
+		check: func(t *testing.T, _ string, destinations []destinationOpts) {
+			t.Helper()
+			require.Len(t, destinations, 2)
+			require.NotNil(t, destinations[0].harborCreateProjects)
+			assert.True(t, *destinations[0].harborCreateProjects)
+			assert.Equal(t, harbor.ProjectOptions{
+				Public:            true,
+				StorageQuotaBytes: 10 << 30,
+				AutoScan:          true,
+			}, destinations[0].harborProjectOpts)
+			assert.Nil(t, destinations[1].harborCreateProjects)
+		},
+	}, {
+		name: "ECR destinations in multiple accounts",
+		content: `
+destinations:
+  - registry: 111111111111.dkr.ecr.us-east-1.amazonaws.com/mirror
+  - registry: 222222222222.dkr.ecr.us-east-1.amazonaws.com/mirror
+    ecrRoleARN: arn:aws:iam::222222222222:role/mirror-push
+  - registry: public.ecr.aws/example
+    ecrRoleARN: arn:aws:iam::333333333333:role/mirror-push
+`,
+		check: func(t *testing.T, _ string, destinations []destinationOpts) {
+			t.Helper()
+			require.Len(t, destinations, 3)
+			assert.Empty(t, destinations[0].ecrRoleARN)
+			assert.Equal(t, "arn:aws:iam::222222222222:role/mirror-push", destinations[1].ecrRoleARN)
+			assert.Equal(t, "public.ecr.aws", destinations[2].registryURI.Host())
+			assert.Equal(t, "/example", destinations[2].registryURI.Path())
+			assert.Equal(t, "arn:aws:iam::333333333333:role/mirror-push", destinations[2].ecrRoleARN)
+		},
+	}, {
+		name:    "invalid harbor project storage quota",
+		content: "destinations:\n  - registry: example.com\n    harbor:\n      projectStorageQuota: lots\n",
+		wantErr: "invalid Harbor project storage quota",
+	}, {
+		name:    "no destinations",
+		content: "destinations: []\n",
+		wantErr: "does not contain any destinations",
+	}, {
+		name:    "unknown field",
+		content: "destinations:\n  - registry: example.com\n    unknown: true\n",
+		wantErr: "failed to parse destinations file",
+	}, {
+		name: "CA certificate and skip TLS verify",
+		content: `
+destinations:
+  - registry: example.com
+    caCertFile: ca.crt
+    insecureSkipTLSVerify: true
+`,
+		wantErr: "cannot specify both CA certificate and skip TLS verify",
+	}, {
+		name:    "username without password",
+		content: "destinations:\n  - registry: example.com\n    username: user\n",
+		wantErr: "both username and password must be provided together",
+	}, {
+		name:    "missing registry",
+		content: "destinations:\n  - username: user\n    password: pass\n",
+		wantErr: "registry URI is required",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			f := filepath.Join(dir, "destinations.yaml")
+			require.NoError(t, os.WriteFile(f, []byte(tt.content), 0o600))
+
+			destinations, err := parseDestinationsFile(f)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, dir, destinations)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+)
+
+func TestHarborPrePushFunc(t *testing.T) {
+	t.Parallel()
+
+	harborSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2.0/systeminfo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"harbor_version":"v2.11.0"}`))
+	}))
+	t.Cleanup(harborSrv.Close)
+	otherSrv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(otherSrv.Close)
+
+	tests := []struct {
+		name           string
+		srv            *httptest.Server
+		createProjects *bool
+		wantFunc       bool
+	}{
+		{name: "auto-detected Harbor", srv: harborSrv, wantFunc: true},
+		{name: "auto-detected non-Harbor", srv: otherSrv, wantFunc: false},
+		{name: "explicitly disabled", srv: harborSrv, createProjects: new(false), wantFunc: false},
+		{name: "explicitly enabled", srv: otherSrv, createProjects: new(true), wantFunc: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registryURI, err := flags.NewRegistryURI(tt.srv.URL)
+			require.NoError(t, err)
+			destRegistry, err := name.NewRegistry(registryURI.Host(), name.Insecure)
+			require.NoError(t, err)
+
+			buf := &bytes.Buffer{}
+			fn, err := harborPrePushFunc(
+				context.Background(),
+				destinationOpts{registryURI: registryURI, harborCreateProjects: tt.createProjects},
+				destRegistry,
+				tt.srv.Client().Transport,
+				authn.DefaultKeychain,
+				harborDetection{},
+				output.NewNonInteractiveShell(buf, buf, 0),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFunc, fn != nil)
+		})
+	}
+}
+
+func TestHarborPrePushFuncProbesHostOnce(t *testing.T) {
+	t.Parallel()
+
+	var probes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		_, _ = w.Write([]byte(`{"harbor_version":"v2.11.0"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	harborHosts := harborDetection{}
+	for _, path := range []string{"/mirror", "/other"} {
+		registryURI, err := flags.NewRegistryURI(srv.URL + path)
+		require.NoError(t, err)
+		destRegistry, err := name.NewRegistry(registryURI.Host(), name.Insecure)
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		fn, err := harborPrePushFunc(
+			context.Background(),
+			destinationOpts{registryURI: registryURI},
+			destRegistry,
+			srv.Client().Transport,
+			authn.DefaultKeychain,
+			harborHosts,
+			output.NewNonInteractiveShell(buf, buf, 0),
+		)
+		require.NoError(t, err)
+		assert.NotNil(t, fn)
+	}
+	assert.Equal(t, int32(1), probes.Load())
+}
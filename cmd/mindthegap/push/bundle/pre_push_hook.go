@@ -0,0 +1,71 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+// prePushHookInput is written as JSON to the stdin of the pre-push hook command.
+type prePushHookInput struct {
+	// Registry is the destination registry host.
+	Registry string `json:"registry"`
+	// Repository is the repository path within the destination registry.
+	Repository string `json:"repository"`
+	// Name is the fully qualified repository name, including the registry.
+	Name string `json:"name"`
+	// Tags are the tags that are about to be pushed to the repository.
+	Tags []string `json:"tags"`
+}
+
+// prePushHookFunc returns a prePushFunc that runs command once per destination repository. The command is split
+// on whitespace into the executable and its arguments, without any shell interpretation. The command is killed
+// if ctx is done before it exits.
+func prePushHookFunc(ctx context.Context, command string, out output.Output) (prePushFunc, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("pre-push hook command must not be empty")
+	}
+
+	return func(destRepositoryName name.Repository, imageTags ...string) error {
+		input, err := json.Marshal(prePushHookInput{
+			Registry:   destRepositoryName.RegistryStr(),
+			Repository: destRepositoryName.RepositoryStr(),
+			Name:       destRepositoryName.Name(),
+			Tags:       append([]string{}, imageTags...),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal pre-push hook input: %w", err)
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // Command is configured by the user.
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		runErr := cmd.Run()
+		if stdout.Len() > 0 {
+			out.V(4).Infof("Pre-push hook output for %s:\n%s", destRepositoryName, stdout.String())
+		}
+		if runErr != nil {
+			return fmt.Errorf(
+				"pre-push hook failed for repository %s: %w: %s",
+				destRepositoryName,
+				runErr,
+				strings.TrimSpace(stderr.String()),
+			)
+		}
+		return nil
+	}, nil
+}
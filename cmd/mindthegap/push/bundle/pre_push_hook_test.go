@@ -0,0 +1,98 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+)
+
+func TestPrePushHookFunc(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a shell script as the hook")
+	}
+
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.json")
+	hook := filepath.Join(dir, "hook.sh")
+	require.NoError(t, os.WriteFile(hook, []byte(`#!/bin/sh
+cat > "$1"
+if grep -q forbidden "$1"; then
+  echo "repository is forbidden" >&2
+  exit 3
+fi
+`), 0o700))
+
+	buf := &bytes.Buffer{}
+	hookFn, err := prePushHookFunc(
+		context.Background(), hook+" "+inputFile, output.NewNonInteractiveShell(buf, buf, 0),
+	)
+	require.NoError(t, err)
+
+	repo, err := name.NewRepository("registry.example.com:5000/mirror/nginx")
+	require.NoError(t, err)
+	require.NoError(t, hookFn(repo, "1.0", "1.1"))
+
+	b, err := os.ReadFile(inputFile)
+	require.NoError(t, err)
+	var got prePushHookInput
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, prePushHookInput{
+		Registry:   "registry.example.com:5000",
+		Repository: "mirror/nginx",
+		Name:       "registry.example.com:5000/mirror/nginx",
+		Tags:       []string{"1.0", "1.1"},
+	}, got)
+
+	forbidden, err := name.NewRepository("registry.example.com:5000/forbidden/nginx")
+	require.NoError(t, err)
+	err = hookFn(forbidden, "1.0")
+	require.ErrorContains(t, err, "pre-push hook failed for repository registry.example.com:5000/forbidden/nginx")
+	require.ErrorContains(t, err, "exit status 3")
+	require.ErrorContains(t, err, "repository is forbidden")
+}
+
+func TestPrePushHookFuncEmptyCommand(t *testing.T) {
+	t.Parallel()
+
+	_, err := prePushHookFunc(
+		context.Background(), "  ", output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0),
+	)
+	require.ErrorContains(t, err, "must not be empty")
+}
+
+func TestPrePushHookFuncCanceled(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses sleep as the hook")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	t.Cleanup(cancel)
+	hookFn, err := prePushHookFunc(
+		ctx, "sleep 60", output.NewNonInteractiveShell(&bytes.Buffer{}, &bytes.Buffer{}, 0),
+	)
+	require.NoError(t, err)
+
+	repo, err := name.NewRepository("registry.example.com/mirror/nginx")
+	require.NoError(t, err)
+	start := time.Now()
+	require.ErrorContains(t, hookFn(repo, "1.0"), "signal: killed")
+	assert.Less(t, time.Since(start), 30*time.Second)
+}
@@ -0,0 +1,241 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/push/report"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+func newTestRegistry(t *testing.T) name.Registry {
+	t.Helper()
+	srv := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(srv.Close)
+	reg, err := name.NewRegistry(srv.Listener.Addr().String(), name.Insecure)
+	require.NoError(t, err)
+	return reg
+}
+
+func digestOf(t *testing.T, ref name.Reference) v1.Hash {
+	t.Helper()
+	desc, err := remote.Head(ref)
+	require.NoError(t, err)
+	return desc.Digest
+}
+
+func TestPushOCIArtifacts(t *testing.T) {
+	t.Parallel()
+
+	chartVersions := []string{"1.0.0", "1.1.0", "2.0.0"}
+	cfg := config.HelmChartsConfig{
+		Repositories: map[string]config.HelmRepositorySyncConfig{
+			"podinfo": {Charts: map[string][]string{"podinfo": chartVersions}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		onExistingTag   onExistingTagMode
+		wantErr         string
+		wantOverwritten bool
+	}{{
+		name:            "overwrite",
+		onExistingTag:   Overwrite,
+		wantOverwritten: true,
+	}, {
+		name:            "merge-with-overwrite behaves as overwrite",
+		onExistingTag:   MergeWithOverwrite,
+		wantOverwritten: true,
+	}, {
+		name:          "skip",
+		onExistingTag: Skip,
+	}, {
+		name:          "error",
+		onExistingTag: Error,
+		wantErr:       "chart version already exists in destination registry",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srcRegistry := newTestRegistry(t)
+			destRegistry := newTestRegistry(t)
+
+			bundled := make(map[string]v1.Hash, len(chartVersions))
+			for _, v := range chartVersions {
+				chrt := bundletest.NewChart(t)
+				require.NoError(t, remote.Write(srcRegistry.Repo("charts", "podinfo").Tag(v), chrt))
+				bundled[v], _ = chrt.Digest()
+			}
+
+			// Pre-populate a single chart version in the destination with different content.
+			existing := bundletest.NewChart(t)
+			existingTag := destRegistry.Repo("podinfo").Tag("1.1.0")
+			require.NoError(t, remote.Write(existingTag, existing))
+			existingDigest, err := existing.Digest()
+			require.NoError(t, err)
+
+			var prePushCalls int
+			countPrePush := func(name.Repository, ...string) error {
+				prePushCalls++
+				return nil
+			}
+
+			buf := &bytes.Buffer{}
+			err = pushOCIArtifacts(
+				context.Background(),
+				cfg,
+				srcRegistry, "/charts", nil,
+				[]pushDestination{{registry: destRegistry, prePushFuncs: []prePushFunc{countPrePush}}},
+				tt.onExistingTag,
+				len(chartVersions),
+				output.NewNonInteractiveShell(buf, buf, 0),
+				nil,
+				nil,
+			)
+			assert.Equal(t, 1, prePushCalls, "pre-push func must run once per repository")
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				assert.Equal(t, existingDigest, digestOf(t, existingTag))
+				return
+			}
+			require.NoError(t, err)
+
+			for _, v := range chartVersions {
+				got := digestOf(t, destRegistry.Repo("podinfo").Tag(v))
+				if v == "1.1.0" && !tt.wantOverwritten {
+					assert.Equal(t, existingDigest, got)
+					continue
+				}
+				assert.Equal(t, bundled[v], got)
+			}
+		})
+	}
+}
+
+func TestPushOCIArtifactsToMultipleDestinations(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	chrt := bundletest.NewChart(t)
+	require.NoError(t, remote.Write(srcRegistry.Repo("charts", "podinfo").Tag("1.0.0"), chrt))
+	wantDigest, err := chrt.Digest()
+	require.NoError(t, err)
+
+	primary, dr := newTestRegistry(t), newTestRegistry(t)
+
+	buf := &bytes.Buffer{}
+	err = pushOCIArtifacts(
+		context.Background(),
+		config.HelmChartsConfig{
+			Repositories: map[string]config.HelmRepositorySyncConfig{
+				"podinfo": {Charts: map[string][]string{"podinfo": {"1.0.0"}}},
+			},
+		},
+		srcRegistry, "/charts", nil,
+		[]pushDestination{{registry: primary}, {registry: dr, path: "/mirror"}},
+		Overwrite,
+		2,
+		output.NewNonInteractiveShell(buf, buf, 0),
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, wantDigest, digestOf(t, primary.Repo("podinfo").Tag("1.0.0")))
+	assert.Equal(t, wantDigest, digestOf(t, dr.Repo("mirror", "podinfo").Tag("1.0.0")))
+}
+
+func TestPushOCIArtifactsCanceled(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	require.NoError(t, remote.Write(srcRegistry.Repo("charts", "podinfo").Tag("1.0.0"), bundletest.NewChart(t)))
+	destRegistry := newTestRegistry(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	buf := &bytes.Buffer{}
+	err := pushOCIArtifacts(
+		ctx,
+		config.HelmChartsConfig{
+			Repositories: map[string]config.HelmRepositorySyncConfig{
+				"podinfo": {Charts: map[string][]string{"podinfo": {"1.0.0"}}},
+			},
+		},
+		srcRegistry, "/charts", nil,
+		[]pushDestination{{registry: destRegistry}},
+		Overwrite,
+		1,
+		output.NewNonInteractiveShell(buf, buf, 0),
+		// Interrupted pushes are not reported as individual failures even when continuing on error.
+		newPushFailures(),
+		nil,
+	)
+	require.ErrorIs(t, err, context.Canceled)
+
+	_, err = remote.Head(destRegistry.Repo("podinfo").Tag("1.0.0"))
+	require.Error(t, err)
+}
+
+func TestPushOCIArtifactsReport(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		require.NoError(t, remote.Write(srcRegistry.Repo("charts", "podinfo").Tag(v), bundletest.NewChart(t)))
+	}
+
+	destRegistry := newTestRegistry(t)
+	existingTag := destRegistry.Repo("podinfo").Tag("1.1.0")
+	require.NoError(t, remote.Write(existingTag, bundletest.NewChart(t)))
+
+	pushReport := report.New()
+	buf := &bytes.Buffer{}
+	err := pushOCIArtifacts(
+		context.Background(),
+		config.HelmChartsConfig{
+			Repositories: map[string]config.HelmRepositorySyncConfig{
+				"podinfo": {Charts: map[string][]string{"podinfo": {"1.0.0", "1.1.0"}}},
+			},
+		},
+		srcRegistry, "/charts", nil,
+		[]pushDestination{{registry: destRegistry}},
+		Skip,
+		1,
+		output.NewNonInteractiveShell(buf, buf, 0),
+		nil,
+		pushReport,
+	)
+	require.NoError(t, err)
+
+	entries := pushReport.Entries()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "podinfo:1.0.0", entries[0].Source)
+	assert.Equal(t, report.Pushed, entries[0].Action)
+	assert.Equal(t, digestOf(t, destRegistry.Repo("podinfo").Tag("1.0.0")).String(), entries[0].Digest)
+	assert.Empty(t, entries[0].Platforms)
+
+	assert.Equal(t, existingTag.String(), entries[1].Destination)
+	assert.Equal(t, report.Skipped, entries[1].Action)
+	assert.Equal(t, digestOf(t, existingTag).String(), entries[1].Digest)
+	assert.Equal(t, string(types.OCIManifestSchema1), entries[1].MediaType)
+}
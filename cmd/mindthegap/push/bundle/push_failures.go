@@ -0,0 +1,69 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// pushFailures collects the references that failed to push when continuing on error. A nil *pushFailures
+// records nothing, so that the first failure aborts the push.
+type pushFailures struct {
+	mu       sync.Mutex
+	failures map[string]error
+}
+
+func newPushFailures() *pushFailures {
+	return &pushFailures{failures: map[string]error{}}
+}
+
+// record records that pushing ref failed with err. It returns nil if failures are being collected so that the
+// push continues, and err otherwise.
+func (f *pushFailures) record(ref fmt.Stringer, err error) error {
+	if f == nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[ref.String()] = err
+	return nil
+}
+
+// count returns the number of recorded failures.
+func (f *pushFailures) count() int {
+	if f == nil {
+		return 0
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.failures)
+}
+
+// err returns an error summarising all recorded failures, sorted by reference, or nil if there were none.
+func (f *pushFailures) err() error {
+	if f.count() == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	refs := make([]string, 0, len(f.failures))
+	for ref := range f.failures {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "failed to push %d reference(s):", len(refs))
+	for _, ref := range refs {
+		fmt.Fprintf(&sb, "\n  %s: %v", ref, f.failures[ref])
+	}
+	return fmt.Errorf("%s", sb.String())
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+func TestPushOCIArtifactsContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	chartVersions := []string{"1.0.0", "1.1.0", "2.0.0"}
+	srcRegistry := newTestRegistry(t)
+	for _, v := range chartVersions {
+		require.NoError(t, remote.Write(srcRegistry.Repo("charts", "podinfo").Tag(v), bundletest.NewChart(t)))
+	}
+
+	destRegistry := newTestRegistry(t)
+	require.NoError(t, remote.Write(destRegistry.Repo("podinfo").Tag("1.1.0"), bundletest.NewChart(t)))
+
+	failures := newPushFailures()
+	buf := &bytes.Buffer{}
+	err := pushOCIArtifacts(
+		context.Background(),
+		config.HelmChartsConfig{
+			Repositories: map[string]config.HelmRepositorySyncConfig{
+				"podinfo": {Charts: map[string][]string{"podinfo": chartVersions}},
+			},
+		},
+		srcRegistry, "/charts", nil,
+		[]pushDestination{{registry: destRegistry}},
+		Error,
+		1,
+		output.NewNonInteractiveShell(buf, buf, 0),
+		failures,
+		nil,
+	)
+	require.NoError(t, err)
+
+	for _, v := range []string{"1.0.0", "2.0.0"} {
+		_, err := remote.Head(destRegistry.Repo("podinfo").Tag(v))
+		assert.NoError(t, err, "chart version %s should have been pushed", v)
+	}
+
+	assert.Equal(t, 1, failures.count())
+	require.ErrorContains(t, failures.err(), "failed to push 1 reference(s):")
+	require.ErrorContains(t, failures.err(), destRegistry.Repo("podinfo").Tag("1.1.0").String())
+}
+
+func TestPushOCIArtifactsRetriesTransientErrors(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	chrt := bundletest.NewChart(t)
+	require.NoError(t, remote.Write(srcRegistry.Repo("charts", "podinfo").Tag("1.0.0"), chrt))
+
+	// Fail the first two manifest uploads with a transient error.
+	var manifestPuts atomic.Int32
+	inner := ggcrregistry.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/manifests/") &&
+			manifestPuts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		inner.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	destRegistry, err := name.NewRegistry(srv.Listener.Addr().String(), name.Insecure)
+	require.NoError(t, err)
+
+	push := func(retries int) error {
+		buf := &bytes.Buffer{}
+		return pushOCIArtifacts(
+			context.Background(),
+			config.HelmChartsConfig{
+				Repositories: map[string]config.HelmRepositorySyncConfig{
+					"podinfo": {Charts: map[string][]string{"podinfo": {"1.0.0"}}},
+				},
+			},
+			srcRegistry, "/charts", nil,
+			[]pushDestination{{
+				registry: destRegistry,
+				remoteOpts: []remote.Option{remote.WithRetryBackoff(remote.Backoff{
+					Duration: time.Millisecond,
+					Factor:   1,
+					Steps:    retries + 1,
+				})},
+			}},
+			Overwrite,
+			1,
+			output.NewNonInteractiveShell(buf, buf, 0),
+			nil,
+			nil,
+		)
+	}
+
+	require.Error(t, push(0))
+	require.NoError(t, push(2))
+
+	wantDigest, err := chrt.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, wantDigest, digestOf(t, destRegistry.Repo("podinfo").Tag("1.0.0")))
+}
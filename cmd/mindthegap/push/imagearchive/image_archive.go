@@ -20,6 +20,7 @@ import (
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/flags"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/push/report"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/utils"
 	"github.com/mesosphere/mindthegap/images/archive"
 	"github.com/mesosphere/mindthegap/images/authnhelpers"
@@ -35,7 +36,10 @@ func NewCommand(out output.Output) *cobra.Command {
 		destRegistrySkipTLSVerify     bool
 		destRegistryUsername          string
 		destRegistryPassword          string
+		registryAuthFile              string
 		imageTagOverride              string
+		maxBandwidth                  flags.Bandwidth
+		reportFile                    string
 	)
 
 	cmd := &cobra.Command{
@@ -59,7 +63,10 @@ func NewCommand(out output.Output) *cobra.Command {
 				destRegistrySkipTLSVerify,
 				destRegistryUsername,
 				destRegistryPassword,
+				registryAuthFile,
 				imageTagOverride,
+				maxBandwidth.BytesPerSecond(),
+				reportFile,
 			)
 		},
 	}
@@ -91,6 +98,9 @@ func NewCommand(out output.Output) *cobra.Command {
 		"to-registry-username",
 		"to-registry-password",
 	)
+	cmd.Flags().StringVar(&registryAuthFile, "registry-auth-file", "",
+		"Registry auth file to read destination registry credentials from: a docker config.json, containers "+
+			"auth.json or Kubernetes dockerconfigjson secret, including credential helpers")
 
 	cmd.Flags().StringVar(&imageTagOverride, "image-tag", "",
 		"Destination image reference (repo:tag) to use when the archive "+
@@ -98,6 +108,11 @@ func NewCommand(out output.Output) *cobra.Command {
 			"if the archive has no embedded tag. Only valid when exactly "+
 			"one archive with one image is provided.")
 
+	cmd.Flags().Var(&maxBandwidth, "max-bandwidth",
+		"Maximum bandwidth to use across all pushes, e.g. 50MiB/s (default unlimited)")
+	cmd.Flags().StringVar(&reportFile, "report", "",
+		"File to write a JSON report of all pushed references, including their digests, to")
+
 	return cmd
 }
 
@@ -110,7 +125,10 @@ func runPushImageArchive(
 	destRegistrySkipTLSVerify bool,
 	destRegistryUsername string,
 	destRegistryPassword string,
+	registryAuthFile string,
 	imageTagOverride string,
+	maxBandwidthBytesPerSecond int64,
+	reportFile string,
 ) error {
 	paths, err := utils.FilesWithGlobs(archiveFiles)
 	if err != nil {
@@ -138,7 +156,10 @@ func runPushImageArchive(
 	}
 	destRemoteOpts := make([]remote.Option, 0, 3)
 	destRemoteOpts = append(destRemoteOpts,
-		remote.WithTransport(destTLSRoundTripper),
+		remote.WithTransport(httputils.BandwidthLimitedRoundTripper(
+			destTLSRoundTripper,
+			httputils.NewBandwidthLimiter(maxBandwidthBytesPerSecond),
+		)),
 		remote.WithUserAgent(utils.Useragent()),
 	)
 
@@ -149,6 +170,13 @@ func runPushImageArchive(
 	destNameOpts = append(destNameOpts, ggcrname.StrictValidation)
 
 	var keychain authn.Keychain = authn.DefaultKeychain
+	if registryAuthFile != "" {
+		authFileKeychain, err := authnhelpers.NewAuthFileKeychain(registryAuthFile)
+		if err != nil {
+			return err
+		}
+		keychain = authn.NewMultiKeychain(authFileKeychain, keychain)
+	}
 	if destRegistryUsername != "" && destRegistryPassword != "" {
 		keychain = authn.NewMultiKeychain(
 			authn.NewKeychainFromHelper(
@@ -170,6 +198,11 @@ func runPushImageArchive(
 		return fmt.Errorf("parsing destination registry: %w", err)
 	}
 
+	var pushReport *report.Report
+	if reportFile != "" {
+		pushReport = report.New()
+	}
+
 	for _, oa := range opened {
 		for i := range oa.entries {
 			entry := oa.entries[i]
@@ -178,6 +211,7 @@ func runPushImageArchive(
 				return fmt.Errorf("resolving destination reference for %s: %w", oa.path, err)
 			}
 			displayName := destRef.Name()
+			reportEntry := report.NewEntry(sourceRef(oa.path, entry), destRef)
 			out.StartOperation(fmt.Sprintf("Pushing %s", displayName))
 			switch {
 			case entry.Image != nil:
@@ -185,21 +219,47 @@ func runPushImageArchive(
 					out.EndOperationWithStatus(output.Failure())
 					return fmt.Errorf("pushing %s: %w", displayName, err)
 				}
+				err = reportEntry.SetImage(entry.Image)
 			case entry.Index != nil:
 				if err := remote.WriteIndex(destRef, entry.Index, destRemoteOpts...); err != nil {
 					out.EndOperationWithStatus(output.Failure())
 					return fmt.Errorf("pushing %s: %w", displayName, err)
 				}
+				err = reportEntry.SetIndex(entry.Index)
 			default:
 				out.EndOperationWithStatus(output.Failure())
 				return fmt.Errorf("archive %s: entry has neither image nor index", oa.path)
 			}
+			if err != nil {
+				out.EndOperationWithStatus(output.Failure())
+				return fmt.Errorf("reporting %s: %w", displayName, err)
+			}
+			pushReport.Add(*reportEntry)
 			out.EndOperationWithStatus(output.Success())
 		}
 	}
+
+	if reportFile != "" {
+		out.StartOperation(fmt.Sprintf("Writing push report to %s", reportFile))
+		if err := pushReport.WriteFile(reportFile); err != nil {
+			out.EndOperationWithStatus(output.Failure())
+			return err
+		}
+		out.EndOperationWithStatus(output.Success())
+	}
+
 	return nil
 }
 
+// sourceRef returns the reference to report as the source of entry: the embedded reference if the archive
+// has one, otherwise the archive path.
+func sourceRef(archivePath string, entry archive.Entry) string {
+	if entry.Ref != nil {
+		return entry.Ref.String()
+	}
+	return archivePath
+}
+
 // resolveDestRef decides the destination reference for the given
 // entry: use imageTagOverride when set, otherwise use the embedded
 // reference stripped of its origin registry host. The destination
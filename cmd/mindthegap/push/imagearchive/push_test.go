@@ -5,10 +5,13 @@ package imagearchive_test
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -19,6 +22,7 @@ import (
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/push/imagearchive"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/push/report"
 	"github.com/mesosphere/mindthegap/images/archive/testutil"
 )
 
@@ -102,6 +106,41 @@ func TestPushDockerArchive_BasicAuth(t *testing.T) {
 	}
 }
 
+func TestPushDockerArchive_RegistryAuthFile(t *testing.T) {
+	const user, pass = "u", "p"
+	srv := httptest.NewServer(basicAuthWrap(registry.New(), user, pass))
+	defer srv.Close()
+	regHost := srv.Listener.Addr().String()
+
+	tmp := t.TempDir()
+	archivePath := filepath.Join(tmp, "auth.tar")
+	testutil.BuildDockerArchive(t, archivePath, "example.com/app:v1")
+
+	authFile := filepath.Join(tmp, "auth.json")
+	authFileContent := fmt.Sprintf(
+		`{"auths":{%q:{"auth":%q}}}`,
+		regHost, base64.StdEncoding.EncodeToString([]byte(user+":"+pass)),
+	)
+	if err := os.WriteFile(authFile, []byte(authFileContent), 0o600); err != nil {
+		t.Fatalf("write auth file: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	out := output.NewNonInteractiveShell(buf, buf, 0)
+	cmd := imagearchive.NewCommand(out)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetArgs([]string{
+		"--image-archive", archivePath,
+		"--to-registry", fmt.Sprintf("http://%s", regHost),
+		"--to-registry-insecure-skip-tls-verify",
+		"--registry-auth-file", authFile,
+	})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute: %v\noutput:\n%s", err, buf.String())
+	}
+}
+
 // bearerAuthWrap wraps inner with a Bearer-token challenge served from the
 // same host:port as the registry. /token mints a token in exchange for
 // HTTP basic credentials; every other request requires a matching bearer
@@ -280,3 +319,57 @@ func TestPushOCIArchive_EndToEnd(t *testing.T) {
 		t.Fatalf("digest mismatch: got %s, want %s", gotDigest, wantDigest)
 	}
 }
+
+func TestPushDockerArchive_Report(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	regHost := srv.Listener.Addr().String()
+
+	tmp := t.TempDir()
+	archivePath := filepath.Join(tmp, "src.tar")
+	img := testutil.BuildDockerArchive(t, archivePath, "example.com/app:v1")
+	reportPath := filepath.Join(tmp, "report.json")
+
+	buf := &bytes.Buffer{}
+	out := output.NewNonInteractiveShell(buf, buf, 0)
+	cmd := imagearchive.NewCommand(out)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetArgs([]string{
+		"--image-archive", archivePath,
+		"--to-registry", fmt.Sprintf("http://%s", regHost),
+		"--to-registry-insecure-skip-tls-verify",
+		"--report", reportPath,
+	})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute: %v\noutput:\n%s", err, buf.String())
+	}
+
+	b, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("reading report: %v", err)
+	}
+	var got struct {
+		References []report.Entry `json:"references"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("parsing report: %v", err)
+	}
+	if len(got.References) != 1 {
+		t.Fatalf("expected 1 reference in report, got %d", len(got.References))
+	}
+	wantDigest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("want digest: %v", err)
+	}
+	entry := got.References[0]
+	if entry.Digest != wantDigest.String() {
+		t.Fatalf("digest mismatch: got %s, want %s", entry.Digest, wantDigest)
+	}
+	if want := fmt.Sprintf("%s/app@%s", regHost, wantDigest); entry.DigestReference != want {
+		t.Fatalf("digest reference mismatch: got %s, want %s", entry.DigestReference, want)
+	}
+	if entry.Action != report.Pushed {
+		t.Fatalf("unexpected action: %s", entry.Action)
+	}
+}
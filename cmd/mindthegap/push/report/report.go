@@ -0,0 +1,203 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package report records the references pushed to registries so that they can be written to a machine-readable
+// report once pushing has completed.
+package report
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Action is the action taken for a pushed reference.
+type Action string
+
+const (
+	// Pushed means the reference was pushed to the destination registry.
+	Pushed Action = "pushed"
+	// Skipped means the reference already existed in the destination registry and was left untouched.
+	Skipped Action = "skipped"
+	// Merged means the pushed image index was merged with an image index already existing in the
+	// destination registry.
+	Merged Action = "merged"
+)
+
+// Entry describes a single reference in the destination registry.
+type Entry struct {
+	// Source is the reference the pushed artifact originates from.
+	Source string `json:"source"`
+	// Destination is the tagged reference in the destination registry.
+	Destination string `json:"destination"`
+	// DigestReference is the destination reference pinned to the manifest digest.
+	DigestReference string `json:"digestReference"`
+	// Digest is the digest of the manifest in the destination registry.
+	Digest string `json:"digest"`
+	// MediaType is the media type of the manifest in the destination registry.
+	MediaType string `json:"mediaType"`
+	// Platforms contains the platforms of the image or image index, if any.
+	Platforms []string `json:"platforms,omitempty"`
+	// Action is the action taken for the reference.
+	Action Action `json:"action"`
+
+	destRepository name.Repository
+}
+
+// NewEntry returns an entry for pushing source to destination. The action defaults to Pushed.
+func NewEntry(source string, destination name.Reference) *Entry {
+	return &Entry{
+		Source:         source,
+		Destination:    destination.String(),
+		Action:         Pushed,
+		destRepository: destination.Context(),
+	}
+}
+
+// SetImage sets the digest, media type and platform of e from img.
+func (e *Entry) SetImage(img v1.Image) error {
+	digest, err := img.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to get image digest: %w", err)
+	}
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return fmt.Errorf("failed to get image media type: %w", err)
+	}
+	e.setDigest(digest, string(mediaType))
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return fmt.Errorf("failed to read image manifest: %w", err)
+	}
+	// Only container images have a platform. OCI artifacts such as Helm charts have their own config media
+	// types.
+	e.Platforms = nil
+	if manifest.Config.MediaType.IsConfig() {
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return fmt.Errorf("failed to read image config: %w", err)
+		}
+		if p := cfg.Platform(); p != nil && p.OS != "" {
+			e.Platforms = []string{p.String()}
+		}
+	}
+	return nil
+}
+
+// SetIndex sets the digest, media type and platforms of e from idx.
+func (e *Entry) SetIndex(idx v1.ImageIndex) error {
+	digest, err := idx.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to get image index digest: %w", err)
+	}
+	mediaType, err := idx.MediaType()
+	if err != nil {
+		return fmt.Errorf("failed to get image index media type: %w", err)
+	}
+	e.setDigest(digest, string(mediaType))
+
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("failed to read image index manifest: %w", err)
+	}
+	e.Platforms = nil
+	for _, m := range manifest.Manifests {
+		// Skip attestation manifests which are recorded with an unknown platform.
+		if m.Platform == nil || m.Platform.OS == "" || m.Platform.OS == "unknown" {
+			continue
+		}
+		if p := m.Platform.String(); !slices.Contains(e.Platforms, p) {
+			e.Platforms = append(e.Platforms, p)
+		}
+	}
+	return nil
+}
+
+// SetDescriptor sets the digest, media type and platforms of e from the descriptor of an existing reference.
+func (e *Entry) SetDescriptor(desc *remote.Descriptor) error {
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return err
+		}
+		return e.SetIndex(idx)
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return err
+	}
+	return e.SetImage(img)
+}
+
+func (e *Entry) setDigest(digest v1.Hash, mediaType string) {
+	e.Digest = digest.String()
+	e.MediaType = mediaType
+	e.DigestReference = e.destRepository.Digest(digest.String()).String()
+}
+
+// Report collects report entries. It is safe for concurrent use. A nil *Report records nothing.
+type Report struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns an empty report.
+func New() *Report {
+	return &Report{}
+}
+
+// Add adds an entry to the report.
+func (r *Report) Add(e Entry) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+}
+
+// Entries returns the entries of the report sorted by destination and source.
+func (r *Report) Entries() []Entry {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := slices.Clone(r.entries)
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return cmp.Or(cmp.Compare(a.Destination, b.Destination), cmp.Compare(a.Source, b.Source))
+	})
+	return entries
+}
+
+// fileContents is the structure of the written report file.
+type fileContents struct {
+	References []Entry `json:"references"`
+}
+
+// WriteFile writes the report as JSON to the specified file.
+func (r *Report) WriteFile(fileName string) error {
+	entries := r.Entries()
+	if entries == nil {
+		entries = []Entry{}
+	}
+	b, err := json.MarshalIndent(fileContents{References: entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal push report: %w", err)
+	}
+	if err := os.WriteFile(fileName, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write push report: %w", err)
+	}
+	return nil
+}
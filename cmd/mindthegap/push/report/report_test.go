@@ -0,0 +1,94 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPlatformImage(t *testing.T, os, arch string) v1.Image {
+	t.Helper()
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	cfg = cfg.DeepCopy()
+	cfg.OS, cfg.Architecture = os, arch
+	img, err = mutate.ConfigFile(img, cfg)
+	require.NoError(t, err)
+	return img
+}
+
+func TestEntrySetImage(t *testing.T) {
+	t.Parallel()
+
+	img := newPlatformImage(t, "linux", "arm64")
+	wantDigest, err := img.Digest()
+	require.NoError(t, err)
+
+	e := NewEntry("docker.io/library/nginx:1.0", name.MustParseReference("registry.example.com:5000/nginx:1.0"))
+	require.NoError(t, e.SetImage(img))
+
+	assert.Equal(t, Pushed, e.Action)
+	assert.Equal(t, wantDigest.String(), e.Digest)
+	assert.Equal(t, "registry.example.com:5000/nginx@"+wantDigest.String(), e.DigestReference)
+	assert.Equal(t, []string{"linux/arm64"}, e.Platforms)
+}
+
+func TestEntrySetIndex(t *testing.T) {
+	t.Parallel()
+
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        newPlatformImage(t, "linux", "amd64"),
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+		mutate.IndexAddendum{
+			Add:        newPlatformImage(t, "linux", "arm64"),
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		},
+		mutate.IndexAddendum{
+			Add:        newPlatformImage(t, "", ""),
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "unknown", Architecture: "unknown"}},
+		},
+	)
+	wantDigest, err := idx.Digest()
+	require.NoError(t, err)
+
+	e := NewEntry("docker.io/library/nginx:1.0", name.MustParseReference("registry.example.com/nginx:1.0"))
+	require.NoError(t, e.SetIndex(idx))
+
+	assert.Equal(t, wantDigest.String(), e.Digest)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64/v8"}, e.Platforms)
+}
+
+func TestReportWriteFile(t *testing.T) {
+	t.Parallel()
+
+	r := New()
+	r.Add(Entry{Source: "b", Destination: "registry.example.com/b:1", Action: Skipped})
+	r.Add(Entry{Source: "a", Destination: "registry.example.com/a:1", Action: Pushed})
+
+	f := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, r.WriteFile(f))
+
+	b, err := os.ReadFile(f)
+	require.NoError(t, err)
+	var got fileContents
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Len(t, got.References, 2)
+	assert.Equal(t, "a", got.References[0].Source)
+	assert.Equal(t, Skipped, got.References[1].Action)
+}
@@ -15,7 +15,10 @@ import (
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/create"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/export"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/filter"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/importcmd"
+	"github.com/mesosphere/mindthegap/cmd/mindthegap/merge"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/push"
 	"github.com/mesosphere/mindthegap/cmd/mindthegap/serve"
 )
@@ -51,6 +54,9 @@ func NewCommand(in io.Reader, out, errOut io.Writer) (*cobra.Command, output.Out
 	rootCmd.AddCommand(push.NewCommand(rootOpts.Output))
 	rootCmd.AddCommand(serve.NewCommand(rootOpts.Output))
 	rootCmd.AddCommand(importcmd.NewCommand(rootOpts.Output))
+	rootCmd.AddCommand(export.NewCommand(rootOpts.Output))
+	rootCmd.AddCommand(merge.NewCommand(rootOpts.Output))
+	rootCmd.AddCommand(filter.NewCommand(rootOpts.Output))
 
 	return rootCmd, rootOpts.Output
 }
@@ -25,6 +25,7 @@ func NewCommand(
 ) (cmd *cobra.Command, stopCh chan struct{}) {
 	var (
 		bundleFiles        []string
+		decryptionKeyFiles []string
 		listenAddress      string
 		listenPort         uint16
 		tlsCertificate     string
@@ -59,9 +60,13 @@ func NewCommand(
 			if err != nil {
 				return err
 			}
+			bundleFiles, err = utils.ExpandBundleParts(out, bundleFiles)
+			if err != nil {
+				return err
+			}
 
 			out.StartOperation("Creating Docker registry")
-			storage, err := registry.ArchiveStorage(repositoriesPrefix, bundleFiles...)
+			storage, err := registry.ArchiveStorage(repositoriesPrefix, decryptionKeyFiles, bundleFiles...)
 			if err != nil {
 				out.EndOperationWithStatus(output.Failure())
 				return fmt.Errorf("failed to create storage for Docker registry from supplied bundles: %w", err)
@@ -97,8 +102,11 @@ func NewCommand(
 	}
 
 	cmd.Flags().StringSliceVar(&bundleFiles, bundleCmdName, nil,
-		"Bundle to serve. Can also be a glob pattern.")
+		"Bundle to serve, or the .parts.yaml index of a split bundle. Can also be a glob pattern.")
 	_ = cmd.MarkFlagRequired(bundleCmdName)
+	cmd.Flags().StringSliceVar(&decryptionKeyFiles, "decrypt-key", nil,
+		"File containing age private keys (AGE-SECRET-KEY-1...) or a passphrase to decrypt encrypted bundles with. "+
+			"Can be specified multiple times")
 	cmd.Flags().StringVar(&listenAddress, "listen-address", "127.0.0.1", "Address to listen on")
 	cmd.Flags().
 		Uint16Var(&listenPort, "listen-port", 0, "Port to listen on (0 means use any free port)")
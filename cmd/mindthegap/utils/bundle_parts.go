@@ -0,0 +1,30 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"slices"
+
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+)
+
+// ExpandBundleParts replaces the index of every bundle split into parts with the parts it lists, after verifying
+// their checksums, so that the parts can be read as a single logical bundle.
+func ExpandBundleParts(out output.Output, bundleFiles []string) ([]string, error) {
+	if !slices.ContainsFunc(bundleFiles, archive.IsPartsIndex) {
+		return bundleFiles, nil
+	}
+
+	out.StartOperation("Verifying bundle parts")
+	expanded, err := archive.ExpandPartsIndexes(bundleFiles)
+	if err != nil {
+		out.EndOperationWithStatus(output.Failure())
+		return nil, err
+	}
+	out.EndOperationWithStatus(output.Success())
+
+	return expanded, nil
+}
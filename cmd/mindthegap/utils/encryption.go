@@ -0,0 +1,27 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"github.com/mesosphere/dkp-cli-runtime/core/output"
+
+	"github.com/mesosphere/mindthegap/archive"
+)
+
+// WarnUnencryptedOutput warns about every encrypted bundle, as its contents are written to a bundle that is not
+// encrypted.
+func WarnUnencryptedOutput(out output.Output, bundleFiles []string) error {
+	for _, bundleFile := range bundleFiles {
+		encrypted, err := archive.IsEncrypted(bundleFile)
+		if err != nil {
+			return err
+		}
+		if encrypted {
+			out.Warnf(
+				"Bundle %s is encrypted, but the output bundle is not: specify --encrypt to encrypt it", bundleFile,
+			)
+		}
+	}
+	return nil
+}
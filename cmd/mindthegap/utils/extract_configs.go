@@ -15,13 +15,21 @@ import (
 	"github.com/mesosphere/mindthegap/config"
 )
 
+// ExtractConfigs extracts and merges the images and Helm charts configs of the bundles. Encrypted bundles are
+// decrypted with the keys read from decryptionKeyFiles.
 func ExtractConfigs(
 	dest string,
 	out output.Output,
+	decryptionKeyFiles []string,
 	imageBundleFiles ...string,
 ) (*config.ImagesConfig, *config.HelmChartsConfig, error) {
 	sort.Strings(imageBundleFiles)
 
+	identities, err := archive.ParseDecryptionKeys(decryptionKeyFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var (
 		// This will hold the merged config from all the image bundles which will be used to import
 		// all the images from all the bundles.
@@ -42,7 +50,7 @@ func ExtractConfigs(
 		extractedBundles[imageBundleFile] = struct{}{}
 
 		out.StartOperation(fmt.Sprintf("Extracting bundle configs from %q", imageBundleFile))
-		err := archive.ExtractFileToDirectory(imageBundleFile, dest, "images.yaml")
+		err := archive.ExtractFileToDirectory(imageBundleFile, dest, "images.yaml", identities...)
 		if err != nil {
 			out.EndOperationWithStatus(output.Failure())
 			return nil, nil, fmt.Errorf(
@@ -50,7 +58,7 @@ func ExtractConfigs(
 				err,
 			)
 		}
-		err = archive.ExtractFileToDirectory(imageBundleFile, dest, "charts.yaml")
+		err = archive.ExtractFileToDirectory(imageBundleFile, dest, "charts.yaml", identities...)
 		if err != nil && !os.IsNotExist(err) {
 			out.EndOperationWithStatus(output.Failure())
 			return nil, nil, fmt.Errorf(
@@ -0,0 +1,26 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// WaitForRegistry waits for a temporary registry started in the background to accept connections, for clients
+// that do not retry failed requests.
+func WaitForRegistry(addr string) error {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			return conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("temporary Docker registry at %s did not start: %w", addr, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/containers/image/v5/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mesosphere/mindthegap/images/authnhelpers"
+)
+
+// CredentialsSource configures where credentials are read from, rather than specifying them in plaintext in the
+// config file. Exactly one source must be specified.
+type CredentialsSource struct {
+	// Env contains the names of the environment variables to read the credentials from.
+	Env *EnvCredentialsSource `yaml:"env,omitempty"`
+	// File is a YAML or JSON file containing the credentials as username and password, or identitytoken.
+	File string `yaml:"file,omitempty"`
+	// AuthFile is a docker config.json, containers auth.json or Kubernetes dockerconfigjson secret to read the
+	// credentials for the registry from, including via credential helpers.
+	AuthFile string `yaml:"authFile,omitempty"`
+}
+
+// EnvCredentialsSource contains the names of the environment variables to read credentials from.
+type EnvCredentialsSource struct {
+	Username      string `yaml:"username,omitempty"`
+	Password      string `yaml:"password,omitempty"`
+	IdentityToken string `yaml:"identityToken,omitempty"`
+}
+
+// envVarRegexp matches ${VAR} references to environment variables. Only the braced form is expanded so that
+// plaintext credentials containing $ are left untouched.
+var envVarRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces all ${VAR} references in s with the value of the environment variable. It is an error to
+// reference an environment variable that is not set.
+func expandEnv(s string) (string, error) {
+	var errs []error
+	expanded := envVarRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		envVar := envVarRegexp.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(envVar)
+		if !ok {
+			errs = append(errs, fmt.Errorf("environment variable %s is not set", envVar))
+		}
+		return v
+	})
+	return expanded, errors.Join(errs...)
+}
+
+// expandCredentialsEnv expands ${VAR} references in the credentials.
+func expandCredentialsEnv(creds *types.DockerAuthConfig) error {
+	for _, f := range []*string{&creds.Username, &creds.Password, &creds.IdentityToken} {
+		expanded, err := expandEnv(*f)
+		if err != nil {
+			return err
+		}
+		*f = expanded
+	}
+	return nil
+}
+
+// resolve reads the credentials for the registry from the configured source. Relative file paths are resolved
+// relative to baseDir.
+func (s CredentialsSource) resolve(registry, baseDir string) (*types.DockerAuthConfig, error) {
+	numSources := 0
+	for _, set := range []bool{s.Env != nil, s.File != "", s.AuthFile != ""} {
+		if set {
+			numSources++
+		}
+	}
+	if numSources != 1 {
+		return nil, fmt.Errorf("exactly one of env, file or authFile must be specified in credentialsFrom")
+	}
+
+	resolvePath := func(p string) (string, error) {
+		p, err := expandEnv(p)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(p) {
+			return p, nil
+		}
+		return filepath.Join(baseDir, p), nil
+	}
+
+	switch {
+	case s.Env != nil:
+		return s.Env.resolve()
+	case s.File != "":
+		f, err := resolvePath(s.File)
+		if err != nil {
+			return nil, err
+		}
+		return readCredentialsFile(f)
+	default:
+		f, err := resolvePath(s.AuthFile)
+		if err != nil {
+			return nil, err
+		}
+		return readCredentialsFromAuthFile(f, registry)
+	}
+}
+
+func (e EnvCredentialsSource) resolve() (*types.DockerAuthConfig, error) {
+	if e.Username == "" && e.Password == "" && e.IdentityToken == "" {
+		return nil, fmt.Errorf("at least one environment variable must be specified in credentialsFrom.env")
+	}
+
+	lookup := func(envVar string) (string, error) {
+		if envVar == "" {
+			return "", nil
+		}
+		v, ok := os.LookupEnv(envVar)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", envVar)
+		}
+		return v, nil
+	}
+
+	var (
+		creds types.DockerAuthConfig
+		err   error
+	)
+	if creds.Username, err = lookup(e.Username); err != nil {
+		return nil, err
+	}
+	if creds.Password, err = lookup(e.Password); err != nil {
+		return nil, err
+	}
+	if creds.IdentityToken, err = lookup(e.IdentityToken); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+func readCredentialsFile(credentialsFile string) (*types.DockerAuthConfig, error) {
+	b, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	var creds types.DockerAuthConfig
+	if err := yaml.Unmarshal(b, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", credentialsFile, err)
+	}
+	return &creds, nil
+}
+
+func readCredentialsFromAuthFile(authFile, registry string) (*types.DockerAuthConfig, error) {
+	keychain, err := authnhelpers.NewAuthFileKeychain(authFile)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := name.NewRegistry(registry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry %q: %w", registry, err)
+	}
+	authenticator, err := keychain.Resolve(reg)
+	if err != nil {
+		return nil, err
+	}
+	if authenticator == authn.Anonymous {
+		return nil, fmt.Errorf("registry auth file %s does not contain credentials for %s", authFile, registry)
+	}
+	cfg, err := authenticator.Authorization()
+	if err != nil {
+		return nil, err
+	}
+	return &types.DockerAuthConfig{
+		Username:      cfg.Username,
+		Password:      cfg.Password,
+		IdentityToken: cfg.IdentityToken,
+	}, nil
+}
+
+// resolveCredentials returns the credentials to use, expanding ${VAR} references in plaintext credentials and
+// reading credentials from credentialsFrom if specified.
+func resolveCredentials(
+	registry string,
+	creds *types.DockerAuthConfig,
+	credentialsFrom *CredentialsSource,
+	baseDir string,
+) (*types.DockerAuthConfig, error) {
+	if credentialsFrom == nil {
+		if creds == nil {
+			return nil, nil
+		}
+		if err := expandCredentialsEnv(creds); err != nil {
+			return nil, err
+		}
+		return creds, nil
+	}
+
+	if creds != nil && (creds.Username != "" || creds.Password != "" || creds.IdentityToken != "") {
+		return nil, fmt.Errorf("credentials and credentialsFrom cannot both be specified")
+	}
+	return credentialsFrom.resolve(registry, baseDir)
+}
+
+// hostFromURL returns the host of the URL, or the URL itself if it cannot be parsed.
+func hostFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
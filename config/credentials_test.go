@@ -0,0 +1,168 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		f := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(f), 0o755))
+		require.NoError(t, os.WriteFile(f, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestParseImagesConfigFileCredentials(t *testing.T) {
+	t.Setenv("MINDTHEGAP_TEST_USERNAME", "env-user")
+	t.Setenv("MINDTHEGAP_TEST_PASSWORD", "env-pass")
+
+	dir := writeConfigFiles(t, map[string]string{
+		"images.yaml": `
+expanded.example.com:
+  credentials:
+    username: ${MINDTHEGAP_TEST_USERNAME}
+    password: p$ss-${MINDTHEGAP_TEST_PASSWORD}
+  images:
+    app: [v1]
+env.example.com:
+  credentialsFrom:
+    env:
+      username: MINDTHEGAP_TEST_USERNAME
+      password: MINDTHEGAP_TEST_PASSWORD
+file.example.com:
+  credentialsFrom:
+    file: secrets/creds.yaml
+authfile.example.com:
+  credentialsFrom:
+    authFile: secrets/auth.json
+`,
+		"secrets/creds.yaml": "username: file-user\npassword: file-pass\n",
+		"secrets/auth.json": `{"auths":{"authfile.example.com":{"auth":"` +
+			base64.StdEncoding.EncodeToString([]byte("auth-user:auth-pass")) + `"}}}`,
+	})
+
+	cfg, err := ParseImagesConfigFile(filepath.Join(dir, "images.yaml"))
+	require.NoError(t, err)
+
+	assert.Equal(
+		t, &types.DockerAuthConfig{Username: "env-user", Password: "p$ss-env-pass"},
+		cfg["expanded.example.com"].Credentials,
+	)
+	assert.Equal(
+		t, &types.DockerAuthConfig{Username: "env-user", Password: "env-pass"},
+		cfg["env.example.com"].Credentials,
+	)
+	assert.Equal(
+		t, &types.DockerAuthConfig{Username: "file-user", Password: "file-pass"},
+		cfg["file.example.com"].Credentials,
+	)
+	assert.Equal(
+		t, &types.DockerAuthConfig{Username: "auth-user", Password: "auth-pass"},
+		cfg["authfile.example.com"].Credentials,
+	)
+	for _, regConfig := range cfg {
+		assert.Nil(t, regConfig.CredentialsFrom)
+	}
+
+	sanitizedFile := filepath.Join(dir, "sanitized.yaml")
+	require.NoError(t, WriteSanitizedImagesConfigs(sanitizedFile, cfg))
+	sanitized, err := os.ReadFile(sanitizedFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(sanitized), "credentials")
+	assert.NotContains(t, string(sanitized), "user")
+}
+
+func TestParseImagesConfigFileCredentialsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{{
+		name:    "unset environment variable",
+		content: "example.com:\n  credentials:\n    password: ${MINDTHEGAP_TEST_UNSET}\n",
+		wantErr: "environment variable MINDTHEGAP_TEST_UNSET is not set",
+	}, {
+		name:    "unset environment variable in credentialsFrom",
+		content: "example.com:\n  credentialsFrom:\n    env:\n      password: MINDTHEGAP_TEST_UNSET\n",
+		wantErr: "environment variable MINDTHEGAP_TEST_UNSET is not set",
+	}, {
+		name: "credentials and credentialsFrom",
+		content: "example.com:\n  credentials:\n    password: pass\n" +
+			"  credentialsFrom:\n    file: creds.yaml\n",
+		wantErr: "credentials and credentialsFrom cannot both be specified",
+	}, {
+		name:    "multiple sources",
+		content: "example.com:\n  credentialsFrom:\n    file: creds.yaml\n    authFile: auth.json\n",
+		wantErr: "exactly one of env, file or authFile must be specified",
+	}, {
+		name:    "missing file",
+		content: "example.com:\n  credentialsFrom:\n    file: missing.yaml\n",
+		wantErr: "failed to read credentials file",
+	}, {
+		name:    "auth file without credentials for registry",
+		content: "example.com:\n  credentialsFrom:\n    authFile: auth.json\n",
+		wantErr: "does not contain credentials for example.com",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, map[string]string{
+				"images.yaml": tt.content,
+				"auth.json":   `{"auths":{}}`,
+			})
+			_, err := ParseImagesConfigFile(filepath.Join(dir, "images.yaml"))
+			require.ErrorContains(t, err, "invalid credentials for registry example.com")
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestParseHelmChartsConfigFileCredentials(t *testing.T) {
+	t.Setenv("MINDTHEGAP_TEST_PASSWORD", "env-pass")
+
+	dir := writeConfigFiles(t, map[string]string{
+		"charts.yaml": `
+repositories:
+  expanded:
+    repoURL: https://charts.example.com
+    username: user
+    password: ${MINDTHEGAP_TEST_PASSWORD}
+    charts:
+      app: [1.0.0]
+  from-file:
+    repoURL: oci://registry.example.com/charts
+    credentialsFrom:
+      file: creds.json
+    charts:
+      app: [1.0.0]
+`,
+		"creds.json": `{"username": "file-user", "password": "file-pass"}`,
+	})
+
+	cfg, err := ParseHelmChartsConfigFile(filepath.Join(dir, "charts.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "user", cfg.Repositories["expanded"].Username)
+	assert.Equal(t, "env-pass", cfg.Repositories["expanded"].Password)
+	assert.Equal(t, "file-user", cfg.Repositories["from-file"].Username)
+	assert.Equal(t, "file-pass", cfg.Repositories["from-file"].Password)
+	assert.Nil(t, cfg.Repositories["from-file"].CredentialsFrom)
+
+	sanitizedFile := filepath.Join(dir, "sanitized.yaml")
+	require.NoError(t, WriteSanitizedHelmChartsConfig(sanitizedFile, cfg))
+	sanitized, err := os.ReadFile(sanitizedFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(sanitized), "credentialsFrom")
+	assert.NotContains(t, string(sanitized), "pass")
+}
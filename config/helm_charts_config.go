@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/containers/image/v5/types"
 	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
@@ -22,8 +24,12 @@ type HelmRepositorySyncConfig struct {
 	RepoURL string `yaml:"repoURL,omitempty"`
 	// Username holds the username for the repository.
 	Username string `yaml:"username,omitempty"`
-	// Password holds the password for the repository.
+	// Password holds the password for the repository. ${VAR} references to environment variables are expanded
+	// in both username and password.
 	Password string `yaml:"password,omitempty"`
+	// CredentialsFrom reads the username and password from environment variables or files instead. Resolved
+	// into Username and Password when parsing the config file.
+	CredentialsFrom *CredentialsSource `yaml:"credentialsFrom,omitempty"`
 	// TLS verification mode (enabled by default)
 	TLSVerify *bool `yaml:"tlsVerify,omitempty"`
 	// Charts map charts name to slices with the chart versions.
@@ -41,11 +47,17 @@ func (c HelmRepositorySyncConfig) Clone() HelmRepositorySyncConfig {
 		tlsVerify = new(*c.TLSVerify)
 	}
 
+	var credsFrom *CredentialsSource = nil
+	if c.CredentialsFrom != nil {
+		credsFrom = new(*c.CredentialsFrom)
+	}
+
 	return HelmRepositorySyncConfig{
-		Charts:    charts,
-		TLSVerify: tlsVerify,
-		Username:  c.Username,
-		Password:  c.Password,
+		Charts:          charts,
+		TLSVerify:       tlsVerify,
+		Username:        c.Username,
+		Password:        c.Password,
+		CredentialsFrom: credsFrom,
 	}
 }
 
@@ -58,6 +70,14 @@ func (c HelmRepositorySyncConfig) SortedChartNames() []string {
 	return chartNames
 }
 
+func (c HelmRepositorySyncConfig) TotalCharts() int {
+	n := 0
+	for _, chartVersions := range c.Charts {
+		n += len(chartVersions)
+	}
+	return n
+}
+
 // HelmChartsConfig contains all helm charts information read from the source YAML file.
 type HelmChartsConfig struct {
 	Repositories map[string]HelmRepositorySyncConfig `yaml:"repositories,omitempty"`
@@ -73,6 +93,14 @@ func (c HelmChartsConfig) SortedRepositoryNames() []string {
 	return repoNames
 }
 
+func (c HelmChartsConfig) TotalCharts() int {
+	n := 0
+	for _, repo := range c.Repositories {
+		n += repo.TotalCharts()
+	}
+	return n
+}
+
 func (c *HelmChartsConfig) Merge(cfg HelmChartsConfig) *HelmChartsConfig {
 	if c == nil {
 		return &cfg
@@ -102,6 +130,7 @@ func (c *HelmChartsConfig) Merge(cfg HelmChartsConfig) *HelmChartsConfig {
 
 			f.Username = cloned.Username
 			f.Password = cloned.Password
+			f.CredentialsFrom = cloned.CredentialsFrom
 			f.TLSVerify = cloned.TLSVerify
 
 			for chrt, versions := range cloned.Charts {
@@ -135,6 +164,36 @@ func (c *HelmChartsConfig) Merge(cfg HelmChartsConfig) *HelmChartsConfig {
 	}
 }
 
+// resolveCredentials expands environment variables in and reads credentials from credentialsFrom for all
+// repositories. Relative file paths are resolved relative to baseDir.
+func (c HelmChartsConfig) resolveCredentials(baseDir string) error {
+	for repoName, repoConfig := range c.Repositories {
+		var creds *types.DockerAuthConfig
+		if repoConfig.Username != "" || repoConfig.Password != "" {
+			creds = &types.DockerAuthConfig{Username: repoConfig.Username, Password: repoConfig.Password}
+		}
+		creds, err := resolveCredentials(
+			hostFromURL(repoConfig.RepoURL), creds, repoConfig.CredentialsFrom, baseDir,
+		)
+		if err != nil {
+			return fmt.Errorf("invalid credentials for repository %s: %w", repoName, err)
+		}
+		if creds != nil {
+			if creds.IdentityToken != "" {
+				return fmt.Errorf(
+					"invalid credentials for repository %s: identity tokens are not supported for Helm repositories",
+					repoName,
+				)
+			}
+			repoConfig.Username = creds.Username
+			repoConfig.Password = creds.Password
+		}
+		repoConfig.CredentialsFrom = nil
+		c.Repositories[repoName] = repoConfig
+	}
+	return nil
+}
+
 func ParseHelmChartsConfigFile(configFile string) (HelmChartsConfig, error) {
 	f, yamlParseErr := os.Open(configFile)
 	if yamlParseErr != nil {
@@ -152,6 +211,9 @@ func ParseHelmChartsConfigFile(configFile string) (HelmChartsConfig, error) {
 	dec.KnownFields(true)
 	yamlParseErr = dec.Decode(&config)
 	if yamlParseErr == nil {
+		if err := config.resolveCredentials(filepath.Dir(configFile)); err != nil {
+			return HelmChartsConfig{}, fmt.Errorf("failed to parse helm charts config file: %w", err)
+		}
 		return config, nil
 	}
 
@@ -183,6 +245,7 @@ func WriteSanitizedHelmChartsConfig(fileName string, cfgs ...HelmChartsConfig) e
 	for regName, regConfig := range cfg.Repositories {
 		regConfig.Username = ""
 		regConfig.Password = ""
+		regConfig.CredentialsFrom = nil
 		regConfig.TLSVerify = nil
 		cfg.Repositories[regName] = regConfig
 	}
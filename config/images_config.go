@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"slices"
 	"sort"
 	"strings"
@@ -24,8 +25,15 @@ type RegistrySyncConfig struct {
 	Images map[string][]string
 	// TLS verification mode (enabled by default)
 	TLSVerify *bool `yaml:"tlsVerify,omitempty"`
-	// Username and password used to authenticate with the registry
+	// Username and password used to authenticate with the registry. ${VAR} references to environment
+	// variables are expanded.
 	Credentials *types.DockerAuthConfig `yaml:"credentials,omitempty"`
+	// CredentialsFrom reads the credentials from environment variables or files instead. Resolved into
+	// Credentials when parsing the config file.
+	CredentialsFrom *CredentialsSource `yaml:"credentialsFrom,omitempty"`
+	// Mirrors are tried in order when pulling images from the registry before falling back to the registry
+	// itself. Prefix a mirror with http:// to access it over plain HTTP.
+	Mirrors []string `yaml:"mirrors,omitempty"`
 }
 
 func (rsc RegistrySyncConfig) SortedImageNames() []string {
@@ -65,10 +73,17 @@ func (rsc RegistrySyncConfig) Clone() RegistrySyncConfig {
 		}
 	}
 
+	var credsFrom *CredentialsSource = nil
+	if rsc.CredentialsFrom != nil {
+		credsFrom = new(*rsc.CredentialsFrom)
+	}
+
 	return RegistrySyncConfig{
-		Images:      images,
-		TLSVerify:   tlsVerify,
-		Credentials: creds,
+		Images:          images,
+		TLSVerify:       tlsVerify,
+		Credentials:     creds,
+		CredentialsFrom: credsFrom,
+		Mirrors:         slices.Clone(rsc.Mirrors),
 	}
 }
 
@@ -101,7 +116,11 @@ func (ic *ImagesConfig) Merge(cfg ImagesConfig) *ImagesConfig {
 		}
 
 		f.Credentials = cloned.Credentials
+		f.CredentialsFrom = cloned.CredentialsFrom
 		f.TLSVerify = cloned.TLSVerify
+		if len(cloned.Mirrors) > 0 {
+			f.Mirrors = cloned.Mirrors
+		}
 
 		for img, tags := range cloned.Images {
 			fImg, ok := f.Images[img]
@@ -146,6 +165,21 @@ func (ic ImagesConfig) TotalImages() int {
 	return n
 }
 
+// resolveCredentials expands environment variables in and reads credentials from credentialsFrom for all
+// registries. Relative file paths are resolved relative to baseDir.
+func (ic ImagesConfig) resolveCredentials(baseDir string) error {
+	for regName, regConfig := range ic {
+		creds, err := resolveCredentials(regName, regConfig.Credentials, regConfig.CredentialsFrom, baseDir)
+		if err != nil {
+			return fmt.Errorf("invalid credentials for registry %s: %w", regName, err)
+		}
+		regConfig.Credentials = creds
+		regConfig.CredentialsFrom = nil
+		ic[regName] = regConfig
+	}
+	return nil
+}
+
 func ParseImagesConfigFile(configFile string) (ImagesConfig, error) {
 	f, err := os.Open(configFile)
 	if err != nil {
@@ -160,6 +194,9 @@ func ParseImagesConfigFile(configFile string) (ImagesConfig, error) {
 	dec.KnownFields(true)
 	yamlParseErr := dec.Decode(&config)
 	if yamlParseErr == nil {
+		if err := config.resolveCredentials(filepath.Dir(configFile)); err != nil {
+			return ImagesConfig{}, fmt.Errorf("failed to parse images config file: %w", err)
+		}
 		return config, nil
 	}
 
@@ -211,7 +248,9 @@ func WriteSanitizedImagesConfigs(fileName string, cfgs ...ImagesConfig) error {
 	cfg := *merged
 	for regName, regConfig := range cfg {
 		regConfig.Credentials = nil
+		regConfig.CredentialsFrom = nil
 		regConfig.TLSVerify = nil
+		regConfig.Mirrors = nil
 		cfg[regName] = regConfig
 	}
 
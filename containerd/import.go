@@ -0,0 +1,156 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containerd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/core/remotes"
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+)
+
+// DefaultAddress is the default address of the containerd socket.
+const DefaultAddress = "/run/containerd/containerd.sock"
+
+// Importer imports images from a registry into the content store of a containerd daemon via the containerd API.
+type Importer struct {
+	client    *client.Client
+	namespace string
+	resolver  remotes.Resolver
+}
+
+// NewImporter connects to the containerd daemon listening on address. Images are imported into namespace.
+func NewImporter(address, namespace string) (*Importer, error) {
+	c, err := client.New(address, client.WithDefaultNamespace(namespace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to containerd at %s: %w", address, err)
+	}
+
+	return &Importer{
+		client:    c,
+		namespace: namespace,
+		// Images are always imported from the local temporary registry, which is only served over plain HTTP.
+		resolver: docker.NewResolver(docker.ResolverOptions{
+			Hosts: docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchAllHosts)),
+		}),
+	}, nil
+}
+
+// Close closes the connection to the containerd daemon.
+func (i *Importer) Close() error {
+	return i.client.Close()
+}
+
+type importOptions struct {
+	platforms   platforms.MatchComparer
+	unpack      bool
+	snapshotter string
+}
+
+type ImportOption func(*importOptions)
+
+// WithPlatforms restricts the imported content to images matching the platform matcher, e.g. platforms.All to
+// import all platforms. Defaults to the platform of the running binary. The image index is always preserved.
+func WithPlatforms(m platforms.MatchComparer) ImportOption {
+	return func(o *importOptions) {
+		o.platforms = m
+	}
+}
+
+// WithUnpack unpacks imported images for each imported platform into the named snapshotter. An empty snapshotter
+// name uses the containerd default snapshotter.
+func WithUnpack(snapshotter string) ImportOption {
+	return func(o *importOptions) {
+		o.unpack = true
+		o.snapshotter = snapshotter
+	}
+}
+
+// ImportImage streams the image srcRef from its registry into the containerd content store and creates (or
+// updates) the image record imageName pointing at it.
+func (i *Importer) ImportImage(ctx context.Context, srcRef, imageName string, opts ...ImportOption) error {
+	o := importOptions{platforms: platforms.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx = namespaces.WithNamespace(ctx, i.namespace)
+	// Hold a lease while importing so that fetched content is not garbage collected before the image record
+	// referencing it has been created.
+	ctx, done, err := i.client.WithLease(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create containerd lease: %w", err)
+	}
+	defer func() { _ = done(context.WithoutCancel(ctx)) }()
+
+	resolvedName, desc, err := i.resolver.Resolve(ctx, srcRef)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", srcRef, err)
+	}
+	fetcher, err := i.resolver.Fetcher(ctx, resolvedName)
+	if err != nil {
+		return fmt.Errorf("failed to create fetcher for %s: %w", srcRef, err)
+	}
+
+	store := i.client.ContentStore()
+	// Labels on parent content reference children content so that the containerd garbage collector keeps them.
+	childrenHandler := images.SetChildrenLabels(store, images.ChildrenHandler(store))
+	childrenHandler = images.FilterPlatforms(childrenHandler, o.platforms)
+	if err := images.Dispatch(
+		ctx,
+		images.Handlers(remotes.FetchHandler(store, fetcher), childrenHandler),
+		nil,
+		desc,
+	); err != nil {
+		return fmt.Errorf("failed to fetch %s into containerd content store: %w", srcRef, err)
+	}
+
+	img, err := i.createOrUpdateImage(ctx, images.Image{Name: imageName, Target: desc})
+	if err != nil {
+		return err
+	}
+
+	if o.unpack {
+		imagePlatforms, err := images.Platforms(ctx, store, img.Target)
+		if err != nil {
+			return fmt.Errorf("failed to read platforms of %s: %w", imageName, err)
+		}
+		// Unpack every imported platform, e.g. to prepare images for nodes with a different architecture.
+		for _, p := range imagePlatforms {
+			if !o.platforms.Match(p) {
+				continue
+			}
+			if err := client.NewImageWithPlatform(i.client, img, platforms.OnlyStrict(p)).
+				Unpack(ctx, o.snapshotter); err != nil {
+				return fmt.Errorf("failed to unpack %s for platform %s: %w", imageName, platforms.Format(p), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (i *Importer) createOrUpdateImage(ctx context.Context, img images.Image) (images.Image, error) {
+	is := i.client.ImageService()
+	created, err := is.Create(ctx, img)
+	if err == nil {
+		return created, nil
+	}
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		return images.Image{}, fmt.Errorf("failed to create image %s: %w", img.Name, err)
+	}
+
+	updated, err := is.Update(ctx, img, "target")
+	if err != nil {
+		return images.Image{}, fmt.Errorf("failed to update image %s: %w", img.Name, err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,309 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containerd
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/core/leases"
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/containerd/errdefs"
+	"github.com/containerd/platforms"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/mindthegap/docker/registry"
+	"github.com/mesosphere/mindthegap/internal/bundletest"
+)
+
+// memoryLabelStore keeps content labels in memory so that the local content store supports setting children labels.
+type memoryLabelStore struct {
+	mu     sync.Mutex
+	labels map[digest.Digest]map[string]string
+}
+
+func (s *memoryLabelStore) Get(d digest.Digest) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.labels[d], nil
+}
+
+func (s *memoryLabelStore) Set(d digest.Digest, labels map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labels[d] = labels
+	return nil
+}
+
+func (s *memoryLabelStore) Update(d digest.Digest, update map[string]string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	labels := s.labels[d]
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range update {
+		if v == "" {
+			delete(labels, k)
+			continue
+		}
+		labels[k] = v
+	}
+	s.labels[d] = labels
+	return labels, nil
+}
+
+// memoryImageStore is an in-memory containerd image store that records how image records were written.
+type memoryImageStore struct {
+	mu      sync.Mutex
+	images  map[string]images.Image
+	created []string
+	updated []string
+}
+
+func (s *memoryImageStore) Get(_ context.Context, name string) (images.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	img, ok := s.images[name]
+	if !ok {
+		return images.Image{}, errdefs.ErrNotFound
+	}
+	return img, nil
+}
+
+func (s *memoryImageStore) List(context.Context, ...string) ([]images.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	imgs := make([]images.Image, 0, len(s.images))
+	for _, img := range s.images {
+		imgs = append(imgs, img)
+	}
+	return imgs, nil
+}
+
+func (s *memoryImageStore) Create(_ context.Context, img images.Image) (images.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.images[img.Name]; ok {
+		return images.Image{}, errdefs.ErrAlreadyExists
+	}
+	s.images[img.Name] = img
+	s.created = append(s.created, img.Name)
+	return img, nil
+}
+
+func (s *memoryImageStore) Update(_ context.Context, img images.Image, fieldpaths ...string) (images.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.images[img.Name]
+	if !ok {
+		return images.Image{}, errdefs.ErrNotFound
+	}
+	for _, f := range fieldpaths {
+		if f == "target" {
+			existing.Target = img.Target
+		}
+	}
+	s.images[img.Name] = existing
+	s.updated = append(s.updated, img.Name)
+	return existing, nil
+}
+
+func (s *memoryImageStore) Delete(_ context.Context, name string, _ ...images.DeleteOpt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.images, name)
+	return nil
+}
+
+// noopLeases hands out leases without tracking any resources.
+type noopLeases struct{}
+
+func (noopLeases) Create(_ context.Context, opts ...leases.Opt) (leases.Lease, error) {
+	var l leases.Lease
+	for _, opt := range opts {
+		if err := opt(&l); err != nil {
+			return leases.Lease{}, err
+		}
+	}
+	return l, nil
+}
+
+func (noopLeases) Delete(context.Context, leases.Lease, ...leases.DeleteOpt) error { return nil }
+
+func (noopLeases) List(context.Context, ...string) ([]leases.Lease, error) { return nil, nil }
+
+func (noopLeases) AddResource(context.Context, leases.Lease, leases.Resource) error { return nil }
+
+func (noopLeases) DeleteResource(context.Context, leases.Lease, leases.Resource) error { return nil }
+
+func (noopLeases) ListResources(context.Context, leases.Lease) ([]leases.Resource, error) {
+	return nil, nil
+}
+
+func newTestImporter(t *testing.T) (*Importer, content.Store, *memoryImageStore) {
+	t.Helper()
+
+	store, err := local.NewLabeledStore(t.TempDir(), &memoryLabelStore{labels: map[digest.Digest]map[string]string{}})
+	require.NoError(t, err)
+	imageStore := &memoryImageStore{images: map[string]images.Image{}}
+	c, err := client.New("", client.WithServices(
+		client.WithContentStore(store),
+		client.WithImageStore(imageStore),
+		client.WithLeasesService(noopLeases{}),
+	))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+
+	return &Importer{
+		client:    c,
+		namespace: "test",
+		resolver: docker.NewResolver(docker.ResolverOptions{
+			Hosts: docker.ConfigureDefaultRegistries(docker.WithPlainHTTP(docker.MatchAllHosts)),
+		}),
+	}, store, imageStore
+}
+
+// pushTestIndex pushes an image index with a random image for each platform and returns the images by platform.
+func pushTestIndex(t *testing.T, ref string, imagePlatforms ...v1.Platform) (v1.Hash, []v1.Image) {
+	t.Helper()
+
+	var (
+		idx  v1.ImageIndex = empty.Index
+		imgs []v1.Image
+	)
+	for _, p := range imagePlatforms {
+		img, err := random.Image(64, 1)
+		require.NoError(t, err)
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: &p},
+		})
+		imgs = append(imgs, img)
+	}
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(tag, idx))
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+	return idxDigest, imgs
+}
+
+func contentExists(t *testing.T, store content.Store, h v1.Hash) bool {
+	t.Helper()
+	_, err := store.Info(context.Background(), digest.Digest(h.String()))
+	if errdefs.IsNotFound(err) {
+		return false
+	}
+	require.NoError(t, err)
+	return true
+}
+
+// imageBlobs returns the digests of the manifest, config and layers of the image.
+func imageBlobs(t *testing.T, img v1.Image) []v1.Hash {
+	t.Helper()
+	manifestDigest, err := img.Digest()
+	require.NoError(t, err)
+	configDigest, err := img.ConfigName()
+	require.NoError(t, err)
+	blobs := []v1.Hash{manifestDigest, configDigest}
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	for _, l := range layers {
+		layerDigest, err := l.Digest()
+		require.NoError(t, err)
+		blobs = append(blobs, layerDigest)
+	}
+	return blobs
+}
+
+func TestImportImagePlatforms(t *testing.T) {
+	t.Parallel()
+
+	reg := bundletest.StartRegistry(t, registry.FilesystemStorage(t.TempDir()))
+	srcRef := reg.Address() + "/team/app:v1"
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := v1.Platform{OS: "linux", Architecture: "arm64"}
+	idxDigest, imgs := pushTestIndex(t, srcRef, amd64, arm64)
+
+	t.Run("requested platform only", func(t *testing.T) {
+		t.Parallel()
+
+		importer, store, imageStore := newTestImporter(t)
+		require.NoError(t, importer.ImportImage(
+			context.Background(), srcRef, "example.com/team/app:v1",
+			WithPlatforms(platforms.Only(ocispecPlatform(arm64))),
+		))
+
+		// The index is always imported, but only the content of the requested platform.
+		assert.True(t, contentExists(t, store, idxDigest))
+		for _, h := range imageBlobs(t, imgs[1]) {
+			assert.True(t, contentExists(t, store, h), h)
+		}
+		for _, h := range imageBlobs(t, imgs[0]) {
+			assert.False(t, contentExists(t, store, h), h)
+		}
+
+		img, err := imageStore.Get(context.Background(), "example.com/team/app:v1")
+		require.NoError(t, err)
+		assert.Equal(t, idxDigest.String(), img.Target.Digest.String())
+	})
+
+	t.Run("all platforms", func(t *testing.T) {
+		t.Parallel()
+
+		importer, store, _ := newTestImporter(t)
+		require.NoError(t, importer.ImportImage(
+			context.Background(), srcRef, "example.com/team/app:v1", WithPlatforms(platforms.All),
+		))
+
+		for _, img := range imgs {
+			for _, h := range imageBlobs(t, img) {
+				assert.True(t, contentExists(t, store, h), h)
+			}
+		}
+	})
+}
+
+func TestImportImageUpdatesExistingImage(t *testing.T) {
+	t.Parallel()
+
+	reg := bundletest.StartRegistry(t, registry.FilesystemStorage(t.TempDir()))
+	amd64 := v1.Platform{OS: "linux", Architecture: "amd64"}
+	v1Digest, _ := pushTestIndex(t, reg.Address()+"/app:v1", amd64)
+	v2Digest, _ := pushTestIndex(t, reg.Address()+"/app:v2", amd64)
+
+	importer, _, imageStore := newTestImporter(t)
+	opts := WithPlatforms(platforms.All)
+	require.NoError(t, importer.ImportImage(context.Background(), reg.Address()+"/app:v1", "example.com/app:v1", opts))
+	assert.Equal(t, []string{"example.com/app:v1"}, imageStore.created)
+	assert.Empty(t, imageStore.updated)
+
+	// Importing different content under the same name points the existing image record at the new content.
+	require.NoError(t, importer.ImportImage(context.Background(), reg.Address()+"/app:v2", "example.com/app:v1", opts))
+	assert.Equal(t, []string{"example.com/app:v1"}, imageStore.created)
+	assert.Equal(t, []string{"example.com/app:v1"}, imageStore.updated)
+
+	img, err := imageStore.Get(context.Background(), "example.com/app:v1")
+	require.NoError(t, err)
+	assert.NotEqual(t, v1Digest, v2Digest)
+	assert.Equal(t, v2Digest.String(), img.Target.Digest.String())
+}
+
+func ocispecPlatform(p v1.Platform) platforms.Platform {
+	return platforms.Platform{OS: p.OS, Architecture: p.Architecture, Variant: p.Variant}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	// publicRegion is the only region the ECR Public API is available in.
+	publicRegion = "us-east-1"
+
+	// roleSessionName identifies sessions of assumed roles in AWS CloudTrail.
+	roleSessionName = "mindthegap"
+)
+
+type clientOptions struct {
+	roleARN string
+}
+
+// ClientOption configures ECR clients.
+type ClientOption func(*clientOptions)
+
+// WithRoleARN configures the client to assume the specified IAM role, e.g. to manage the registry of another
+// AWS account. The role is assumed using the default AWS credentials. An empty role ARN uses the default AWS
+// credentials directly.
+func WithRoleARN(roleARN string) ClientOption {
+	return func(o *clientOptions) {
+		o.roleARN = roleARN
+	}
+}
+
+// ClientForRegistry returns a client for the private ECR registry at registryAddress.
+func ClientForRegistry(registryAddress string, opts ...ClientOption) (API, error) {
+	_, _, region, err := ParseECRRegistry(registryAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ECR registry host URI: %w", err)
+	}
+	cfg, err := loadConfig(context.TODO(), region, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Using the Config value, create the ECR client
+	return ecr.NewFromConfig(cfg), nil
+}
+
+// PublicClient returns a client for the ECR Public registry.
+func PublicClient(opts ...ClientOption) (PublicAPI, error) {
+	cfg, err := loadConfig(context.TODO(), publicRegion, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ecrpublic.NewFromConfig(cfg), nil
+}
+
+func loadConfig(ctx context.Context, region string, opts ...ClientOption) (aws.Config, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("unable to load SDK config, %w", err)
+	}
+
+	if o.roleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(
+			sts.NewFromConfig(cfg),
+			o.roleARN,
+			func(o *stscreds.AssumeRoleOptions) {
+				o.RoleSessionName = roleSessionName
+			},
+		))
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	// Isolate from any AWS configuration of the environment running the tests.
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	cfg, err := loadConfig(context.Background(), "eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.False(t, aws.IsCredentialsProvider(cfg.Credentials, &stscreds.AssumeRoleProvider{}))
+
+	cfg, err = loadConfig(
+		context.Background(), publicRegion, WithRoleARN("arn:aws:iam::222222222222:role/mirror-push"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", cfg.Region)
+	assert.True(t, aws.IsCredentialsProvider(cfg.Credentials, &stscreds.AssumeRoleProvider{}))
+}
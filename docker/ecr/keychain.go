@@ -0,0 +1,84 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// tokenRefreshMargin is how long before expiry an ECR authorization token is refreshed.
+const tokenRefreshMargin = 30 * time.Minute
+
+// NewKeychain returns a keychain providing credentials for the ECR registry at registryAddress using ECR
+// authorization tokens retrieved via ecrClient. Tokens are cached and refreshed shortly before they expire, so the
+// keychain can be used for pushes that take longer than the validity of a single token. Other registries resolve
+// to anonymous.
+func NewKeychain(ecrClient API, registryAddress string) authn.Keychain {
+	return newKeychain(registryAddress, func(ctx context.Context) (string, string, time.Time, error) {
+		return retrieveUsernameAndToken(ctx, ecrClient)
+	})
+}
+
+// NewPublicKeychain returns a keychain providing credentials for the ECR Public registry using authorization tokens
+// retrieved via client. Tokens are refreshed in the same way as for NewKeychain.
+func NewPublicKeychain(client PublicAPI) authn.Keychain {
+	return newKeychain(PublicRegistryAddress, func(ctx context.Context) (string, string, time.Time, error) {
+		return retrievePublicUsernameAndToken(ctx, client)
+	})
+}
+
+func newKeychain(registryAddress string, retrieveToken tokenRetriever) *keychain {
+	return &keychain{
+		registryAddress: registryAddress,
+		authenticator:   &tokenAuthenticator{retrieveToken: retrieveToken, now: time.Now},
+	}
+}
+
+type keychain struct {
+	registryAddress string
+	authenticator   *tokenAuthenticator
+}
+
+// Resolve implements authn.Keychain.
+func (k *keychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if target.RegistryStr() != k.registryAddress {
+		return authn.Anonymous, nil
+	}
+	return k.authenticator, nil
+}
+
+// tokenRetriever retrieves a new authorization token and the time it expires at.
+type tokenRetriever func(ctx context.Context) (username, token string, expiresAt time.Time, err error)
+
+// tokenAuthenticator caches an ECR authorization token, refreshing it before it expires.
+type tokenAuthenticator struct {
+	retrieveToken tokenRetriever
+	now           func() time.Time
+
+	mu        sync.Mutex
+	cfg       *authn.AuthConfig
+	expiresAt time.Time
+}
+
+// Authorization implements authn.Authenticator.
+func (a *tokenAuthenticator) Authorization() (*authn.AuthConfig, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cfg != nil && a.now().Before(a.expiresAt.Add(-tokenRefreshMargin)) {
+		return a.cfg, nil
+	}
+
+	username, token, expiresAt, err := a.retrieveToken(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	a.cfg = &authn.AuthConfig{Username: username, Password: token}
+	a.expiresAt = expiresAt
+	return a.cfg, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTokenECR returns a new authorization token, valid for 12 hours from now, every time one is requested.
+type fakeTokenECR struct {
+	API
+
+	now         func() time.Time
+	tokenCalls  int
+	failNextErr error
+}
+
+func (f *fakeTokenECR) GetAuthorizationToken(
+	_ context.Context, _ *ecr.GetAuthorizationTokenInput, _ ...func(*ecr.Options),
+) (*ecr.GetAuthorizationTokenOutput, error) {
+	if f.failNextErr != nil {
+		return nil, f.failNextErr
+	}
+	f.tokenCalls++
+	token := base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "AWS:token-%d", f.tokenCalls))
+	return &ecr.GetAuthorizationTokenOutput{
+		AuthorizationData: []types.AuthorizationData{{
+			AuthorizationToken: &token,
+			ExpiresAt:          new(f.now().Add(12 * time.Hour)),
+		}},
+	}, nil
+}
+
+func TestKeychainRefreshesTokenBeforeExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	fake := &fakeTokenECR{now: clock}
+
+	const registryAddress = "123456789.dkr.ecr.us-east-1.amazonaws.com"
+	kc := NewKeychain(fake, registryAddress)
+	kc.(*keychain).authenticator.now = clock
+
+	authorization := func() *authn.AuthConfig {
+		t.Helper()
+		auth, err := kc.Resolve(name.MustParseReference(registryAddress + "/nginx:1.0").Context())
+		require.NoError(t, err)
+		cfg, err := auth.Authorization()
+		require.NoError(t, err)
+		return cfg
+	}
+
+	assert.Equal(t, &authn.AuthConfig{Username: "AWS", Password: "token-1"}, authorization())
+
+	// Token is cached while it is valid for longer than the refresh margin.
+	now = now.Add(11 * time.Hour)
+	assert.Equal(t, "token-1", authorization().Password)
+	assert.Equal(t, 1, fake.tokenCalls)
+
+	// Token is refreshed shortly before it expires.
+	now = now.Add(45 * time.Minute)
+	assert.Equal(t, "token-2", authorization().Password)
+	assert.Equal(t, 2, fake.tokenCalls)
+
+	// Errors refreshing the token are returned.
+	now = now.Add(12 * time.Hour)
+	fake.failNextErr = fmt.Errorf("expired AWS session")
+	auth, err := kc.Resolve(name.MustParseReference(registryAddress + "/nginx:1.0").Context())
+	require.NoError(t, err)
+	_, err = auth.Authorization()
+	require.ErrorContains(t, err, "expired AWS session")
+}
+
+func TestKeychainOtherRegistry(t *testing.T) {
+	t.Parallel()
+
+	kc := NewKeychain(&fakeTokenECR{now: time.Now}, "123456789.dkr.ecr.us-east-1.amazonaws.com")
+	auth, err := kc.Resolve(name.MustParseReference("docker.io/library/nginx:1.0").Context())
+	require.NoError(t, err)
+	assert.Equal(t, authn.Anonymous, auth)
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic/types"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// PublicAPI is the subset of the ECR Public API used to manage repositories and retrieve credentials. It is
+// implemented by *ecrpublic.Client.
+type PublicAPI interface {
+	DescribeRepositories(
+		ctx context.Context, params *ecrpublic.DescribeRepositoriesInput, optFns ...func(*ecrpublic.Options),
+	) (*ecrpublic.DescribeRepositoriesOutput, error)
+	CreateRepository(
+		ctx context.Context, params *ecrpublic.CreateRepositoryInput, optFns ...func(*ecrpublic.Options),
+	) (*ecrpublic.CreateRepositoryOutput, error)
+	SetRepositoryPolicy(
+		ctx context.Context, params *ecrpublic.SetRepositoryPolicyInput, optFns ...func(*ecrpublic.Options),
+	) (*ecrpublic.SetRepositoryPolicyOutput, error)
+	TagResource(
+		ctx context.Context, params *ecrpublic.TagResourceInput, optFns ...func(*ecrpublic.Options),
+	) (*ecrpublic.TagResourceOutput, error)
+	GetAuthorizationToken(
+		ctx context.Context, params *ecrpublic.GetAuthorizationTokenInput, optFns ...func(*ecrpublic.Options),
+	) (*ecrpublic.GetAuthorizationTokenOutput, error)
+}
+
+var _ PublicAPI = &ecrpublic.Client{}
+
+// EnsurePublicRepositoryExistsFunc returns a function that creates the destination repository in ECR Public
+// configured from tmpl if it does not exist yet. ECR Public repositories only support tags and repository policies,
+// so an error is returned if tmpl specifies any other options.
+func EnsurePublicRepositoryExistsFunc(client PublicAPI, tmpl *RepositoryTemplate) (func(
+	destRepositoryName name.Repository, _ ...string,
+) error, error) {
+	if tmpl == nil {
+		tmpl = &RepositoryTemplate{}
+	}
+	if err := tmpl.validatePublic(); err != nil {
+		return nil, err
+	}
+
+	return func(
+		destRepositoryName name.Repository, _ ...string,
+	) error {
+		repositoryName, err := publicRepositoryName(destRepositoryName)
+		if err != nil {
+			return err
+		}
+
+		repos, err := client.DescribeRepositories(
+			context.TODO(),
+			&ecrpublic.DescribeRepositoriesInput{
+				RepositoryNames: []string{repositoryName},
+			},
+		)
+		repoNotExistsErr := &types.RepositoryNotFoundException{}
+		if err != nil && !errors.As(err, &repoNotExistsErr) {
+			return fmt.Errorf("failed to check if ECR Public repository exists: %w", err)
+		}
+		if repos != nil && len(repos.Repositories) > 0 {
+			if !tmpl.ReconcileExisting {
+				return nil
+			}
+			if len(tmpl.Tags) > 0 {
+				_, err := client.TagResource(
+					context.TODO(),
+					&ecrpublic.TagResourceInput{
+						ResourceArn: repos.Repositories[0].RepositoryArn,
+						Tags:        tmpl.publicResourceTags(),
+					},
+				)
+				if err != nil {
+					return fmt.Errorf("failed to apply ECR Public repository tags: %w", err)
+				}
+			}
+			return applyPublicRepositoryPolicy(client, repositoryName, tmpl)
+		}
+
+		createInput := &ecrpublic.CreateRepositoryInput{
+			RepositoryName: &repositoryName,
+		}
+		if len(tmpl.Tags) > 0 {
+			createInput.Tags = tmpl.publicResourceTags()
+		}
+		if _, err := client.CreateRepository(context.TODO(), createInput); err != nil {
+			return fmt.Errorf("failed to create repository in ECR Public: %w", err)
+		}
+
+		return applyPublicRepositoryPolicy(client, repositoryName, tmpl)
+	}, nil
+}
+
+// publicRepositoryName returns the name of the ECR Public repository, which excludes the registry alias that ECR
+// Public repository URIs are prefixed with.
+func publicRepositoryName(destRepositoryName name.Repository) (string, error) {
+	_, repositoryName, found := strings.Cut(destRepositoryName.RepositoryStr(), "/")
+	if !found {
+		return "", fmt.Errorf(
+			"ECR Public repository %s must be prefixed with the registry alias, e.g. %s/<alias>/<repository>",
+			destRepositoryName, PublicRegistryAddress,
+		)
+	}
+	return repositoryName, nil
+}
+
+func applyPublicRepositoryPolicy(client PublicAPI, repositoryName string, tmpl *RepositoryTemplate) error {
+	if tmpl.repositoryPolicy == "" {
+		return nil
+	}
+	_, err := client.SetRepositoryPolicy(
+		context.TODO(),
+		&ecrpublic.SetRepositoryPolicyInput{
+			RepositoryName: &repositoryName,
+			PolicyText:     &tmpl.repositoryPolicy,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to apply ECR Public repository policy: %w", err)
+	}
+	return nil
+}
+
+func retrievePublicUsernameAndToken(
+	ctx context.Context, client PublicAPI,
+) (username, token string, expiresAt time.Time, err error) {
+	out, err := client.GetAuthorizationToken(ctx, &ecrpublic.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	if out.AuthorizationData == nil {
+		return "", "", time.Time{}, fmt.Errorf("no authorization data returned from ECR Public")
+	}
+	username, token, err = decodeAuthorizationToken(out.AuthorizationData.AuthorizationToken)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return username, token, aws.ToTime(out.AuthorizationData.ExpiresAt), nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePublicECR records the calls made to the ECR Public API.
+type fakePublicECR struct {
+	PublicAPI
+
+	repositories map[string]types.Repository
+	calls        []string
+
+	created          *ecrpublic.CreateRepositoryInput
+	repositoryPolicy string
+	tags             []types.Tag
+}
+
+func (f *fakePublicECR) DescribeRepositories(
+	_ context.Context, params *ecrpublic.DescribeRepositoriesInput, _ ...func(*ecrpublic.Options),
+) (*ecrpublic.DescribeRepositoriesOutput, error) {
+	f.calls = append(f.calls, "DescribeRepositories")
+	repo, ok := f.repositories[params.RepositoryNames[0]]
+	if !ok {
+		return nil, &types.RepositoryNotFoundException{}
+	}
+	return &ecrpublic.DescribeRepositoriesOutput{Repositories: []types.Repository{repo}}, nil
+}
+
+func (f *fakePublicECR) CreateRepository(
+	_ context.Context, params *ecrpublic.CreateRepositoryInput, _ ...func(*ecrpublic.Options),
+) (*ecrpublic.CreateRepositoryOutput, error) {
+	f.calls = append(f.calls, "CreateRepository")
+	f.created = params
+	return &ecrpublic.CreateRepositoryOutput{}, nil
+}
+
+func (f *fakePublicECR) SetRepositoryPolicy(
+	_ context.Context, params *ecrpublic.SetRepositoryPolicyInput, _ ...func(*ecrpublic.Options),
+) (*ecrpublic.SetRepositoryPolicyOutput, error) {
+	f.calls = append(f.calls, "SetRepositoryPolicy")
+	f.repositoryPolicy = aws.ToString(params.PolicyText)
+	return &ecrpublic.SetRepositoryPolicyOutput{}, nil
+}
+
+func (f *fakePublicECR) TagResource(
+	_ context.Context, params *ecrpublic.TagResourceInput, _ ...func(*ecrpublic.Options),
+) (*ecrpublic.TagResourceOutput, error) {
+	f.calls = append(f.calls, "TagResource")
+	f.tags = params.Tags
+	return &ecrpublic.TagResourceOutput{}, nil
+}
+
+func (f *fakePublicECR) GetAuthorizationToken(
+	_ context.Context, _ *ecrpublic.GetAuthorizationTokenInput, _ ...func(*ecrpublic.Options),
+) (*ecrpublic.GetAuthorizationTokenOutput, error) {
+	f.calls = append(f.calls, "GetAuthorizationToken")
+	return &ecrpublic.GetAuthorizationTokenOutput{
+		AuthorizationData: &types.AuthorizationData{
+			AuthorizationToken: new(base64.StdEncoding.EncodeToString([]byte("AWS:public-token"))),
+			ExpiresAt:          new(time.Now().Add(12 * time.Hour)),
+		},
+	}, nil
+}
+
+var testPublicRepo = name.MustParseReference("public.ecr.aws/example/mirror/nginx:1.0").Context()
+
+func TestEnsurePublicRepositoryExistsFunc(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := LoadRepositoryTemplateFile(writeTemplate(t, `
+repositoryPolicyFile: policy.json
+tags:
+  team: platform
+`))
+	require.NoError(t, err)
+
+	fake := &fakePublicECR{}
+	ensureRepositoryExists, err := EnsurePublicRepositoryExistsFunc(fake, tmpl)
+	require.NoError(t, err)
+	require.NoError(t, ensureRepositoryExists(testPublicRepo))
+
+	assert.Equal(t, []string{"DescribeRepositories", "CreateRepository", "SetRepositoryPolicy"}, fake.calls)
+	assert.Equal(t, "mirror/nginx", aws.ToString(fake.created.RepositoryName))
+	assert.Equal(t, []types.Tag{{Key: new("team"), Value: new("platform")}}, fake.created.Tags)
+	assert.JSONEq(t, `{"repository":"policy"}`, fake.repositoryPolicy)
+}
+
+func TestEnsurePublicRepositoryExistsFuncExistingRepository(t *testing.T) {
+	t.Parallel()
+
+	existing := map[string]types.Repository{
+		"mirror/nginx": {
+			RepositoryName: new("mirror/nginx"),
+			RepositoryArn:  new("arn:aws:ecr-public::123456789:repository/mirror/nginx"),
+		},
+	}
+
+	tmpl, err := LoadRepositoryTemplateFile(writeTemplate(t, "tags:\n  team: platform\n"))
+	require.NoError(t, err)
+
+	fake := &fakePublicECR{repositories: existing}
+	ensureRepositoryExists, err := EnsurePublicRepositoryExistsFunc(fake, tmpl)
+	require.NoError(t, err)
+	require.NoError(t, ensureRepositoryExists(testPublicRepo))
+	assert.Equal(t, []string{"DescribeRepositories"}, fake.calls, "existing repository must not be modified")
+
+	tmpl.ReconcileExisting = true
+	fake = &fakePublicECR{repositories: existing}
+	ensureRepositoryExists, err = EnsurePublicRepositoryExistsFunc(fake, tmpl)
+	require.NoError(t, err)
+	require.NoError(t, ensureRepositoryExists(testPublicRepo))
+	assert.Equal(t, []string{"DescribeRepositories", "TagResource"}, fake.calls)
+	assert.Len(t, fake.tags, 1)
+}
+
+func TestEnsurePublicRepositoryExistsFuncErrors(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := LoadRepositoryTemplateFile(writeTemplate(t, `
+imageTagMutability: IMMUTABLE
+lifecyclePolicyFile: lifecycle.json
+`))
+	require.NoError(t, err)
+	_, err = EnsurePublicRepositoryExistsFunc(&fakePublicECR{}, tmpl)
+	require.EqualError(t, err, "ECR Public repositories do not support imageTagMutability, lifecycle policy")
+
+	ensureRepositoryExists, err := EnsurePublicRepositoryExistsFunc(&fakePublicECR{}, nil)
+	require.NoError(t, err)
+	err = ensureRepositoryExists(name.MustParseReference("public.ecr.aws/nginx:1.0").Context())
+	require.ErrorContains(t, err, "must be prefixed with the registry alias")
+}
+
+func TestPublicKeychain(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakePublicECR{}
+	kc := NewPublicKeychain(fake)
+
+	auth, err := kc.Resolve(testPublicRepo)
+	require.NoError(t, err)
+	cfg, err := auth.Authorization()
+	require.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "AWS", Password: "public-token"}, cfg)
+
+	auth, err = kc.Resolve(testRepo)
+	require.NoError(t, err)
+	assert.Equal(t, authn.Anonymous, auth)
+}
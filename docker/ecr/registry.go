@@ -17,6 +17,16 @@ func IsECRRegistry(registryAddress string) bool {
 	return ecrRegistryRegexp.MatchString(registryAddress)
 }
 
+// PublicRegistryAddress is the address of the Amazon ECR Public registry.
+const PublicRegistryAddress = "public.ecr.aws"
+
+// regular expression to represent the ECR Public endpoint, optionally followed by the registry alias.
+var ecrPublicRegistryRegexp = regexp.MustCompile(`^(?:https://)?public\.ecr\.aws(?:/|$)`)
+
+func IsECRPublicRegistry(registryAddress string) bool {
+	return ecrPublicRegistryRegexp.MatchString(registryAddress)
+}
+
 func ParseECRRegistry(
 	registryAddress string,
 ) (accountID string, fips bool, region string, err error) {
@@ -67,6 +67,37 @@ func TestIsECRRegistry(t *testing.T) {
 	}
 }
 
+func TestIsECRPublicRegistry(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		registryAddress string
+		want            bool
+	}{{
+		name:            "ECR Public",
+		registryAddress: "public.ecr.aws",
+		want:            true,
+	}, {
+		name:            "ECR Public with https protocol and alias",
+		registryAddress: "https://public.ecr.aws/example",
+		want:            true,
+	}, {
+		name:            "private ECR",
+		registryAddress: "123456789.dkr.ecr.us-east-1.amazonaws.com",
+		want:            false,
+	}, {
+		name:            "similar host",
+		registryAddress: "public.ecr.aws.example.com",
+		want:            false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, IsECRPublicRegistry(tt.registryAddress))
+		})
+	}
+}
+
 func TestParseECRRegistry(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -8,33 +8,55 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
 	"github.com/google/go-containerregistry/pkg/name"
 )
 
-func ClientForRegistry(registryAddress string) (*ecr.Client, error) {
-	_, _, region, err := ParseECRRegistry(registryAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse ECR registry host URI: %w", err)
-	}
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
-	if err != nil {
-		return nil, fmt.Errorf("unable to load SDK config, %w", err)
-	}
-
-	// Using the Config value, create the ECR client
-	return ecr.NewFromConfig(cfg), nil
+// API is the subset of the ECR API used to manage repositories and retrieve credentials. It is implemented by
+// *ecr.Client.
+type API interface {
+	DescribeRepositories(
+		ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options),
+	) (*ecr.DescribeRepositoriesOutput, error)
+	CreateRepository(
+		ctx context.Context, params *ecr.CreateRepositoryInput, optFns ...func(*ecr.Options),
+	) (*ecr.CreateRepositoryOutput, error)
+	PutLifecyclePolicy(
+		ctx context.Context, params *ecr.PutLifecyclePolicyInput, optFns ...func(*ecr.Options),
+	) (*ecr.PutLifecyclePolicyOutput, error)
+	SetRepositoryPolicy(
+		ctx context.Context, params *ecr.SetRepositoryPolicyInput, optFns ...func(*ecr.Options),
+	) (*ecr.SetRepositoryPolicyOutput, error)
+	PutImageTagMutability(
+		ctx context.Context, params *ecr.PutImageTagMutabilityInput, optFns ...func(*ecr.Options),
+	) (*ecr.PutImageTagMutabilityOutput, error)
+	PutImageScanningConfiguration(
+		ctx context.Context, params *ecr.PutImageScanningConfigurationInput, optFns ...func(*ecr.Options),
+	) (*ecr.PutImageScanningConfigurationOutput, error)
+	TagResource(
+		ctx context.Context, params *ecr.TagResourceInput, optFns ...func(*ecr.Options),
+	) (*ecr.TagResourceOutput, error)
+	GetAuthorizationToken(
+		ctx context.Context, params *ecr.GetAuthorizationTokenInput, optFns ...func(*ecr.Options),
+	) (*ecr.GetAuthorizationTokenOutput, error)
 }
 
-func EnsureRepositoryExistsFunc(ecrClient *ecr.Client, ecrLifecyclePolicy string) func(
+var _ API = &ecr.Client{}
+
+// EnsureRepositoryExistsFunc returns a function that creates the destination repository configured from tmpl
+// if it does not exist yet. If tmpl.ReconcileExisting is set then tmpl is also applied to existing repositories.
+func EnsureRepositoryExistsFunc(ecrClient API, tmpl *RepositoryTemplate) func(
 	destRepositoryName name.Repository, _ ...string,
 ) error {
+	if tmpl == nil {
+		tmpl = &RepositoryTemplate{}
+	}
+
 	return func(
 		destRepositoryName name.Repository, _ ...string,
 	) error {
@@ -51,59 +73,138 @@ func EnsureRepositoryExistsFunc(ecrClient *ecr.Client, ecrLifecyclePolicy string
 			return fmt.Errorf("failed to check if ECR repository exists: %w", err)
 		}
 		if repos != nil && len(repos.Repositories) > 0 {
-			return nil
+			if !tmpl.ReconcileExisting {
+				return nil
+			}
+			return reconcileRepository(ecrClient, repos.Repositories[0], tmpl)
 		}
 
-		_, err = ecrClient.CreateRepository(
+		createInput := &ecr.CreateRepositoryInput{
+			RepositoryName: &repositoryName,
+			ImageScanningConfiguration: &types.ImageScanningConfiguration{
+				ScanOnPush: tmpl.scanOnPush(),
+			},
+			ImageTagMutability: tmpl.ImageTagMutability,
+		}
+		if tmpl.Encryption != nil {
+			createInput.EncryptionConfiguration = &types.EncryptionConfiguration{
+				EncryptionType: tmpl.Encryption.Type,
+			}
+			if tmpl.Encryption.KMSKey != "" {
+				createInput.EncryptionConfiguration.KmsKey = &tmpl.Encryption.KMSKey
+			}
+		}
+		if len(tmpl.Tags) > 0 {
+			createInput.Tags = tmpl.resourceTags()
+		}
+		if _, err := ecrClient.CreateRepository(context.TODO(), createInput); err != nil {
+			return fmt.Errorf("failed to create reposiotry in ECR: %w", err)
+		}
+
+		return applyRepositoryPolicies(ecrClient, repositoryName, tmpl)
+	}
+}
+
+// reconcileRepository applies the template to an existing repository. Encryption cannot be changed after a
+// repository has been created so it is not reconciled.
+func reconcileRepository(ecrClient API, repo types.Repository, tmpl *RepositoryTemplate) error {
+	if repo.ImageScanningConfiguration == nil ||
+		repo.ImageScanningConfiguration.ScanOnPush != tmpl.scanOnPush() {
+		_, err := ecrClient.PutImageScanningConfiguration(
 			context.TODO(),
-			&ecr.CreateRepositoryInput{
-				RepositoryName:             &repositoryName,
-				ImageScanningConfiguration: &types.ImageScanningConfiguration{ScanOnPush: true},
+			&ecr.PutImageScanningConfigurationInput{
+				RepositoryName: repo.RepositoryName,
+				ImageScanningConfiguration: &types.ImageScanningConfiguration{
+					ScanOnPush: tmpl.scanOnPush(),
+				},
 			},
 		)
 		if err != nil {
-			return fmt.Errorf("failed to create reposiotry in ECR: %w", err)
+			return fmt.Errorf("failed to apply ECR repository image scanning configuration: %w", err)
 		}
+	}
 
-		if ecrLifecyclePolicy == "" {
-			return nil
+	if tmpl.ImageTagMutability != "" && repo.ImageTagMutability != tmpl.ImageTagMutability {
+		_, err := ecrClient.PutImageTagMutability(
+			context.TODO(),
+			&ecr.PutImageTagMutabilityInput{
+				RepositoryName:     repo.RepositoryName,
+				ImageTagMutability: tmpl.ImageTagMutability,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to apply ECR repository image tag mutability: %w", err)
 		}
-		ecrLifecyclePolicyText, err := os.ReadFile(ecrLifecyclePolicy)
+	}
+
+	if len(tmpl.Tags) > 0 {
+		_, err := ecrClient.TagResource(
+			context.TODO(),
+			&ecr.TagResourceInput{
+				ResourceArn: repo.RepositoryArn,
+				Tags:        tmpl.resourceTags(),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to apply ECR repository tags: %w", err)
+		}
+	}
+
+	return applyRepositoryPolicies(ecrClient, aws.ToString(repo.RepositoryName), tmpl)
+}
+
+func applyRepositoryPolicies(ecrClient API, repositoryName string, tmpl *RepositoryTemplate) error {
+	if tmpl.repositoryPolicy != "" {
+		_, err := ecrClient.SetRepositoryPolicy(
+			context.TODO(),
+			&ecr.SetRepositoryPolicyInput{
+				RepositoryName: &repositoryName,
+				PolicyText:     &tmpl.repositoryPolicy,
+			},
+		)
 		if err != nil {
-			return fmt.Errorf(
-				"failed to read ECR lifecycle policy from %q: %w",
-				ecrLifecyclePolicy,
-				err,
-			)
+			return fmt.Errorf("failed to apply ECR repository policy: %w", err)
 		}
-		_, err = ecrClient.PutLifecyclePolicy(
+	}
+
+	if tmpl.lifecyclePolicy != "" {
+		_, err := ecrClient.PutLifecyclePolicy(
 			context.TODO(),
 			&ecr.PutLifecyclePolicyInput{
 				RepositoryName:      &repositoryName,
-				LifecyclePolicyText: new(string(ecrLifecyclePolicyText)),
+				LifecyclePolicyText: &tmpl.lifecyclePolicy,
 			},
 		)
 		if err != nil {
 			return fmt.Errorf("failed to apply ECR repository lifecycle policy: %w", err)
 		}
-
-		return nil
 	}
+
+	return nil
 }
 
-func RetrieveUsernameAndToken(ecrClient *ecr.Client) (username, token string, err error) {
+func retrieveUsernameAndToken(
+	ctx context.Context, ecrClient API,
+) (username, token string, expiresAt time.Time, err error) {
 	// Passing nil as second parameter as passing registry ID is deprecated and does not affect authorization.
-	out, err := ecrClient.GetAuthorizationToken(context.Background(), nil)
+	out, err := ecrClient.GetAuthorizationToken(ctx, nil)
 	if err != nil {
-		return "", "", err
+		return "", "", time.Time{}, err
 	}
+	if len(out.AuthorizationData) == 0 {
+		return "", "", time.Time{}, fmt.Errorf("no authorization data returned from ECR")
+	}
+	username, token, err = decodeAuthorizationToken(out.AuthorizationData[0].AuthorizationToken)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return username, token, aws.ToTime(out.AuthorizationData[0].ExpiresAt), nil
+}
+
+func decodeAuthorizationToken(authorizationToken *string) (username, token string, err error) {
 	// Returned token is a base64-encoded `<username>:<password>``. Username will normally be AWS but that is not
 	// guaranteed.
-	base64EncodedAuthorizationToken := aws.ToString(out.AuthorizationData[0].AuthorizationToken)
-
-	decodedAuthorizationToken, err := base64.StdEncoding.DecodeString(
-		base64EncodedAuthorizationToken,
-	)
+	decodedAuthorizationToken, err := base64.StdEncoding.DecodeString(aws.ToString(authorizationToken))
 	if err != nil {
 		return "", "", err
 	}
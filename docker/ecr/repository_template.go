@@ -0,0 +1,202 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	publictypes "github.com/aws/aws-sdk-go-v2/service/ecrpublic/types"
+	"gopkg.in/yaml.v3"
+)
+
+// RepositoryTemplate configures ECR repositories created before pushing into them.
+type RepositoryTemplate struct {
+	// ScanOnPush enables image scanning on push. Defaults to true.
+	ScanOnPush *bool `yaml:"scanOnPush,omitempty"`
+	// ImageTagMutability is the tag mutability of the repository, e.g. MUTABLE or IMMUTABLE. Defaults to the ECR
+	// default of MUTABLE.
+	ImageTagMutability types.ImageTagMutability `yaml:"imageTagMutability,omitempty"`
+	// Encryption is the encryption configuration of the repository. Defaults to the ECR default of AES256.
+	// Encryption cannot be changed after a repository has been created so it is only applied to new
+	// repositories.
+	Encryption *RepositoryEncryption `yaml:"encryption,omitempty"`
+	// RepositoryPolicyFile is a file containing the repository policy JSON.
+	RepositoryPolicyFile string `yaml:"repositoryPolicyFile,omitempty"`
+	// LifecyclePolicyFile is a file containing the lifecycle policy JSON.
+	LifecyclePolicyFile string `yaml:"lifecyclePolicyFile,omitempty"`
+	// Tags are the resource tags to add to the repository.
+	Tags map[string]string `yaml:"tags,omitempty"`
+	// ReconcileExisting applies the template to repositories that already exist as well as to newly created
+	// repositories.
+	ReconcileExisting bool `yaml:"reconcileExisting,omitempty"`
+
+	repositoryPolicy string
+	lifecyclePolicy  string
+}
+
+// RepositoryEncryption is the encryption configuration of a repository.
+type RepositoryEncryption struct {
+	// Type is the encryption type, e.g. AES256 or KMS.
+	Type types.EncryptionType `yaml:"type"`
+	// KMSKey is the KMS key to use for KMS encryption. Defaults to the AWS managed KMS key for ECR.
+	KMSKey string `yaml:"kmsKey,omitempty"`
+}
+
+// LoadRepositoryTemplateFile reads a repository template from the specified file. Relative policy file paths are
+// resolved relative to the directory containing the template file.
+func LoadRepositoryTemplateFile(templateFile string) (*RepositoryTemplate, error) {
+	f, err := os.Open(templateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ECR repository template: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		tmpl RepositoryTemplate
+		dec  = yaml.NewDecoder(f)
+	)
+	dec.KnownFields(true)
+	if err := dec.Decode(&tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse ECR repository template: %w", err)
+	}
+
+	if err := tmpl.validate(); err != nil {
+		return nil, fmt.Errorf("invalid ECR repository template %s: %w", templateFile, err)
+	}
+
+	baseDir := filepath.Dir(templateFile)
+	resolvePath := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(baseDir, p)
+	}
+	tmpl.RepositoryPolicyFile = resolvePath(tmpl.RepositoryPolicyFile)
+	tmpl.LifecyclePolicyFile = resolvePath(tmpl.LifecyclePolicyFile)
+
+	if err := tmpl.loadPolicies(); err != nil {
+		return nil, err
+	}
+
+	return &tmpl, nil
+}
+
+// NewRepositoryTemplate returns the default repository template, scanning images on push and applying the
+// lifecycle policy from the specified file, if any.
+func NewRepositoryTemplate(lifecyclePolicyFile string) (*RepositoryTemplate, error) {
+	tmpl := &RepositoryTemplate{LifecyclePolicyFile: lifecyclePolicyFile}
+	if err := tmpl.loadPolicies(); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// WithLifecyclePolicyFile sets the lifecycle policy from the specified file. It is an error if the template
+// already specifies a lifecycle policy.
+func (t *RepositoryTemplate) WithLifecyclePolicyFile(lifecyclePolicyFile string) error {
+	if lifecyclePolicyFile == "" {
+		return nil
+	}
+	if t.LifecyclePolicyFile != "" {
+		return fmt.Errorf(
+			"lifecycle policy is specified both in the ECR repository template and as %s", lifecyclePolicyFile,
+		)
+	}
+	t.LifecyclePolicyFile = lifecyclePolicyFile
+	return t.loadPolicies()
+}
+
+func (t *RepositoryTemplate) validate() error {
+	if t.ImageTagMutability != "" &&
+		!slices.Contains(t.ImageTagMutability.Values(), t.ImageTagMutability) {
+		return fmt.Errorf(
+			"invalid imageTagMutability %q: must be one of %v",
+			t.ImageTagMutability, t.ImageTagMutability.Values(),
+		)
+	}
+	if t.Encryption != nil {
+		if !slices.Contains(t.Encryption.Type.Values(), t.Encryption.Type) {
+			return fmt.Errorf(
+				"invalid encryption type %q: must be one of %v",
+				t.Encryption.Type, t.Encryption.Type.Values(),
+			)
+		}
+		if t.Encryption.KMSKey != "" && t.Encryption.Type == types.EncryptionTypeAes256 {
+			return fmt.Errorf("kmsKey cannot be specified with %s encryption", types.EncryptionTypeAes256)
+		}
+	}
+	return nil
+}
+
+func (t *RepositoryTemplate) loadPolicies() error {
+	if t.RepositoryPolicyFile != "" {
+		b, err := os.ReadFile(t.RepositoryPolicyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read ECR repository policy from %q: %w", t.RepositoryPolicyFile, err)
+		}
+		t.repositoryPolicy = string(b)
+	}
+	if t.LifecyclePolicyFile != "" {
+		b, err := os.ReadFile(t.LifecyclePolicyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read ECR lifecycle policy from %q: %w", t.LifecyclePolicyFile, err)
+		}
+		t.lifecyclePolicy = string(b)
+	}
+	return nil
+}
+
+func (t *RepositoryTemplate) scanOnPush() bool {
+	return t.ScanOnPush == nil || *t.ScanOnPush
+}
+
+// resourceTags returns the tags of the template sorted by key.
+func (t *RepositoryTemplate) resourceTags() []types.Tag {
+	keys := make([]string, 0, len(t.Tags))
+	for k := range t.Tags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	tags := make([]types.Tag, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, types.Tag{Key: new(k), Value: new(t.Tags[k])})
+	}
+	return tags
+}
+
+// validatePublic checks that the template only uses options supported by ECR Public repositories.
+func (t *RepositoryTemplate) validatePublic() error {
+	var unsupported []string
+	if t.ScanOnPush != nil {
+		unsupported = append(unsupported, "scanOnPush")
+	}
+	if t.ImageTagMutability != "" {
+		unsupported = append(unsupported, "imageTagMutability")
+	}
+	if t.Encryption != nil {
+		unsupported = append(unsupported, "encryption")
+	}
+	if t.LifecyclePolicyFile != "" {
+		unsupported = append(unsupported, "lifecycle policy")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("ECR Public repositories do not support %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// publicResourceTags returns the tags of the template sorted by key for ECR Public repositories.
+func (t *RepositoryTemplate) publicResourceTags() []publictypes.Tag {
+	tags := make([]publictypes.Tag, 0, len(t.Tags))
+	for _, tag := range t.resourceTags() {
+		tags = append(tags, publictypes.Tag{Key: tag.Key, Value: tag.Value})
+	}
+	return tags
+}
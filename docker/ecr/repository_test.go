@@ -0,0 +1,240 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ecr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeECR records the calls made to the ECR API.
+type fakeECR struct {
+	API
+
+	repositories map[string]types.Repository
+	calls        []string
+
+	created          *ecr.CreateRepositoryInput
+	repositoryPolicy string
+	lifecyclePolicy  string
+	tags             []types.Tag
+	tagMutability    types.ImageTagMutability
+	scanOnPush       *bool
+}
+
+func (f *fakeECR) DescribeRepositories(
+	_ context.Context, params *ecr.DescribeRepositoriesInput, _ ...func(*ecr.Options),
+) (*ecr.DescribeRepositoriesOutput, error) {
+	f.calls = append(f.calls, "DescribeRepositories")
+	repo, ok := f.repositories[params.RepositoryNames[0]]
+	if !ok {
+		return nil, &types.RepositoryNotFoundException{}
+	}
+	return &ecr.DescribeRepositoriesOutput{Repositories: []types.Repository{repo}}, nil
+}
+
+func (f *fakeECR) CreateRepository(
+	_ context.Context, params *ecr.CreateRepositoryInput, _ ...func(*ecr.Options),
+) (*ecr.CreateRepositoryOutput, error) {
+	f.calls = append(f.calls, "CreateRepository")
+	f.created = params
+	return &ecr.CreateRepositoryOutput{}, nil
+}
+
+func (f *fakeECR) PutLifecyclePolicy(
+	_ context.Context, params *ecr.PutLifecyclePolicyInput, _ ...func(*ecr.Options),
+) (*ecr.PutLifecyclePolicyOutput, error) {
+	f.calls = append(f.calls, "PutLifecyclePolicy")
+	f.lifecyclePolicy = aws.ToString(params.LifecyclePolicyText)
+	return &ecr.PutLifecyclePolicyOutput{}, nil
+}
+
+func (f *fakeECR) SetRepositoryPolicy(
+	_ context.Context, params *ecr.SetRepositoryPolicyInput, _ ...func(*ecr.Options),
+) (*ecr.SetRepositoryPolicyOutput, error) {
+	f.calls = append(f.calls, "SetRepositoryPolicy")
+	f.repositoryPolicy = aws.ToString(params.PolicyText)
+	return &ecr.SetRepositoryPolicyOutput{}, nil
+}
+
+func (f *fakeECR) PutImageTagMutability(
+	_ context.Context, params *ecr.PutImageTagMutabilityInput, _ ...func(*ecr.Options),
+) (*ecr.PutImageTagMutabilityOutput, error) {
+	f.calls = append(f.calls, "PutImageTagMutability")
+	f.tagMutability = params.ImageTagMutability
+	return &ecr.PutImageTagMutabilityOutput{}, nil
+}
+
+func (f *fakeECR) PutImageScanningConfiguration(
+	_ context.Context, params *ecr.PutImageScanningConfigurationInput, _ ...func(*ecr.Options),
+) (*ecr.PutImageScanningConfigurationOutput, error) {
+	f.calls = append(f.calls, "PutImageScanningConfiguration")
+	f.scanOnPush = &params.ImageScanningConfiguration.ScanOnPush
+	return &ecr.PutImageScanningConfigurationOutput{}, nil
+}
+
+func (f *fakeECR) TagResource(
+	_ context.Context, params *ecr.TagResourceInput, _ ...func(*ecr.Options),
+) (*ecr.TagResourceOutput, error) {
+	f.calls = append(f.calls, "TagResource")
+	f.tags = params.Tags
+	return &ecr.TagResourceOutput{}, nil
+}
+
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.json"), []byte(`{"repository":"policy"}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lifecycle.json"), []byte(`{"rules":[]}`), 0o600))
+	f := filepath.Join(dir, "template.yaml")
+	require.NoError(t, os.WriteFile(f, []byte(content), 0o600))
+	return f
+}
+
+const testTemplate = `
+scanOnPush: false
+imageTagMutability: IMMUTABLE
+encryption:
+  type: KMS
+  kmsKey: arn:aws:kms:us-east-1:123456789:key/abc
+repositoryPolicyFile: policy.json
+lifecyclePolicyFile: lifecycle.json
+tags:
+  team: platform
+  cost-center: "1234"
+`
+
+var testRepo = name.MustParseReference("123456789.dkr.ecr.us-east-1.amazonaws.com/mirror/nginx:1.0").Context()
+
+func TestEnsureRepositoryExistsFuncDefaults(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeECR{}
+	require.NoError(t, EnsureRepositoryExistsFunc(fake, nil)(testRepo))
+
+	assert.Equal(t, []string{"DescribeRepositories", "CreateRepository"}, fake.calls)
+	assert.Equal(t, "mirror/nginx", aws.ToString(fake.created.RepositoryName))
+	assert.True(t, fake.created.ImageScanningConfiguration.ScanOnPush)
+	assert.Nil(t, fake.created.EncryptionConfiguration)
+	assert.Empty(t, fake.created.Tags)
+}
+
+func TestEnsureRepositoryExistsFuncWithTemplate(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := LoadRepositoryTemplateFile(writeTemplate(t, testTemplate))
+	require.NoError(t, err)
+
+	fake := &fakeECR{}
+	require.NoError(t, EnsureRepositoryExistsFunc(fake, tmpl)(testRepo))
+
+	assert.Equal(
+		t,
+		[]string{"DescribeRepositories", "CreateRepository", "SetRepositoryPolicy", "PutLifecyclePolicy"},
+		fake.calls,
+	)
+	assert.False(t, fake.created.ImageScanningConfiguration.ScanOnPush)
+	assert.Equal(t, types.ImageTagMutabilityImmutable, fake.created.ImageTagMutability)
+	assert.Equal(t, types.EncryptionTypeKms, fake.created.EncryptionConfiguration.EncryptionType)
+	assert.Equal(
+		t, "arn:aws:kms:us-east-1:123456789:key/abc", aws.ToString(fake.created.EncryptionConfiguration.KmsKey),
+	)
+	assert.Equal(t, []types.Tag{
+		{Key: new("cost-center"), Value: new("1234")},
+		{Key: new("team"), Value: new("platform")},
+	}, fake.created.Tags)
+	assert.JSONEq(t, `{"repository":"policy"}`, fake.repositoryPolicy)
+	assert.JSONEq(t, `{"rules":[]}`, fake.lifecyclePolicy)
+}
+
+func TestEnsureRepositoryExistsFuncExistingRepository(t *testing.T) {
+	t.Parallel()
+
+	existing := map[string]types.Repository{
+		"mirror/nginx": {
+			RepositoryName:             new("mirror/nginx"),
+			RepositoryArn:              new("arn:aws:ecr:us-east-1:123456789:repository/mirror/nginx"),
+			ImageTagMutability:         types.ImageTagMutabilityMutable,
+			ImageScanningConfiguration: &types.ImageScanningConfiguration{ScanOnPush: true},
+		},
+	}
+
+	tmpl, err := LoadRepositoryTemplateFile(writeTemplate(t, testTemplate))
+	require.NoError(t, err)
+
+	fake := &fakeECR{repositories: existing}
+	require.NoError(t, EnsureRepositoryExistsFunc(fake, tmpl)(testRepo))
+	assert.Equal(t, []string{"DescribeRepositories"}, fake.calls, "existing repository must not be modified")
+
+	tmpl.ReconcileExisting = true
+	fake = &fakeECR{repositories: existing}
+	require.NoError(t, EnsureRepositoryExistsFunc(fake, tmpl)(testRepo))
+	assert.Equal(t, []string{
+		"DescribeRepositories",
+		"PutImageScanningConfiguration",
+		"PutImageTagMutability",
+		"TagResource",
+		"SetRepositoryPolicy",
+		"PutLifecyclePolicy",
+	}, fake.calls)
+	assert.False(t, *fake.scanOnPush)
+	assert.Equal(t, types.ImageTagMutabilityImmutable, fake.tagMutability)
+	assert.Len(t, fake.tags, 2)
+}
+
+func TestLoadRepositoryTemplateFileErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{{
+		name:    "invalid tag mutability",
+		content: "imageTagMutability: SOMETIMES\n",
+		wantErr: `invalid imageTagMutability "SOMETIMES"`,
+	}, {
+		name:    "invalid encryption type",
+		content: "encryption:\n  type: ROT13\n",
+		wantErr: `invalid encryption type "ROT13"`,
+	}, {
+		name:    "KMS key with AES256 encryption",
+		content: "encryption:\n  type: AES256\n  kmsKey: abc\n",
+		wantErr: "kmsKey cannot be specified with AES256 encryption",
+	}, {
+		name:    "unknown field",
+		content: "scanOnPull: true\n",
+		wantErr: "failed to parse ECR repository template",
+	}, {
+		name:    "missing policy file",
+		content: "repositoryPolicyFile: missing.json\n",
+		wantErr: "failed to read ECR repository policy",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := LoadRepositoryTemplateFile(writeTemplate(t, tt.content))
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRepositoryTemplateWithLifecyclePolicyFile(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := LoadRepositoryTemplateFile(writeTemplate(t, "lifecyclePolicyFile: lifecycle.json\n"))
+	require.NoError(t, err)
+	require.ErrorContains(
+		t, tmpl.WithLifecyclePolicyFile("other.json"), "lifecycle policy is specified both",
+	)
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/moby/moby/api/types/jsonstream"
+	"github.com/moby/moby/client"
+)
+
+// PodmanHost returns the address of the Podman API socket. This is the value of the CONTAINER_HOST environment
+// variable if set, the rootless Podman socket when running as a non-root user, or the rootful Podman socket.
+func PodmanHost() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" && os.Geteuid() != 0 {
+		return "unix://" + filepath.Join(runtimeDir, "podman", "podman.sock")
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
+// Loader loads images into the image store of a container engine that serves the Docker Engine API, such as
+// Docker or Podman.
+type Loader struct {
+	client *client.Client
+}
+
+// NewLoader creates a loader for the engine API at host. If host is empty, the host is read from the DOCKER_HOST
+// environment variable, falling back to the default Docker socket.
+func NewLoader(host string) (*Loader, error) {
+	opts := []client.Opt{client.FromEnv}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	c, err := client.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container engine client: %w", err)
+	}
+
+	return &Loader{client: c}, nil
+}
+
+// Close closes the connection to the container engine.
+func (l *Loader) Close() error {
+	return l.client.Close()
+}
+
+// Platform returns the platform of the container engine, which may differ from the platform mindthegap is running
+// on, e.g. when the engine runs in a virtual machine or on a remote host.
+func (l *Loader) Platform(ctx context.Context) (v1.Platform, error) {
+	version, err := l.client.ServerVersion(ctx, client.ServerVersionOptions{})
+	if err != nil {
+		return v1.Platform{}, fmt.Errorf("failed to read container engine version: %w", err)
+	}
+
+	platform := v1.Platform{OS: version.Os, Architecture: version.Arch}
+	if platform.Architecture == "arm" {
+		// The engine version only reports the Go architecture, so the machine hardware name of the host is used to
+		// pick the variant to avoid loading e.g. arm/v7 images on an arm/v6 host.
+		info, err := l.client.Info(ctx, client.InfoOptions{})
+		if err != nil {
+			return v1.Platform{}, fmt.Errorf("failed to read container engine info: %w", err)
+		}
+		platform.Variant = armVariant(info.Info.Architecture)
+	}
+
+	return platform, nil
+}
+
+// armVariant returns the variant of 32-bit arm platforms for the machine hardware name as reported by uname, e.g.
+// armv6l. Defaults to v7, as containerd does, if the name does not specify a supported variant.
+func armVariant(machine string) string {
+	switch {
+	case strings.HasPrefix(machine, "armv5"):
+		return "v5"
+	case strings.HasPrefix(machine, "armv6"):
+		return "v6"
+	default:
+		return "v7"
+	}
+}
+
+// LoadImage loads img into the container engine image store, tagged as tag. The image is streamed to the engine
+// as a docker archive, so no temporary files are written.
+func (l *Loader) LoadImage(ctx context.Context, tag name.Tag, img v1.Image) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tarball.Write(tag, img, pw))
+	}()
+
+	resp, err := l.client.ImageLoad(ctx, pr, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		_ = pr.CloseWithError(err)
+		return fmt.Errorf("failed to load %s into container engine: %w", tag, err)
+	}
+	defer resp.Close()
+
+	// Errors that occur while loading are reported in the response stream rather than the response status.
+	dec := json.NewDecoder(resp)
+	for {
+		var msg jsonstream.Message
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read container engine response loading %s: %w", tag, err)
+		}
+		if msg.Error != nil {
+			return fmt.Errorf("failed to load %s into container engine: %w", tag, msg.Error)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// fakeEngine is a stand-in for the parts of the Docker Engine API used to load images.
+type fakeEngine struct {
+	mu        sync.Mutex
+	loaded    map[string]v1.Hash
+	loadError string
+	// arch and machine are the Go architecture and machine hardware name of the engine host.
+	arch    string
+	machine string
+}
+
+func newFakeEngine(t *testing.T) (*fakeEngine, *httptest.Server) {
+	t.Helper()
+	e := &fakeEngine{loaded: map[string]v1.Hash{}, arch: "arm64", machine: "aarch64"}
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return e, srv
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Api-Version", "1.47")
+
+	switch p := apiVersionPrefix.ReplaceAllString(r.URL.Path, ""); {
+	case p == "/_ping":
+		_, _ = w.Write([]byte("OK"))
+	case r.Method == http.MethodGet && p == "/version":
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"Os": "linux", "Arch": e.arch, "ApiVersion": "1.47"})
+	case r.Method == http.MethodGet && p == "/info":
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"OSType": "linux", "Architecture": e.machine})
+	case r.Method == http.MethodPost && p == "/images/load":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.loadError != "" {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"errorDetail": map[string]any{"message": e.loadError},
+				"error":       e.loadError,
+			})
+			return
+		}
+
+		opener := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		manifest, err := tarball.LoadManifest(opener)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, desc := range manifest {
+			for _, repoTag := range desc.RepoTags {
+				tag, err := name.NewTag(repoTag)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				img, err := tarball.Image(opener, &tag)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				digest, err := img.ConfigName()
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				e.loaded[repoTag] = digest
+				_ = json.NewEncoder(w).Encode(map[string]any{"stream": "Loaded image: " + repoTag + "\n"})
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestLoader(t *testing.T, srv *httptest.Server) *Loader {
+	t.Helper()
+	l, err := NewLoader("tcp://" + strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func TestPlatform(t *testing.T) {
+	t.Parallel()
+	_, srv := newFakeEngine(t)
+
+	platform, err := newTestLoader(t, srv).Platform(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, v1.Platform{OS: "linux", Architecture: "arm64"}, platform)
+}
+
+func TestPlatformArmVariant(t *testing.T) {
+	t.Parallel()
+
+	for machine, want := range map[string]string{
+		"armv6l":  "v6",
+		"armv7l":  "v7",
+		"armv8l":  "v7",
+		"unknown": "v7",
+	} {
+		t.Run(machine, func(t *testing.T) {
+			t.Parallel()
+			e, srv := newFakeEngine(t)
+			e.mu.Lock()
+			e.arch, e.machine = "arm", machine
+			e.mu.Unlock()
+
+			platform, err := newTestLoader(t, srv).Platform(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, v1.Platform{OS: "linux", Architecture: "arm", Variant: want}, platform)
+		})
+	}
+}
+
+func TestLoadImage(t *testing.T) {
+	t.Parallel()
+	e, srv := newFakeEngine(t)
+
+	img, err := random.Image(64, 2)
+	require.NoError(t, err)
+	configDigest, err := img.ConfigName()
+	require.NoError(t, err)
+
+	tag, err := name.NewTag("example.com/team/app:v1")
+	require.NoError(t, err)
+	require.NoError(t, newTestLoader(t, srv).LoadImage(context.Background(), tag, img))
+
+	assert.Equal(t, map[string]v1.Hash{"example.com/team/app:v1": configDigest}, e.loaded)
+}
+
+func TestLoadImageError(t *testing.T) {
+	t.Parallel()
+	e, srv := newFakeEngine(t)
+	e.loadError = "no space left on device"
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	tag, err := name.NewTag("example.com/team/app:v1")
+	require.NoError(t, err)
+
+	err = newTestLoader(t, srv).LoadImage(context.Background(), tag, img)
+	require.ErrorContains(t, err, "no space left on device")
+}
+
+func TestPodmanHost(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "tcp://podman.example.com:8080")
+	assert.Equal(t, "tcp://podman.example.com:8080", PodmanHost())
+
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	assert.Equal(t, "unix:///run/podman/podman.sock", PodmanHost())
+}
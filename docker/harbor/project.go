@@ -0,0 +1,241 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package harbor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// Client is a minimal client for the Harbor v2 API.
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	authenticator authn.Authenticator
+}
+
+// NewClient returns a client for the Harbor API served at the registry address. scheme is either http or
+// https. Requests are authenticated with the credentials provided by authenticator: username and password are
+// sent as basic authentication, registry and identity tokens as bearer tokens (Harbor accepts OIDC ID tokens).
+func NewClient(scheme, registryAddress string, rt http.RoundTripper, authenticator authn.Authenticator) *Client {
+	return &Client{
+		baseURL:       fmt.Sprintf("%s://%s/api/v2.0", scheme, registryAddress),
+		httpClient:    &http.Client{Transport: rt},
+		authenticator: authenticator,
+	}
+}
+
+// IsHarbor returns true if the registry serves the Harbor API. No credentials are sent as the registry is not
+// known to be Harbor yet.
+func (c *Client) IsHarbor(ctx context.Context) (bool, error) {
+	resp, err := c.request(ctx, http.MethodGet, "/systeminfo", nil, false)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+
+	var systemInfo struct {
+		HarborVersion string `json:"harbor_version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&systemInfo); err != nil {
+		// Not a JSON response so definitely not Harbor.
+		return false, nil //nolint:nilerr // Error means not Harbor.
+	}
+	return systemInfo.HarborVersion != "", nil
+}
+
+// ProjectExists returns true if the project exists.
+func (c *Client) ProjectExists(ctx context.Context, project string) (bool, error) {
+	resp, err := c.do(ctx, http.MethodHead, "/projects?project_name="+url.QueryEscape(project), nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, unexpectedResponseError(resp)
+	}
+}
+
+// ProjectOptions configures newly created projects.
+type ProjectOptions struct {
+	// Public makes the project readable without authentication.
+	Public bool
+	// StorageQuotaBytes is the storage quota of the project in bytes. 0 means unlimited.
+	StorageQuotaBytes int64
+	// AutoScan automatically scans images for vulnerabilities when they are pushed.
+	AutoScan bool
+}
+
+// CreateProject creates the project. It is not an error if the project already exists.
+func (c *Client) CreateProject(ctx context.Context, project string, opts ProjectOptions) error {
+	storageLimit := opts.StorageQuotaBytes
+	if storageLimit <= 0 {
+		storageLimit = -1
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"project_name":  project,
+		"storage_limit": storageLimit,
+		"metadata": map[string]string{
+			"public":    strconv.FormatBool(opts.Public),
+			"auto_scan": strconv.FormatBool(opts.AutoScan),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Harbor project: %w", err)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/projects", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusConflict:
+		return nil
+	default:
+		return unexpectedResponseError(resp)
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	return c.request(ctx, method, path, body, true)
+}
+
+func (c *Client) request(
+	ctx context.Context, method, path string, body []byte, authenticate bool,
+) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authenticate {
+		if err := c.setAuthorization(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Harbor API: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) setAuthorization(req *http.Request) error {
+	if c.authenticator == nil {
+		return nil
+	}
+	authConfig, err := c.authenticator.Authorization()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials for Harbor API: %w", err)
+	}
+	switch {
+	case authConfig.Username != "" || authConfig.Password != "":
+		req.SetBasicAuth(authConfig.Username, authConfig.Password)
+	case authConfig.RegistryToken != "":
+		req.Header.Set("Authorization", "Bearer "+authConfig.RegistryToken)
+	case authConfig.IdentityToken != "":
+		req.Header.Set("Authorization", "Bearer "+authConfig.IdentityToken)
+	}
+	return nil
+}
+
+func unexpectedResponseError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf(
+		"unexpected response from Harbor API for %s %s: %s: %s",
+		resp.Request.Method, resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(b)),
+	)
+}
+
+// ProjectForRepository returns the Harbor project that the repository is in, i.e. the first path component of
+// the repository.
+func ProjectForRepository(repository name.Repository) (string, error) {
+	project, _, found := strings.Cut(repository.RepositoryStr(), "/")
+	if !found {
+		return "", fmt.Errorf(
+			"repository %q is not in a Harbor project: specify a project as a path in the registry URI",
+			repository.RepositoryStr(),
+		)
+	}
+	return project, nil
+}
+
+// EnsureProjectExistsFunc returns a function that creates the Harbor project of the destination repository with
+// opts if it does not exist yet. Projects are only checked once, so pushing many repositories into the same project
+// only calls the Harbor API once. ctx is used for all calls to the Harbor API.
+func EnsureProjectExistsFunc(ctx context.Context, client *Client, opts ProjectOptions) func(
+	destRepositoryName name.Repository, _ ...string,
+) error {
+	// Remember which projects are known to exist. Each project has its own lock so that concurrent pushes into
+	// different projects do not wait for each other's Harbor API calls. Failed checks are not remembered so that
+	// they are retried by later pushes into the project.
+	type projectState struct {
+		mu     sync.Mutex
+		exists bool
+	}
+	var projects sync.Map
+
+	return func(
+		destRepositoryName name.Repository, _ ...string,
+	) error {
+		project, err := ProjectForRepository(destRepositoryName)
+		if err != nil {
+			return err
+		}
+
+		v, _ := projects.LoadOrStore(project, &projectState{})
+		state := v.(*projectState)
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		if state.exists {
+			return nil
+		}
+
+		exists, err := client.ProjectExists(ctx, project)
+		if err != nil {
+			return fmt.Errorf("failed to check if Harbor project %q exists: %w", project, err)
+		}
+		if !exists {
+			if err := client.CreateProject(ctx, project, opts); err != nil {
+				return fmt.Errorf("failed to create Harbor project %q: %w", project, err)
+			}
+		}
+
+		state.exists = true
+		return nil
+	}
+}
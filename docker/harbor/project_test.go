@@ -0,0 +1,184 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHarbor is a stand-in for the parts of the Harbor API used to manage projects.
+type fakeHarbor struct {
+	mu       sync.Mutex
+	projects map[string]map[string]any
+	creates  int
+}
+
+func newFakeHarbor(t *testing.T, existingProjects ...string) (*fakeHarbor, *httptest.Server) {
+	t.Helper()
+	h := &fakeHarbor{projects: map[string]map[string]any{}}
+	for _, p := range existingProjects {
+		h.projects[p] = nil
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return h, srv
+}
+
+func (h *fakeHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, pass, basicAuth := r.BasicAuth()
+	authenticated := basicAuth && user == "admin" && pass == "Harbor12345" ||
+		r.Header.Get("Authorization") == "Bearer id-token"
+	if r.URL.Path == "/api/v2.0/systeminfo" {
+		if r.Header.Get("Authorization") != "" {
+			// Credentials must not be sent before the registry is known to be Harbor.
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	} else if !authenticated {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2.0/systeminfo":
+		_, _ = w.Write([]byte(`{"harbor_version":"v2.11.0"}`))
+	case r.Method == http.MethodHead && r.URL.Path == "/api/v2.0/projects":
+		if _, ok := h.projects[r.URL.Query().Get("project_name")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2.0/projects":
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		projectName := req["project_name"].(string)
+		if _, ok := h.projects[projectName]; ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		h.creates++
+		h.projects[projectName] = req
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestClient(srv *httptest.Server) *Client {
+	return newTestClientWithAuth(srv, authn.FromConfig(authn.AuthConfig{Username: "admin", Password: "Harbor12345"}))
+}
+
+func newTestClientWithAuth(srv *httptest.Server, authenticator authn.Authenticator) *Client {
+	return NewClient("http", strings.TrimPrefix(srv.URL, "http://"), srv.Client().Transport, authenticator)
+}
+
+func TestIsHarbor(t *testing.T) {
+	t.Parallel()
+
+	_, harborSrv := newFakeHarbor(t)
+	isHarbor, err := newTestClient(harborSrv).IsHarbor(context.Background())
+	require.NoError(t, err)
+	assert.True(t, isHarbor)
+
+	otherSrv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(otherSrv.Close)
+	isHarbor, err = newTestClient(otherSrv).IsHarbor(context.Background())
+	require.NoError(t, err)
+	assert.False(t, isHarbor)
+}
+
+func TestEnsureProjectExistsFunc(t *testing.T) {
+	t.Parallel()
+
+	h, srv := newFakeHarbor(t, "existing")
+	ensureFn := EnsureProjectExistsFunc(
+		context.Background(),
+		newTestClient(srv),
+		ProjectOptions{Public: true, StorageQuotaBytes: 10 << 30, AutoScan: true},
+	)
+
+	for _, repo := range []string{"existing/nginx", "mirror/library/nginx", "mirror/library/busybox"} {
+		destRepo, err := name.NewRepository("harbor.example.com/" + repo)
+		require.NoError(t, err)
+		require.NoError(t, ensureFn(destRepo))
+	}
+
+	assert.Equal(t, 1, h.creates, "project should only be created once")
+	assert.Equal(t, map[string]any{
+		"project_name":  "mirror",
+		"storage_limit": float64(10 << 30),
+		"metadata":      map[string]any{"public": "true", "auto_scan": "true"},
+	}, h.projects["mirror"])
+}
+
+func TestEnsureProjectExistsFuncDefaults(t *testing.T) {
+	t.Parallel()
+
+	h, srv := newFakeHarbor(t)
+	ensureFn := EnsureProjectExistsFunc(context.Background(), newTestClient(srv), ProjectOptions{})
+	require.NoError(t, ensureFn(name.MustParseReference("harbor.example.com/mirror/nginx").Context()))
+
+	assert.Equal(t, map[string]any{
+		"project_name":  "mirror",
+		"storage_limit": float64(-1),
+		"metadata":      map[string]any{"public": "false", "auto_scan": "false"},
+	}, h.projects["mirror"])
+}
+
+func TestEnsureProjectExistsFuncErrors(t *testing.T) {
+	t.Parallel()
+
+	_, srv := newFakeHarbor(t)
+
+	ensureFn := EnsureProjectExistsFunc(context.Background(), newTestClient(srv), ProjectOptions{})
+	require.ErrorContains(
+		t,
+		ensureFn(name.MustParseReference("harbor.example.com/nginx").Context()),
+		"is not in a Harbor project",
+	)
+
+	require.ErrorContains(
+		t,
+		EnsureProjectExistsFunc(context.Background(), newTestClientWithAuth(srv, authn.Anonymous), ProjectOptions{})(
+			name.MustParseReference("harbor.example.com/mirror/nginx").Context(),
+		),
+		"401 Unauthorized",
+	)
+}
+
+func TestEnsureProjectExistsFuncWithIdentityToken(t *testing.T) {
+	t.Parallel()
+
+	h, srv := newFakeHarbor(t)
+	client := newTestClientWithAuth(srv, authn.FromConfig(authn.AuthConfig{IdentityToken: "id-token"}))
+	ensureFn := EnsureProjectExistsFunc(context.Background(), client, ProjectOptions{})
+	require.NoError(t, ensureFn(name.MustParseReference("harbor.example.com/mirror/nginx").Context()))
+	assert.Equal(t, 1, h.creates)
+}
+
+func TestEnsureProjectExistsFuncCanceled(t *testing.T) {
+	t.Parallel()
+
+	h, srv := newFakeHarbor(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ensureFn := EnsureProjectExistsFunc(ctx, newTestClient(srv), ProjectOptions{})
+	require.ErrorIs(t, ensureFn(name.MustParseReference("harbor.example.com/mirror/nginx").Context()), context.Canceled)
+	assert.Zero(t, h.creates)
+}
@@ -0,0 +1,311 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// GarbageCollectResult reports what GarbageCollect removed from the registry storage.
+type GarbageCollectResult struct {
+	BlobsRemoved   int
+	TagsRemoved    int
+	BytesReclaimed int64
+}
+
+// GarbageCollect removes everything from the registry filesystem storage in rootDir that is not referenced by the
+// tags to keep, keyed by repository. Tags that are not kept are removed, as are manifest revisions and layer links
+// not reachable from a kept tag, and finally all blobs no longer reachable from any kept tag.
+func GarbageCollect(rootDir string, keepTags map[string][]string) (GarbageCollectResult, error) {
+	gc := &garbageCollector{
+		storageDir: filepath.Join(rootDir, "docker", "registry", "v2"),
+		marked:     map[v1.Hash]struct{}{},
+	}
+
+	repositories, err := gc.repositories()
+	if err != nil {
+		return GarbageCollectResult{}, err
+	}
+	for _, repository := range repositories {
+		if err := gc.collectRepository(repository, keepTags[repository]); err != nil {
+			return GarbageCollectResult{}, fmt.Errorf("failed to garbage collect repository %s: %w", repository, err)
+		}
+	}
+
+	if err := gc.sweepBlobs(); err != nil {
+		return GarbageCollectResult{}, err
+	}
+
+	return gc.result, nil
+}
+
+type garbageCollector struct {
+	storageDir string
+	// marked holds the digests of all blobs reachable from the tags kept in any repository.
+	marked map[v1.Hash]struct{}
+	result GarbageCollectResult
+}
+
+// repositories returns the names of all repositories in the storage.
+func (gc *garbageCollector) repositories() ([]string, error) {
+	reposDir := filepath.Join(gc.storageDir, "repositories")
+	var repositories []string
+	err := filepath.WalkDir(reposDir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == reposDir {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || !strings.HasPrefix(d.Name(), "_") {
+			return nil
+		}
+		repository, err := filepath.Rel(reposDir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		repository = filepath.ToSlash(repository)
+		if !slices.Contains(repositories, repository) {
+			repositories = append(repositories, repository)
+		}
+		return fs.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	return repositories, nil
+}
+
+func (gc *garbageCollector) collectRepository(repository string, keepTags []string) error {
+	repoDir := filepath.Join(gc.storageDir, "repositories", filepath.FromSlash(repository))
+	marked := map[v1.Hash]struct{}{}
+
+	tagsDir := filepath.Join(repoDir, "_manifests", "tags")
+	tags, err := readDirNames(tagsDir)
+	if err != nil {
+		return err
+	}
+	keptTags := 0
+	for _, tag := range tags {
+		tagDir := filepath.Join(tagsDir, tag)
+		if !slices.Contains(keepTags, tag) {
+			if err := gc.remove(tagDir); err != nil {
+				return err
+			}
+			gc.result.TagsRemoved++
+			continue
+		}
+		keptTags++
+
+		current, err := readLink(filepath.Join(tagDir, "current", "link"))
+		if err != nil {
+			return fmt.Errorf("failed to read tag %s: %w", tag, err)
+		}
+		if err := gc.markManifest(current, marked); err != nil {
+			return fmt.Errorf("failed to read manifest of tag %s: %w", tag, err)
+		}
+		// The tag index records all digests the tag ever pointed to.
+		if err := gc.sweepLinks(filepath.Join(tagDir, "index"), map[v1.Hash]struct{}{current: {}}); err != nil {
+			return err
+		}
+	}
+
+	if keptTags == 0 {
+		// Only remove the repository itself as other repositories can be nested in its directory.
+		for _, dir := range []string{"_manifests", "_layers", "_uploads"} {
+			if err := gc.remove(filepath.Join(repoDir, dir)); err != nil {
+				return err
+			}
+		}
+		_ = os.Remove(repoDir)
+		return nil
+	}
+
+	if err := gc.sweepLinks(filepath.Join(repoDir, "_manifests", "revisions"), marked); err != nil {
+		return err
+	}
+	if err := gc.sweepLinks(filepath.Join(repoDir, "_layers"), marked); err != nil {
+		return err
+	}
+	// Incomplete uploads are never referenced by manifests.
+	if err := gc.remove(filepath.Join(repoDir, "_uploads")); err != nil {
+		return err
+	}
+
+	for digest := range marked {
+		gc.marked[digest] = struct{}{}
+	}
+	return nil
+}
+
+// manifestReferences holds the references to other blobs of all supported manifest and index media types.
+type manifestReferences struct {
+	Config    *v1.Descriptor  `json:"config"`
+	Layers    []v1.Descriptor `json:"layers"`
+	Manifests []v1.Descriptor `json:"manifests"`
+}
+
+// markManifest marks the manifest with the digest and all blobs it references, including the manifests of an index.
+func (gc *garbageCollector) markManifest(digest v1.Hash, marked map[v1.Hash]struct{}) error {
+	if _, ok := marked[digest]; ok {
+		return nil
+	}
+	marked[digest] = struct{}{}
+
+	content, err := os.ReadFile(gc.blobPath(digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		// Indexes can reference manifests for platforms that are not included in the bundle.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read manifest %s: %w", digest, err)
+	}
+	var refs manifestReferences
+	if err := json.Unmarshal(content, &refs); err != nil {
+		return fmt.Errorf("failed to parse manifest %s: %w", digest, err)
+	}
+
+	if refs.Config != nil {
+		marked[refs.Config.Digest] = struct{}{}
+	}
+	for _, l := range refs.Layers {
+		marked[l.Digest] = struct{}{}
+	}
+	for _, m := range refs.Manifests {
+		if err := gc.markManifest(m.Digest, marked); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweepLinks removes the links in dir, stored as <algorithm>/<hex>/link, to digests that are not marked.
+func (gc *garbageCollector) sweepLinks(dir string, marked map[v1.Hash]struct{}) error {
+	algorithms, err := readDirNames(dir)
+	if err != nil {
+		return err
+	}
+	for _, algorithm := range algorithms {
+		hexes, err := readDirNames(filepath.Join(dir, algorithm))
+		if err != nil {
+			return err
+		}
+		for _, hex := range hexes {
+			if _, ok := marked[v1.Hash{Algorithm: algorithm, Hex: hex}]; ok {
+				continue
+			}
+			if err := gc.remove(filepath.Join(dir, algorithm, hex)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sweepBlobs removes all blobs, stored as blobs/<algorithm>/<first two hex characters>/<hex>/data, that are not
+// marked.
+func (gc *garbageCollector) sweepBlobs() error {
+	blobsDir := filepath.Join(gc.storageDir, "blobs")
+	algorithms, err := readDirNames(blobsDir)
+	if err != nil {
+		return err
+	}
+	for _, algorithm := range algorithms {
+		prefixes, err := readDirNames(filepath.Join(blobsDir, algorithm))
+		if err != nil {
+			return err
+		}
+		for _, prefix := range prefixes {
+			prefixDir := filepath.Join(blobsDir, algorithm, prefix)
+			hexes, err := readDirNames(prefixDir)
+			if err != nil {
+				return err
+			}
+			removed := 0
+			for _, hex := range hexes {
+				if _, ok := gc.marked[v1.Hash{Algorithm: algorithm, Hex: hex}]; ok {
+					continue
+				}
+				if err := gc.remove(filepath.Join(prefixDir, hex)); err != nil {
+					return err
+				}
+				gc.result.BlobsRemoved++
+				removed++
+			}
+			if removed == len(hexes) {
+				if err := os.Remove(prefixDir); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", prefixDir, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (gc *garbageCollector) blobPath(digest v1.Hash) string {
+	if len(digest.Hex) < 2 {
+		return filepath.Join(gc.storageDir, "blobs", digest.Algorithm, digest.Hex, "data")
+	}
+	return filepath.Join(gc.storageDir, "blobs", digest.Algorithm, digest.Hex[:2], digest.Hex, "data")
+}
+
+// remove removes path and everything it contains, recording the size of all removed files.
+func (gc *garbageCollector) remove(path string) error {
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			gc.result.BytesReclaimed += fi.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to determine size of %s: %w", path, err)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+func readLink(path string) (v1.Hash, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	return v1.NewHash(strings.TrimSpace(string(content)))
+}
+
+// readDirNames returns the names of the entries of dir, or no names if dir does not exist.
+func readDirNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
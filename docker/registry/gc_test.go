@@ -0,0 +1,275 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startTestRegistry(t *testing.T, dir string) *Registry {
+	t.Helper()
+
+	reg, err := NewRegistry(Config{Storage: FilesystemStorage(dir)})
+	require.NoError(t, err)
+	go func() {
+		assert.NoError(t, reg.ListenAndServe(logr.Discard()))
+	}()
+	t.Cleanup(func() { _ = reg.Shutdown(context.Background()) })
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", reg.Address())
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	return reg
+}
+
+func parseTag(t *testing.T, reg *Registry, ref string) name.Tag {
+	t.Helper()
+	tag, err := name.NewTag(reg.Address() + "/" + ref)
+	require.NoError(t, err)
+	return tag
+}
+
+func blobExists(t *testing.T, dir string, digest v1.Hash) bool {
+	t.Helper()
+	_, err := os.Stat(filepath.Join(
+		dir, "docker", "registry", "v2", "blobs", digest.Algorithm, digest.Hex[:2], digest.Hex, "data",
+	))
+	if os.IsNotExist(err) {
+		return false
+	}
+	require.NoError(t, err)
+	return true
+}
+
+func layerDigests(t *testing.T, img v1.Image) []v1.Hash {
+	t.Helper()
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	digests := make([]v1.Hash, 0, len(layers))
+	for _, l := range layers {
+		digest, err := l.Digest()
+		require.NoError(t, err)
+		digests = append(digests, digest)
+	}
+	return digests
+}
+
+func newTestImage(t *testing.T) v1.Image {
+	t.Helper()
+	img, err := random.Image(64, 2)
+	require.NoError(t, err)
+	return img
+}
+
+func writeImage(t *testing.T, reg *Registry, ref string, img v1.Image) {
+	t.Helper()
+	require.NoError(t, remote.Write(parseTag(t, reg, ref), img))
+}
+
+// requirePullable checks that the image tagged ref can be pulled completely and has the expected digest.
+func requirePullable(t *testing.T, reg *Registry, ref string, img v1.Image) {
+	t.Helper()
+	pulled, err := remote.Image(parseTag(t, reg, ref))
+	require.NoError(t, err, ref)
+	require.NoError(t, validateImage(pulled), ref)
+	wantDigest, err := img.Digest()
+	require.NoError(t, err)
+	gotDigest, err := pulled.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, wantDigest, gotDigest, ref)
+}
+
+func repositoryDir(dir, repository string) string {
+	return filepath.Join(dir, "docker", "registry", "v2", "repositories", filepath.FromSlash(repository))
+}
+
+func TestGarbageCollectRetaggedTag(t *testing.T) {
+	dir := t.TempDir()
+	reg := startTestRegistry(t, dir)
+
+	// Pulling a tag again whose content changed leaves the previous manifest and config unreferenced, while layers
+	// shared with the new content are still referenced.
+	previous := newTestImage(t)
+	extraLayer, err := random.Layer(64, types.DockerLayer)
+	require.NoError(t, err)
+	current, err := mutate.AppendLayers(previous, extraLayer)
+	require.NoError(t, err)
+	writeImage(t, reg, "app:v1", previous)
+	writeImage(t, reg, "app:v1", current)
+	require.NoError(t, reg.Shutdown(context.Background()))
+
+	result, err := GarbageCollect(dir, map[string][]string{"app": {"v1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.TagsRemoved)
+	// The previous manifest and config.
+	assert.Equal(t, 2, result.BlobsRemoved)
+	assert.Positive(t, result.BytesReclaimed)
+
+	previousDigest, err := previous.Digest()
+	require.NoError(t, err)
+	assert.False(t, blobExists(t, dir, previousDigest))
+	for _, digest := range layerDigests(t, current) {
+		assert.True(t, blobExists(t, dir, digest))
+	}
+	// The tag index and manifest revisions no longer reference the previous content.
+	for _, linksDir := range []string{
+		filepath.Join(repositoryDir(dir, "app"), "_manifests", "tags", "v1", "index"),
+		filepath.Join(repositoryDir(dir, "app"), "_manifests", "revisions"),
+	} {
+		assert.NoDirExists(t, filepath.Join(linksDir, previousDigest.Algorithm, previousDigest.Hex))
+	}
+
+	reg = startTestRegistry(t, dir)
+	requirePullable(t, reg, "app:v1", current)
+}
+
+func TestGarbageCollectUntaggedRepository(t *testing.T) {
+	dir := t.TempDir()
+	reg := startTestRegistry(t, dir)
+
+	kept, untagged := newTestImage(t), newTestImage(t)
+	writeImage(t, reg, "app:v1", kept)
+	writeImage(t, reg, "other:v1", untagged)
+	require.NoError(t, reg.Shutdown(context.Background()))
+
+	result, err := GarbageCollect(dir, map[string][]string{"app": {"v1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.TagsRemoved)
+	// The untagged manifest, config and two layers.
+	assert.Equal(t, 4, result.BlobsRemoved)
+	for _, digest := range layerDigests(t, untagged) {
+		assert.False(t, blobExists(t, dir, digest))
+	}
+	assert.NoDirExists(t, repositoryDir(dir, "other"))
+
+	reg = startTestRegistry(t, dir)
+	requirePullable(t, reg, "app:v1", kept)
+	_, err = remote.Head(parseTag(t, reg, "other:v1"))
+	require.Error(t, err)
+}
+
+func TestGarbageCollectNestedRepositories(t *testing.T) {
+	tests := []struct {
+		name     string
+		keepTags map[string][]string
+		kept     string
+		removed  string
+	}{{
+		name:     "keep nested repository",
+		keepTags: map[string][]string{"app/nested": {"v1"}},
+		kept:     "app/nested",
+		removed:  "app",
+	}, {
+		name:     "keep parent repository",
+		keepTags: map[string][]string{"app": {"v1"}},
+		kept:     "app",
+		removed:  "app/nested",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			reg := startTestRegistry(t, dir)
+
+			imgs := map[string]v1.Image{"app": newTestImage(t), "app/nested": newTestImage(t)}
+			for repository, img := range imgs {
+				writeImage(t, reg, repository+":v1", img)
+			}
+			require.NoError(t, reg.Shutdown(context.Background()))
+
+			result, err := GarbageCollect(dir, tt.keepTags)
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.TagsRemoved)
+			assert.Equal(t, 4, result.BlobsRemoved)
+
+			for _, digest := range layerDigests(t, imgs[tt.removed]) {
+				assert.False(t, blobExists(t, dir, digest))
+			}
+			assert.NoDirExists(t, filepath.Join(repositoryDir(dir, tt.removed), "_manifests"))
+			assert.DirExists(t, filepath.Join(repositoryDir(dir, tt.kept), "_manifests"))
+
+			reg = startTestRegistry(t, dir)
+			requirePullable(t, reg, tt.kept+":v1", imgs[tt.kept])
+			_, err = remote.Head(parseTag(t, reg, tt.removed+":v1"))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGarbageCollectIndexWithAbsentPlatforms(t *testing.T) {
+	dir := t.TempDir()
+	reg := startTestRegistry(t, dir)
+
+	idx, err := random.Index(64, 1, 3)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(parseTag(t, reg, "app:v1"), idx))
+	require.NoError(t, reg.Shutdown(context.Background()))
+
+	// Bundles only contain the requested platforms, so indexes can reference manifests that are not in the bundle.
+	manifest, err := idx.IndexManifest()
+	require.NoError(t, err)
+	absent := manifest.Manifests[0].Digest
+	absentImage, err := idx.Image(absent)
+	require.NoError(t, err)
+	require.NoError(t, os.RemoveAll(filepath.Join(
+		dir, "docker", "registry", "v2", "blobs", absent.Algorithm, absent.Hex[:2], absent.Hex,
+	)))
+
+	result, err := GarbageCollect(dir, map[string][]string{"app": {"v1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.TagsRemoved)
+	// The config and layer of the absent manifest are no longer referenced.
+	assert.Equal(t, 2, result.BlobsRemoved)
+	for _, digest := range layerDigests(t, absentImage) {
+		assert.False(t, blobExists(t, dir, digest))
+	}
+
+	reg = startTestRegistry(t, dir)
+	pulledIdx, err := remote.Index(parseTag(t, reg, "app:v1"))
+	require.NoError(t, err)
+	for _, desc := range manifest.Manifests[1:] {
+		img, err := pulledIdx.Image(desc.Digest)
+		require.NoError(t, err)
+		require.NoError(t, validateImage(img))
+	}
+}
+
+// validateImage reads all layers of the image completely, which verifies them against their digests.
+func validateImage(img v1.Image) error {
+	layers, err := img.Layers()
+	if err != nil {
+		return err
+	}
+	for _, l := range layers {
+		rc, err := l.Compressed()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
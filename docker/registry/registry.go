@@ -9,12 +9,14 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io/fs"
 	"math"
 	"net/http"
 	"strings"
 	"text/template"
 	"time"
 
+	"filippo.io/age"
 	"github.com/distribution/distribution/v3/configuration"
 	"github.com/distribution/distribution/v3/registry/handlers"
 	_ "github.com/distribution/distribution/v3/registry/storage/driver/filesystem"
@@ -24,6 +26,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
 
+	"github.com/mesosphere/mindthegap/archive"
 	_ "github.com/mesosphere/mindthegap/docker/registry/storage/driver/archive"
 )
 
@@ -47,6 +50,7 @@ type Storage struct {
 	Path               string
 	AlwaysReadOnly     bool
 	RepositoriesPrefix string
+	DecryptionKeyFiles string
 }
 
 func FilesystemStorage(rootDir string) Storage {
@@ -56,9 +60,38 @@ func FilesystemStorage(rootDir string) Storage {
 	}
 }
 
-func ArchiveStorage(repositoryPrefix string, bundles ...string) (Storage, error) {
+// ArchiveStorage returns the storage serving the contents of the bundles. Encrypted bundles are decrypted with the
+// keys read from decryptionKeyFiles.
+func ArchiveStorage(repositoryPrefix string, decryptionKeyFiles []string, bundles ...string) (Storage, error) {
+	var identities []age.Identity
 	paths := make([]string, 0, len(bundles))
 	for _, bundle := range bundles {
+		// Bundles that do not exist are reported when the storage driver opens them.
+		encrypted, err := archive.IsEncrypted(bundle)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return Storage{}, err
+		}
+		if encrypted {
+			if len(decryptionKeyFiles) == 0 {
+				return Storage{}, fmt.Errorf("bundle %s is encrypted: decryption keys are required", bundle)
+			}
+			if identities == nil {
+				identities, err = archive.ParseDecryptionKeys(decryptionKeyFiles)
+				if err != nil {
+					return Storage{}, err
+				}
+			}
+			// Check that the bundle can be decrypted up front as the registry fails to start otherwise. The format
+			// of encrypted bundles is identified once decrypted by the storage driver.
+			decrypted, err := archive.OpenDecrypted(bundle, identities...)
+			if err != nil {
+				return Storage{}, err
+			}
+			_ = decrypted.Close()
+			paths = append(paths, fmt.Sprintf("%q", bundle))
+			continue
+		}
+
 		archiver, _, err := archives.Identify(context.Background(), bundle, nil)
 		if err != nil {
 			return Storage{}, fmt.Errorf(
@@ -76,12 +109,21 @@ func ArchiveStorage(repositoryPrefix string, bundles ...string) (Storage, error)
 		paths = append(paths, fmt.Sprintf("%q", bundle))
 	}
 
-	return Storage{
+	storage := Storage{
 		Type:               storageTypeArchive,
 		Path:               "[" + strings.Join(paths, ",") + "]",
 		AlwaysReadOnly:     true,
 		RepositoriesPrefix: repositoryPrefix,
-	}, nil
+	}
+	if len(decryptionKeyFiles) > 0 {
+		keyFiles := make([]string, 0, len(decryptionKeyFiles))
+		for _, keyFile := range decryptionKeyFiles {
+			keyFiles = append(keyFiles, fmt.Sprintf("%q", keyFile))
+		}
+		storage.DecryptionKeyFiles = "[" + strings.Join(keyFiles, ",") + "]"
+	}
+
+	return storage, nil
 }
 
 type TLS struct {
@@ -118,6 +160,9 @@ storage:
     {{- with .RepositoriesPrefix }}
     repositoriesPrefix: {{ . }}
     {{- end }}
+    {{- with .DecryptionKeyFiles }}
+    decryptionKeyFiles: {{ . }}
+    {{- end }}
   {{- end }}
   {{- end }}
   maintenance:
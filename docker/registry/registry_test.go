@@ -105,7 +105,7 @@ func Test_registryConfiguration_withTLS(t *testing.T) {
 
 func Test_registryConfiguration_archive(t *testing.T) {
 	t.Parallel()
-	storage, err := ArchiveStorage("", "/tmp/1.tar", "/some/other/path/2.tar")
+	storage, err := ArchiveStorage("", nil, "/tmp/1.tar", "/some/other/path/2.tar")
 	require.NoError(t, err)
 	c := Config{
 		Storage:  storage,
@@ -121,6 +121,6 @@ func Test_registryConfiguration_archive(t *testing.T) {
 
 func Test_registryConfiguration_archiveDisallowsCompressedArchives(t *testing.T) {
 	t.Parallel()
-	_, err := ArchiveStorage("", "/tmp/1.tar", "/some/other/path/2.tar.gz")
+	_, err := ArchiveStorage("", nil, "/tmp/1.tar", "/some/other/path/2.tar.gz")
 	require.ErrorContains(t, err, "compressed tar archives (.tar.gz) are not supported")
 }
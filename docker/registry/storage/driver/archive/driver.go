@@ -19,6 +19,8 @@ import (
 	"github.com/distribution/distribution/v3/registry/storage/driver/base"
 	"github.com/distribution/distribution/v3/registry/storage/driver/factory"
 	"github.com/mholt/archives"
+
+	bundlearchive "github.com/mesosphere/mindthegap/archive"
 )
 
 const (
@@ -38,6 +40,7 @@ const (
 type DriverParameters struct {
 	Archives           []string
 	RepositoriesPrefix string
+	DecryptionKeyFiles []string
 	MaxThreads         uint64
 }
 
@@ -75,6 +78,7 @@ type Driver struct {
 // FromParameters constructs a new Driver with a given parameters map
 // Optional Parameters:
 // - archives
+// - decryptionKeyFiles
 // - maxthreads.
 func FromParameters(ctx context.Context, parameters map[string]any) (*Driver, error) {
 	params, err := fromParametersImpl(parameters)
@@ -90,23 +94,22 @@ func fromParametersImpl(parameters map[string]any) (*DriverParameters, error) {
 		return nil, errors.New("archive config is required")
 	}
 
-	archivesIface, ok := archivesParam.([]any)
-	if !ok {
-		return nil, errors.New("archives  config must be a string array")
-	}
-
-	archiveBundles := make([]string, 0, len(archivesIface))
-	for _, archive := range archivesIface {
-		archiveStr, ok := archive.(string)
-		if !ok {
-			return nil, errors.New("archives config must be a string array")
-		}
-		archiveBundles = append(archiveBundles, archiveStr)
+	archiveBundles, err := stringArrayParameter(archivesParam)
+	if err != nil {
+		return nil, fmt.Errorf("archives config error: %w", err)
 	}
 	if len(archiveBundles) == 0 {
 		return nil, errors.New("archives config is required")
 	}
 
+	var decryptionKeyFiles []string
+	if decryptionKeyFilesParam, ok := parameters["decryptionKeyFiles"]; ok && decryptionKeyFilesParam != nil {
+		decryptionKeyFiles, err = stringArrayParameter(decryptionKeyFilesParam)
+		if err != nil {
+			return nil, fmt.Errorf("decryptionKeyFiles config error: %w", err)
+		}
+	}
+
 	maxThreads, err := base.GetLimitFromParameter(
 		parameters["maxthreads"],
 		minThreads,
@@ -126,16 +129,55 @@ func fromParametersImpl(parameters map[string]any) (*DriverParameters, error) {
 		Archives:           archiveBundles,
 		MaxThreads:         maxThreads,
 		RepositoriesPrefix: repositoriesPrefix,
+		DecryptionKeyFiles: decryptionKeyFiles,
 	}
 	return params, nil
 }
 
+func stringArrayParameter(param any) ([]string, error) {
+	paramIface, ok := param.([]any)
+	if !ok {
+		return nil, errors.New("must be a string array")
+	}
+
+	strs := make([]string, 0, len(paramIface))
+	for _, v := range paramIface {
+		str, ok := v.(string)
+		if !ok {
+			return nil, errors.New("must be a string array")
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
 // New constructs a new Driver with a given archives.
 func New(ctx context.Context, params DriverParameters) (*Driver, error) {
 	archiveFileSystems := make([]fs.FS, 0, len(params.Archives))
 
+	identities, err := bundlearchive.ParseDecryptionKeys(params.DecryptionKeyFiles)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, archive := range params.Archives {
-		fsys, err := archives.FileSystem(ctx, archive, nil)
+		encrypted, err := bundlearchive.IsEncrypted(archive)
+		if err != nil {
+			return nil, err
+		}
+
+		var fsys fs.FS
+		if encrypted {
+			// The decrypted archive is kept open for the lifetime of the driver.
+			var decrypted *bundlearchive.DecryptedFile
+			decrypted, err = bundlearchive.OpenDecrypted(archive, identities...)
+			if err != nil {
+				return nil, err
+			}
+			fsys, err = archives.FileSystem(ctx, "", decrypted)
+		} else {
+			fsys, err = archives.FileSystem(ctx, archive, nil)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to open archive %s as filesystem: %w", archive, err)
 		}
@@ -201,10 +243,27 @@ func (d *driver) Reader(ctx context.Context, fPath string, offset int64) (io.Rea
 	)
 	fPath = strings.TrimPrefix(fPath, "/")
 
-	var err error
+	file, err := d.open(fPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Files too large for a single part of a split bundle are stored as chunks across parts.
+		if first, _, chunkErr := d.find(bundlearchive.ChunkName(fPath, 0)); chunkErr == nil {
+			return &chunkedReader{d: d, name: fPath, first: first}, nil
+		}
+		return nil, storagedriver.PathNotFoundError{Path: fPath}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// open opens the named file from the first archive that contains it.
+func (d *driver) open(name string) (fs.File, error) {
+	err := fs.ErrNotExist
 	for _, tfs := range d.archiveFileSystems {
 		var file fs.File
-		file, err = tfs.Open(fPath)
+		file, err = tfs.Open(name)
 		if err == nil {
 			return file, nil
 		}
@@ -214,13 +273,127 @@ func (d *driver) Reader(ctx context.Context, fPath string, offset int64) (io.Rea
 		}
 	}
 
-	if errors.Is(err, fs.ErrNotExist) {
-		return nil, storagedriver.PathNotFoundError{Path: fPath}
+	return nil, err
+}
+
+// stat returns the FileInfo of the named file from the first archive that contains it.
+func (d *driver) stat(name string) (fs.FileInfo, error) {
+	_, fi, err := d.find(name)
+	return fi, err
+}
+
+// find returns the index and the FileInfo of the named file in the first archive that contains it.
+func (d *driver) find(name string) (int, fs.FileInfo, error) {
+	err := fs.ErrNotExist
+	for i, tfs := range d.archiveFileSystems {
+		var fi fs.FileInfo
+		fi, err = fs.Stat(tfs, name)
+		if err == nil {
+			return i, fi, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return 0, nil, err
+		}
 	}
 
-	return nil, err
+	return 0, nil, err
+}
+
+// statChunk returns the FileInfo of chunk i of a file stored as chunks, whose first chunk is in archive first.
+// Chunks fill up consecutive parts of a split bundle, and the parts are passed in the order of the parts index, so
+// chunk i is read from the archive i places after the first chunk. This keeps the chunks of files present in several
+// split bundles, that may have been split with different part sizes, from being mixed up.
+func (d *driver) statChunk(name string, first, i int) (fs.FileInfo, error) {
+	if first+i >= len(d.archiveFileSystems) {
+		return nil, fs.ErrNotExist
+	}
+	return fs.Stat(d.archiveFileSystems[first+i], bundlearchive.ChunkName(name, i))
+}
+
+// openChunk opens chunk i of a file stored as chunks, whose first chunk is in archive first. See statChunk.
+func (d *driver) openChunk(name string, first, i int) (fs.File, error) {
+	if first+i >= len(d.archiveFileSystems) {
+		return nil, fs.ErrNotExist
+	}
+	return d.archiveFileSystems[first+i].Open(bundlearchive.ChunkName(name, i))
+}
+
+// statChunks returns the FileInfo of a file stored as chunks, with the size of all chunks combined.
+func (d *driver) statChunks(name string) (fs.FileInfo, error) {
+	first, firstInfo, err := d.find(bundlearchive.ChunkName(name, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	size := firstInfo.Size()
+	for i := 1; ; i++ {
+		fi, err := d.statChunk(name, first, i)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		size += fi.Size()
+	}
+
+	return chunkedFileInfo{FileInfo: firstInfo, name: path.Base(name), size: size}, nil
+}
+
+// chunkedReader reads the chunks of a file stored as chunks across the parts of a split bundle in order.
+type chunkedReader struct {
+	d    *driver
+	name string
+	// first is the index of the archive containing the first chunk.
+	first   int
+	next    int
+	current fs.File
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			file, err := r.d.openChunk(r.name, r.first, r.next)
+			if errors.Is(err, fs.ErrNotExist) && r.next > 0 {
+				return 0, io.EOF
+			}
+			if err != nil {
+				return 0, err
+			}
+			r.current = file
+			r.next++
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			_ = r.current.Close()
+			r.current = nil
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (r *chunkedReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
 }
 
+type chunkedFileInfo struct {
+	fs.FileInfo
+	name string
+	size int64
+}
+
+func (fi chunkedFileInfo) Name() string { return fi.name }
+
+func (fi chunkedFileInfo) Size() int64 { return fi.size }
+
 func (d *driver) Writer(
 	ctx context.Context, subPath string, appendTo bool,
 ) (storagedriver.FileWriter, error) {
@@ -235,33 +408,28 @@ func (d *driver) Stat(ctx context.Context, subPath string) (storagedriver.FileIn
 	)
 	archiveSubpath = strings.TrimPrefix(archiveSubpath, "/")
 
-	var err error
-	for _, tfs := range d.archiveFileSystems {
-		var fi fs.FileInfo
-		fi, err = fs.Stat(tfs, archiveSubpath)
-		if err == nil {
-			return fileInfo{
-				fPath:    subPath,
-				FileInfo: fi,
-			}, nil
-		}
-
-		if !errors.Is(err, fs.ErrNotExist) {
-			return nil, err
-		}
+	fi, err := d.stat(archiveSubpath)
+	if errors.Is(err, fs.ErrNotExist) {
+		fi, err = d.statChunks(archiveSubpath)
 	}
-
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, storagedriver.PathNotFoundError{Path: subPath}
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	return nil, err
+	return fileInfo{
+		fPath:    subPath,
+		FileInfo: fi,
+	}, nil
 }
 
 // List returns a list of the objects that are direct descendants of the given
 // path.
 func (d *driver) List(ctx context.Context, subPath string) ([]string, error) {
 	var keys []string
+	seen := map[string]struct{}{}
 
 	archiveSubpath := strings.Replace(
 		subPath, path.Join(dockerReposPath, d.repositoriesPrefix), dockerReposPath, 1,
@@ -280,6 +448,20 @@ func (d *driver) List(ctx context.Context, subPath string) ([]string, error) {
 		}
 
 		for _, dirEntry := range dirEntries {
+			entryName := dirEntry.Name()
+			// List files stored as chunks across the parts of a split bundle once, under their original name.
+			if name, i, ok := bundlearchive.ParseChunkName(entryName); ok {
+				if i != 0 {
+					continue
+				}
+				entryName = name
+			}
+			// Directories can be present in more than one archive, e.g. in the parts of a split bundle.
+			if _, ok := seen[entryName]; ok {
+				continue
+			}
+			seen[entryName] = struct{}{}
+
 			keys = append(
 				keys,
 				path.Join(
@@ -289,7 +471,7 @@ func (d *driver) List(ctx context.Context, subPath string) ([]string, error) {
 						path.Join(dockerReposPath, d.repositoriesPrefix),
 						1,
 					),
-					dirEntry.Name(),
+					entryName,
 				),
 			)
 		}
@@ -0,0 +1,161 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	bundlearchive "github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/archive/archivetest"
+)
+
+func TestDriverReadsSplitBundle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]int{
+		"docker/registry/v2/blobs/sha256/aa/aa/data": 600 << 10,
+		"docker/registry/v2/blobs/sha256/bb/bb/data": 600 << 10,
+		"docker/registry/v2/blobs/sha256/cc/cc/data": 3 << 20,
+	}
+	contents := make(map[string][]byte, len(files))
+	for name, size := range files {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o644))
+		contents[name] = content
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar")
+	_, err := bundlearchive.ArchiveDirectoryInParts(dir, outputFile, bundlearchive.MinPartSize)
+	require.NoError(t, err)
+	parts, err := bundlearchive.VerifyParts(bundlearchive.PartsIndexFile(outputFile))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	d, err := New(ctx, DriverParameters{Archives: parts, MaxThreads: minThreads})
+	require.NoError(t, err)
+
+	for name, content := range contents {
+		fi, err := d.Stat(ctx, "/"+name)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), fi.Size(), name)
+		assert.False(t, fi.IsDir(), name)
+
+		rc, err := d.Reader(ctx, "/"+name, 0)
+		require.NoError(t, err)
+		read, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, content, read, name)
+	}
+
+	keys, err := d.List(ctx, "/docker/registry/v2/blobs/sha256/cc/cc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/docker/registry/v2/blobs/sha256/cc/cc/data"}, keys)
+
+	keys, err = d.List(ctx, "/docker/registry/v2/blobs/sha256")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"/docker/registry/v2/blobs/sha256/aa",
+		"/docker/registry/v2/blobs/sha256/bb",
+		"/docker/registry/v2/blobs/sha256/cc",
+	}, keys)
+}
+
+func TestDriverReadsChunksFromSameSplitBundle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := "docker/registry/v2/blobs/sha256/aa/aa/data"
+	content := make([]byte, 5<<19)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o644))
+
+	// The same file is split into two chunks in bundle A and three chunks in bundle B.
+	splitBundle := func(bundleName string, maxPartSize int64, wantParts int) []string {
+		outputFile := filepath.Join(t.TempDir(), bundleName)
+		_, err := bundlearchive.ArchiveDirectoryInParts(dir, outputFile, maxPartSize)
+		require.NoError(t, err)
+		parts, err := bundlearchive.VerifyParts(bundlearchive.PartsIndexFile(outputFile))
+		require.NoError(t, err)
+		require.Len(t, parts, wantParts)
+		return parts
+	}
+	partsA := splitBundle("a.tar", 2*bundlearchive.MinPartSize, 2)
+	partsB := splitBundle("b.tar", bundlearchive.MinPartSize, 3)
+
+	ctx := context.Background()
+	for _, archives := range [][]string{
+		append(slices.Clone(partsA), partsB...),
+		append(slices.Clone(partsB), partsA...),
+	} {
+		d, err := New(ctx, DriverParameters{Archives: archives, MaxThreads: minThreads})
+		require.NoError(t, err)
+
+		fi, err := d.Stat(ctx, "/"+name)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), fi.Size(), archives)
+
+		rc, err := d.Reader(ctx, "/"+name, 0)
+		require.NoError(t, err)
+		read, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, content, read, archives)
+	}
+}
+
+func TestDriverReadsEncryptedBundle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := "docker/registry/v2/blobs/sha256/aa/aa/data"
+	content := make([]byte, 300<<10)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o644))
+
+	identity, keyFile := archivetest.NewKeyFile(t)
+
+	outputFile := filepath.Join(t.TempDir(), "bundle.tar.age")
+	require.NoError(t, bundlearchive.ArchiveDirectoryEncrypted(dir, outputFile, identity.Recipient()))
+
+	ctx := context.Background()
+	params, err := fromParametersImpl(map[string]any{
+		"archives":           []any{outputFile},
+		"decryptionKeyFiles": []any{keyFile},
+	})
+	require.NoError(t, err)
+	d, err := New(ctx, *params)
+	require.NoError(t, err)
+
+	fi, err := d.Stat(ctx, "/"+name)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), fi.Size())
+
+	rc, err := d.Reader(ctx, "/"+name, 0)
+	require.NoError(t, err)
+	read, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, content, read)
+
+	_, err = New(ctx, DriverParameters{Archives: []string{outputFile}, MaxThreads: minThreads})
+	require.ErrorIs(t, err, bundlearchive.ErrNoDecryptionKeys)
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/distribution/reference"
 	"github.com/hashicorp/go-getter"
+	"golang.org/x/time/rate"
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/chart"
 	"helm.sh/helm/v4/pkg/chart/loader"
@@ -27,13 +28,15 @@ import (
 	"github.com/mesosphere/dkp-cli-runtime/core/output"
 
 	"github.com/mesosphere/mindthegap/helm/internal/tlsutil"
+	"github.com/mesosphere/mindthegap/images/httputils"
 )
 
 const OCIScheme = registry.OCIScheme
 
 type Client struct {
-	tempDir string
-	out     output.Output
+	tempDir          string
+	out              output.Output
+	bandwidthLimiter *rate.Limiter
 }
 
 type CleanupFunc func() error
@@ -53,6 +56,13 @@ func NewClient(out output.Output) (*Client, CleanupFunc) {
 	}
 }
 
+// WithBandwidthLimiter throttles chart downloads with limiter. Helm repository index files are not throttled. If
+// limiter is nil then downloads are not throttled.
+func (c *Client) WithBandwidthLimiter(limiter *rate.Limiter) *Client {
+	c.bandwidthLimiter = limiter
+	return c
+}
+
 func DoNotUntarOpt() action.PullOpt {
 	return func(p *action.Pull) {
 		p.Untar = false
@@ -115,11 +125,19 @@ func (c *Client) newRegistryClientForPullAction(
 	pull *action.Pull,
 ) (*registry.Client, error) {
 	if pull.PlainHTTP {
-		return registry.NewClient(
+		opts := []registry.ClientOption{
 			registry.ClientOptDebug(klog.V(4).Enabled()),
 			registry.ClientOptPlainHTTP(),
 			registry.ClientOptWriter(c.out.V(4).InfoWriter()),
-		)
+		}
+		if c.bandwidthLimiter != nil {
+			opts = append(opts, registry.ClientOptHTTPClient(&http.Client{
+				Transport: httputils.BandwidthLimitedRoundTripper(
+					http.DefaultTransport.(*http.Transport).Clone(), c.bandwidthLimiter,
+				),
+			}))
+		}
+		return registry.NewClient(opts...)
 	}
 
 	tlsConf, err := tlsutil.NewTLSConfig(
@@ -137,10 +155,10 @@ func (c *Client) newRegistryClientForPullAction(
 		registry.ClientOptEnableCache(true),
 		registry.ClientOptWriter(c.out.V(4).InfoWriter()),
 		registry.ClientOptHTTPClient(&http.Client{
-			Transport: &http.Transport{
+			Transport: httputils.BandwidthLimitedRoundTripper(&http.Transport{
 				TLSClientConfig: tlsConf,
 				Proxy:           http.ProxyFromEnvironment,
-			},
+			}, c.bandwidthLimiter),
 		}),
 		registry.ClientOptBasicAuth(pull.Username, pull.Password),
 	)
@@ -238,6 +256,18 @@ func (c *Client) GetChartFromURL(outputDir, chartURL, workingDir string) (string
 	copyFileGetter := new(getter.FileGetter)
 	copyFileGetter.Copy = true
 	getters["file"] = copyFileGetter
+	if c.bandwidthLimiter != nil {
+		httpGetter := &getter.HttpGetter{
+			Netrc: true,
+			Client: &http.Client{
+				Transport: httputils.BandwidthLimitedRoundTripper(
+					http.DefaultTransport.(*http.Transport).Clone(), c.bandwidthLimiter,
+				),
+			},
+		}
+		getters["http"] = httpGetter
+		getters["https"] = httpGetter
+	}
 
 	u, err := url.Parse(chartURL)
 	if err != nil {
@@ -0,0 +1,82 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// containerdImageNameAnnot is the annotation containerd uses for the full image name when importing OCI layouts.
+const containerdImageNameAnnot = "io.containerd.image.name"
+
+// WriteOCILayout writes the entries to an OCI image layout in dir, which must not exist or be empty. Each entry is
+// annotated with its full reference, so that tools such as skopeo, podman and containerd import it under that
+// name. Image indexes are written as is, preserving all platforms.
+func WriteOCILayout(dir string, entries []Entry) error {
+	if dirEntries, err := os.ReadDir(dir); err == nil && len(dirEntries) > 0 {
+		return fmt.Errorf("OCI layout directory %s is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read OCI layout directory %s: %w", dir, err)
+	}
+
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		return fmt.Errorf("failed to create OCI layout in %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		var opts []layout.Option
+		if entry.Ref != nil {
+			opts = append(opts, layout.WithAnnotations(map[string]string{
+				ociRefNameAnnot:          entry.Ref.Name(),
+				containerdImageNameAnnot: entry.Ref.Name(),
+			}))
+		}
+
+		switch {
+		case entry.Image != nil:
+			err = p.AppendImage(entry.Image, opts...)
+		case entry.Index != nil:
+			err = p.AppendIndex(entry.Index, opts...)
+		default:
+			return fmt.Errorf("entry %v has neither image nor index", entry.Ref)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %v to OCI layout: %w", entry.Ref, err)
+		}
+	}
+
+	return nil
+}
+
+// WriteDockerArchive writes the entries to a docker-save tarball at tarPath, as understood by docker load. The
+// format cannot hold image indexes, so every entry must be a tagged single image.
+func WriteDockerArchive(tarPath string, entries []Entry) error {
+	refToImage := make(map[name.Reference]v1.Image, len(entries))
+	for _, entry := range entries {
+		if entry.Ref == nil {
+			return errors.New("images written to a docker archive must have a reference")
+		}
+		if entry.Image == nil {
+			return fmt.Errorf(
+				"%s is an image index: docker archives can only contain single platform images", entry.Ref,
+			)
+		}
+		refToImage[entry.Ref] = entry.Image
+	}
+
+	if err := tarball.MultiRefWriteToFile(tarPath, refToImage); err != nil {
+		return fmt.Errorf("failed to write docker archive %s: %w", tarPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+
+	"github.com/mesosphere/mindthegap/images/archive"
+)
+
+func testEntries(t *testing.T) (archive.Entry, archive.Entry) {
+	t.Helper()
+	img, err := mutate.Canonical(empty.Image)
+	if err != nil {
+		t.Fatalf("canonical image: %v", err)
+	}
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+		},
+	)
+	return archive.Entry{Ref: name.MustParseReference("example.com/single:v1"), Image: img},
+		archive.Entry{Ref: name.MustParseReference("example.com/multi:v2"), Index: idx}
+}
+
+func TestWriteOCILayout(t *testing.T) {
+	imageEntry, indexEntry := testEntries(t)
+
+	layoutDir := filepath.Join(t.TempDir(), "layout")
+	if err := archive.WriteOCILayout(layoutDir, []archive.Entry{imageEntry, indexEntry}); err != nil {
+		t.Fatalf("WriteOCILayout: %v", err)
+	}
+
+	a, err := archive.Open(tarLayoutDir(t, layoutDir))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer a.Close()
+	entries, err := a.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].Ref.String() != "example.com/single:v1" || entries[0].Image == nil {
+		t.Fatalf("first entry = %v, want image example.com/single:v1", entries[0].Ref)
+	}
+	if entries[1].Ref.String() != "example.com/multi:v2" || entries[1].Index == nil {
+		t.Fatalf("second entry = %v, want index example.com/multi:v2", entries[1].Ref)
+	}
+
+	if err := archive.WriteOCILayout(layoutDir, []archive.Entry{imageEntry}); err == nil ||
+		!strings.Contains(err.Error(), "is not empty") {
+		t.Fatalf("WriteOCILayout to non-empty directory: got %v, want not empty error", err)
+	}
+}
+
+func TestWriteDockerArchive(t *testing.T) {
+	imageEntry, indexEntry := testEntries(t)
+
+	tarPath := filepath.Join(t.TempDir(), "docker.tar")
+	if err := archive.WriteDockerArchive(tarPath, []archive.Entry{imageEntry}); err != nil {
+		t.Fatalf("WriteDockerArchive: %v", err)
+	}
+
+	format, err := archive.Detect(tarPath)
+	if err != nil {
+		t.Fatalf("Detect: %v", err)
+	}
+	if format != archive.FormatDockerArchive {
+		t.Fatalf("format = %v, want %v", format, archive.FormatDockerArchive)
+	}
+	a, err := archive.Open(tarPath)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer a.Close()
+	entries, err := a.Entries()
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Ref.String() != "example.com/single:v1" {
+		t.Fatalf("got entries %v, want example.com/single:v1", entries)
+	}
+
+	err = archive.WriteDockerArchive(filepath.Join(t.TempDir(), "docker.tar"), []archive.Entry{indexEntry})
+	if err == nil || !strings.Contains(err.Error(), "is an image index") {
+		t.Fatalf("WriteDockerArchive with index: got %v, want image index error", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package authnhelpers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dockerConfigJSONKey = ".dockerconfigjson"
+	dockerCfgKey        = ".dockercfg"
+
+	// dockerHubAuthKey is the key of Docker Hub credentials in containers auth.json files.
+	dockerHubAuthKey = "docker.io"
+)
+
+type authFileKeychain struct {
+	configFile *configfile.ConfigFile
+}
+
+// NewAuthFileKeychain returns a keychain providing credentials from the specified registry auth file. The file can
+// be a docker config.json, a containers auth.json, or a Kubernetes secret of type kubernetes.io/dockerconfigjson or
+// kubernetes.io/dockercfg. Credentials can be specified as username and password, identity token or registry
+// token, and per-registry credential helpers (credHelpers) and the default credential store (credsStore) are used
+// if configured. Registries without credentials in the file resolve to anonymous.
+func NewAuthFileKeychain(authFile string) (authn.Keychain, error) {
+	b, err := os.ReadFile(authFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry auth file: %w", err)
+	}
+
+	dockerConfig, err := dockerConfigFromAuthFile(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry auth file %s: %w", authFile, err)
+	}
+
+	configFile, err := config.LoadFromReader(bytes.NewReader(dockerConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry auth file %s: %w", authFile, err)
+	}
+
+	return authFileKeychain{configFile: configFile}, nil
+}
+
+// Resolve implements authn.Keychain.
+func (k authFileKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	// Docker Hub credentials are looked up with the legacy index URL as key, the same key that docker login stores
+	// them with and that authn.DefaultKeychain looks up.
+	cfg, err := k.configFile.GetAuthConfig(target.RegistryStr())
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to retrieve credentials for %s from registry auth file: %w", target.RegistryStr(), err,
+		)
+	}
+	if isEmptyAuthConfig(cfg) && target.RegistryStr() == name.DefaultRegistry {
+		// Containers auth.json files store Docker Hub credentials as docker.io instead.
+		cfg = k.configFile.AuthConfigs[dockerHubAuthKey]
+	}
+	if isEmptyAuthConfig(cfg) {
+		return authn.Anonymous, nil
+	}
+
+	return authn.FromConfig(authn.AuthConfig{
+		Username:      cfg.Username,
+		Password:      cfg.Password,
+		IdentityToken: cfg.IdentityToken,
+		RegistryToken: cfg.RegistryToken,
+	}), nil
+}
+
+func isEmptyAuthConfig(cfg types.AuthConfig) bool {
+	return cfg.Username == "" && cfg.Password == "" && cfg.IdentityToken == "" && cfg.RegistryToken == ""
+}
+
+// kubernetesSecret contains the fields of a Kubernetes secret containing registry credentials.
+type kubernetesSecret struct {
+	Kind       string            `json:"kind"       yaml:"kind"`
+	Type       string            `json:"type"       yaml:"type"`
+	Data       map[string]string `json:"data"       yaml:"data"`
+	StringData map[string]string `json:"stringData" yaml:"stringData"`
+}
+
+// dockerConfigFromAuthFile returns the docker config JSON from the contents of a registry auth file, extracting it
+// from Kubernetes secrets. Kubernetes secrets can be specified as either JSON or YAML.
+func dockerConfigFromAuthFile(b []byte) ([]byte, error) {
+	var secret kubernetesSecret
+	if json.Valid(b) {
+		if err := json.Unmarshal(b, &secret); err != nil {
+			return nil, err
+		}
+	} else if err := yaml.Unmarshal(b, &secret); err != nil {
+		return nil, err
+	}
+
+	if secret.Kind != "Secret" {
+		return b, nil
+	}
+
+	secretValue := func(key string) (string, bool, error) {
+		if v, ok := secret.StringData[key]; ok {
+			return v, true, nil
+		}
+		v, ok := secret.Data[key]
+		if !ok {
+			return "", false, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to decode %s in Kubernetes secret: %w", key, err)
+		}
+		return string(decoded), true, nil
+	}
+
+	if v, ok, err := secretValue(dockerConfigJSONKey); err != nil || ok {
+		return []byte(v), err
+	}
+	// The legacy .dockercfg format only contains the auths section of the docker config.
+	if v, ok, err := secretValue(dockerCfgKey); err != nil || ok {
+		return []byte(`{"auths":` + v + `}`), err
+	}
+
+	return nil, fmt.Errorf(
+		"secret of type %q does not contain %s or %s", secret.Type, dockerConfigJSONKey, dockerCfgKey,
+	)
+}
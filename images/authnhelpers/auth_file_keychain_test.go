@@ -0,0 +1,200 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package authnhelpers
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeAuthFile(t *testing.T, content string) string {
+	t.Helper()
+	f := filepath.Join(t.TempDir(), "auth.json")
+	require.NoError(t, os.WriteFile(f, []byte(content), 0o600))
+	return f
+}
+
+func resolve(t *testing.T, kc authn.Keychain, registry string) *authn.AuthConfig {
+	t.Helper()
+	reg, err := name.NewRegistry(registry)
+	require.NoError(t, err)
+	auth, err := kc.Resolve(reg)
+	require.NoError(t, err)
+	if auth == authn.Anonymous {
+		return nil
+	}
+	cfg, err := auth.Authorization()
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestAuthFileKeychain(t *testing.T) {
+	t.Parallel()
+
+	userPass := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	dockerConfig := `{
+	"auths": {
+		"registry.example.com": {"auth": "` + userPass + `"},
+		"https://index.docker.io/v1/": {"username": "hubuser", "password": "hubpass"},
+		"identity.example.com": {"username": "<token>", "identitytoken": "refresh-token"},
+		"bearer.example.com": {"registrytoken": "bearer-token"}
+	}
+}`
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]*authn.AuthConfig
+	}{{
+		name:    "docker config",
+		content: dockerConfig,
+		want: map[string]*authn.AuthConfig{
+			"registry.example.com": {Username: "user", Password: "pass"},
+			"index.docker.io":      {Username: "hubuser", Password: "hubpass"},
+			"identity.example.com": {Username: "<token>", IdentityToken: "refresh-token"},
+			"bearer.example.com":   {RegistryToken: "bearer-token"},
+			"other.example.com":    nil,
+		},
+	}, {
+		name: "containers auth.json with repository scoped credentials",
+		content: `{"auths": {"https://registry.example.com/v2/": {"auth": "` + userPass + `"}}}
+`,
+		want: map[string]*authn.AuthConfig{
+			"registry.example.com": {Username: "user", Password: "pass"},
+		},
+	}, {
+		name: "Kubernetes dockerconfigjson secret",
+		content: `apiVersion: v1
+kind: Secret
+type: kubernetes.io/dockerconfigjson
+metadata:
+  name: regcred
+data:
+  .dockerconfigjson: ` + base64.StdEncoding.EncodeToString([]byte(dockerConfig)) + `
+`,
+		want: map[string]*authn.AuthConfig{
+			"registry.example.com": {Username: "user", Password: "pass"},
+		},
+	}, {
+		name: "Kubernetes dockercfg secret as JSON with stringData",
+		content: `{
+	"apiVersion": "v1",
+	"kind": "Secret",
+	"type": "kubernetes.io/dockercfg",
+	"stringData": {".dockercfg": "{\"registry.example.com\": {\"auth\": \"` + userPass + `\"}}"}
+}`,
+		want: map[string]*authn.AuthConfig{
+			"registry.example.com": {Username: "user", Password: "pass"},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			kc, err := NewAuthFileKeychain(writeAuthFile(t, tt.content))
+			require.NoError(t, err)
+			for registry, want := range tt.want {
+				assert.Equal(t, want, resolve(t, kc, registry), registry)
+			}
+		})
+	}
+}
+
+func TestAuthFileKeychainCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a shell script as the credential helper")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(`#!/bin/sh
+read server
+echo "{\"ServerURL\":\"$server\",\"Username\":\"helper-user\",\"Secret\":\"helper-secret\"}"
+`), 0o700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	kc, err := NewAuthFileKeychain(writeAuthFile(t, `{"credHelpers": {"helper.example.com": "fake"}}`))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&authn.AuthConfig{Username: "helper-user", Password: "helper-secret"},
+		resolve(t, kc, "helper.example.com"),
+	)
+	assert.Nil(t, resolve(t, kc, "other.example.com"))
+}
+
+func TestAuthFileKeychainDockerHub(t *testing.T) {
+	t.Parallel()
+
+	// Containers auth.json files store Docker Hub credentials as docker.io.
+	kc, err := NewAuthFileKeychain(
+		writeAuthFile(t, `{"auths": {"docker.io": {"username": "hubuser", "password": "hubpass"}}}`),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "hubuser", Password: "hubpass"}, resolve(t, kc, "docker.io"))
+	assert.Equal(t, &authn.AuthConfig{Username: "hubuser", Password: "hubpass"}, resolve(t, kc, "index.docker.io"))
+	assert.Nil(t, resolve(t, kc, "registry.example.com"))
+}
+
+func TestAuthFileKeychainDockerHubCredentialStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a shell script as the credential helper")
+	}
+
+	// docker login stores Docker Hub credentials in the credential store with the legacy index URL as key.
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(`#!/bin/sh
+read server
+if [ "$server" != "https://index.docker.io/v1/" ]; then
+  echo "credentials not found in native keychain"
+  exit 1
+fi
+echo "{\"ServerURL\":\"$server\",\"Username\":\"hubuser\",\"Secret\":\"hubsecret\"}"
+`), 0o700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	kc, err := NewAuthFileKeychain(writeAuthFile(t, `{"credsStore": "fake"}`))
+	require.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "hubuser", Password: "hubsecret"}, resolve(t, kc, "docker.io"))
+	assert.Nil(t, resolve(t, kc, "registry.example.com"))
+}
+
+func TestAuthFileKeychainErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{{
+		name:    "invalid JSON",
+		content: `{"auths": `,
+		wantErr: "failed to parse registry auth file",
+	}, {
+		name:    "secret without docker config",
+		content: "kind: Secret\ntype: Opaque\ndata:\n  password: cGFzcw==\n",
+		wantErr: `secret of type "Opaque" does not contain .dockerconfigjson or .dockercfg`,
+	}, {
+		name:    "invalid base64 in secret",
+		content: "kind: Secret\ndata:\n  .dockerconfigjson: '!!!'\n",
+		wantErr: "failed to decode .dockerconfigjson in Kubernetes secret",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewAuthFileKeychain(writeAuthFile(t, tt.content))
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := NewAuthFileKeychain(filepath.Join(t.TempDir(), "missing.json"))
+	require.ErrorContains(t, err, "failed to read registry auth file")
+}
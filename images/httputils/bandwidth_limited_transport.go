@@ -0,0 +1,101 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httputils
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// maxBandwidthBurst caps the number of bytes read in a single rate-limited read. Keeping individual reads
+// small spreads bandwidth fairly between concurrent transfers sharing the same limiter.
+const maxBandwidthBurst = 256 * 1024
+
+// NewBandwidthLimiter returns a limiter allowing at most bytesPerSecond bytes to be transferred per second.
+// It returns nil if bytesPerSecond is not positive, which BandwidthLimitedRoundTripper treats as unlimited.
+func NewBandwidthLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := int(min(bytesPerSecond, maxBandwidthBurst, math.MaxInt32))
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// BandwidthLimitedRoundTripper wraps rt so that request and response bodies are throttled by limiter.
+// Sharing a single limiter between multiple round trippers enforces the limit across all transfers made
+// through any of them. If limiter is nil then rt is returned unchanged.
+func BandwidthLimitedRoundTripper(rt http.RoundTripper, limiter *rate.Limiter) http.RoundTripper {
+	if limiter == nil {
+		return rt
+	}
+	return &bandwidthLimitedTransport{inner: rt, limiter: limiter}
+}
+
+// bandwidthLimitedTransport throttles request and response bodies.
+type bandwidthLimitedTransport struct {
+	inner   http.RoundTripper
+	limiter *rate.Limiter
+}
+
+// RoundTrip implements http.RoundTripper.
+func (bt *bandwidthLimitedTransport) RoundTrip(in *http.Request) (*http.Response, error) {
+	if in.Body != nil && in.Body != http.NoBody {
+		req := in.Clone(in.Context())
+		req.Body = &rateLimitedReadCloser{ctx: in.Context(), rc: in.Body, limiter: bt.limiter}
+		if in.GetBody != nil {
+			req.GetBody = func() (io.ReadCloser, error) {
+				body, err := in.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				return &rateLimitedReadCloser{ctx: in.Context(), rc: body, limiter: bt.limiter}, nil
+			}
+		}
+		in = req
+	}
+
+	resp, err := bt.inner.RoundTrip(in)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &rateLimitedReadCloser{ctx: in.Context(), rc: resp.Body, limiter: bt.limiter}
+	}
+	return resp, nil
+}
+
+// CloseIdleConnections closes idle connections of the wrapped transport, if supported.
+func (bt *bandwidthLimitedTransport) CloseIdleConnections() {
+	if tr, ok := bt.inner.(interface{ CloseIdleConnections() }); ok {
+		tr.CloseIdleConnections()
+	}
+}
+
+// rateLimitedReadCloser waits on the limiter for every chunk of bytes read.
+type rateLimitedReadCloser struct {
+	ctx     context.Context
+	rc      io.ReadCloser
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitedReadCloser) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.rc.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *rateLimitedReadCloser) Close() error {
+	return r.rc.Close()
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httputils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimitedRoundTripperNilLimiter(t *testing.T) {
+	t.Parallel()
+	assert.Same(t, http.DefaultTransport, BandwidthLimitedRoundTripper(http.DefaultTransport, nil))
+	assert.Nil(t, NewBandwidthLimiter(0))
+}
+
+func TestBandwidthLimitedRoundTripperSharedLimit(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bytesPerSecond = 64 * 1024
+		bodySize       = 48 * 1024
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write(make([]byte, bodySize))
+	}))
+	t.Cleanup(srv.Close)
+
+	limiter := NewBandwidthLimiter(bytesPerSecond)
+	clients := []*http.Client{
+		{Transport: BandwidthLimitedRoundTripper(srv.Client().Transport, limiter)},
+		{Transport: BandwidthLimitedRoundTripper(srv.Client().Transport, limiter)},
+	}
+
+	// Each client uploads and downloads bodySize bytes, so 4*bodySize bytes in total are transferred
+	// through the shared limiter. With an initial burst of one second's worth of bytes the transfers
+	// must take at least (4*bodySize - bytesPerSecond) / bytesPerSecond seconds.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Go(func() {
+			resp, err := c.Post(srv.URL, "application/octet-stream", bytes.NewReader(make([]byte, bodySize)))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			n, err := io.Copy(io.Discard, resp.Body)
+			assert.NoError(t, err)
+			assert.EqualValues(t, bodySize, n)
+		})
+	}
+	wg.Wait()
+
+	require.GreaterOrEqual(t, time.Since(start), 1900*time.Millisecond)
+}
@@ -27,6 +27,17 @@ func ManifestListForImage(
 	if err != nil {
 		return nil, fmt.Errorf("invalid image reference %q: %w", img, err)
 	}
+	return ManifestListForReference(ref, platforms, opts...)
+}
+
+// ManifestListForReference is like ManifestListForImage for an already parsed image reference, e.g. one parsed
+// with name.Insecure for a plain HTTP registry.
+func ManifestListForReference(
+	ref name.Reference,
+	platforms []string,
+	opts ...remote.Option,
+) (v1.ImageIndex, error) {
+	img := ref.String()
 	desc, err := remote.Get(ref, opts...)
 	if err != nil {
 		localImage, localErr := daemon.Image(ref)
@@ -72,7 +83,16 @@ func OCIArtifactImage(
 	if err != nil {
 		return nil, fmt.Errorf("invalid OCI artifact reference %q: %w", img, err)
 	}
+	return OCIArtifactForReference(ref, opts...)
+}
 
+// OCIArtifactForReference is like OCIArtifactImage for an already parsed reference, e.g. one parsed with
+// name.Insecure for a plain HTTP registry.
+func OCIArtifactForReference(
+	ref name.Reference,
+	opts ...remote.Option,
+) (v1.Image, error) {
+	img := ref.String()
 	desc, err := remote.Get(ref, opts...)
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -132,6 +152,41 @@ func RetainOnlyRequestedPlatformsInIndex(
 	return filterIndex(index, v1Platforms), nil
 }
 
+// ImageMatchesPlatforms returns true if no platforms are requested, or the platform of the single image satisfies
+// any of the requested platforms. Images without a platform, such as OCI artifacts, match all platforms.
+func ImageMatchesPlatforms(image v1.Image, platforms ...string) (bool, error) {
+	if len(platforms) == 0 || slices.Contains(platforms, "*/*") {
+		return true, nil
+	}
+
+	manifest, err := image.Manifest()
+	if err != nil {
+		return false, fmt.Errorf("failed to read image manifest: %w", err)
+	}
+	if !manifest.Config.MediaType.IsConfig() {
+		return true, nil
+	}
+	imageConfig, err := image.ConfigFile()
+	if err != nil {
+		return false, fmt.Errorf("failed to read image config: %w", err)
+	}
+	imagePlatform := imageConfig.Platform()
+	if imagePlatform == nil {
+		return true, nil
+	}
+
+	for _, p := range platforms {
+		v1P, err := v1.ParsePlatform(p)
+		if err != nil {
+			return false, fmt.Errorf("invalid platform %q: %w", p, err)
+		}
+		if imagePlatform.Satisfies(*v1P) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func filterIndex(idx v1.ImageIndex, platforms []v1.Platform) v1.ImageIndex {
 	matcher := not(satisfiesPlatforms(platforms))
 	return mutate.RemoveManifests(idx, matcher)
@@ -15,6 +15,9 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -695,3 +698,46 @@ func TestManifestListForOCIArtifact(t *testing.T) {
 		})
 	}
 }
+
+func TestImageMatchesPlatforms(t *testing.T) {
+	t.Parallel()
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	cfg.OS, cfg.Architecture, cfg.Variant = "linux", "arm", "v7"
+	img, err = mutate.ConfigFile(img, cfg)
+	require.NoError(t, err)
+
+	artifact := mutate.ConfigMediaType(
+		mutate.MediaType(empty.Image, types.OCIManifestSchema1), "application/vnd.example.config.v1+json",
+	)
+
+	tests := []struct {
+		name      string
+		image     v1.Image
+		platforms []string
+		want      bool
+	}{
+		{name: "no platforms", image: img, want: true},
+		{name: "all platforms", image: img, platforms: []string{"*/*"}, want: true},
+		{name: "matching platform", image: img, platforms: []string{"linux/amd64", "linux/arm/v7"}, want: true},
+		{name: "platform without variant", image: img, platforms: []string{"linux/arm"}, want: true},
+		{name: "other variant", image: img, platforms: []string{"linux/arm/v6"}, want: false},
+		{name: "other platform", image: img, platforms: []string{"linux/amd64"}, want: false},
+		{name: "OCI artifact", image: artifact, platforms: []string{"linux/amd64"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ImageMatchesPlatforms(tt.image, tt.platforms...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = ImageMatchesPlatforms(img, "linux/arm/v7/extra")
+	require.ErrorContains(t, err, "invalid platform")
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package mirrors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/types"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// Mirror is a registry mirror to pull images from instead of the origin registry.
+type Mirror struct {
+	// Location is the mirror registry host, optionally followed by a namespace that image repositories are
+	// nested under, e.g. mirror.corp:5000 or mirror.corp:5000/dockerhub.
+	Location string
+	// Insecure allows pulling from the mirror over plain HTTP or without verifying its TLS certificate.
+	Insecure bool
+}
+
+// Host returns the registry host of the mirror.
+func (m Mirror) Host() string {
+	host, _, _ := strings.Cut(m.Location, "/")
+	return host
+}
+
+// ParseMirror parses a mirror location. Mirrors prefixed with http:// are marked insecure.
+func ParseMirror(s string) (Mirror, error) {
+	m := Mirror{Location: s}
+	switch {
+	case strings.HasPrefix(s, "http://"):
+		m.Location = strings.TrimPrefix(s, "http://")
+		m.Insecure = true
+	case strings.HasPrefix(s, "https://"):
+		m.Location = strings.TrimPrefix(s, "https://")
+	}
+	m.Location = strings.TrimSuffix(m.Location, "/")
+
+	if _, err := name.NewRegistry(m.Host(), name.StrictValidation); err != nil || m.Host() == "" {
+		return Mirror{}, fmt.Errorf("invalid registry mirror %q", s)
+	}
+
+	return m, nil
+}
+
+// ParseRegistryMirror parses a mirror specified as <registry>=<mirror>, returning the registry and the mirror.
+func ParseRegistryMirror(s string) (string, Mirror, error) {
+	registry, mirror, ok := strings.Cut(s, "=")
+	if !ok || registry == "" || mirror == "" {
+		return "", Mirror{}, fmt.Errorf(
+			"invalid registry mirror %q: must be specified as <registry>=<mirror>", s,
+		)
+	}
+
+	m, err := ParseMirror(mirror)
+	if err != nil {
+		return "", Mirror{}, err
+	}
+
+	return registry, m, nil
+}
+
+// Source is a location to pull an image from.
+type Source struct {
+	// Reference is the image reference to pull.
+	Reference string
+	// Insecure allows pulling over plain HTTP or without verifying TLS certificates.
+	Insecure bool
+	// Mirror is true if the source is a mirror rather than the origin registry.
+	Mirror bool
+}
+
+// RegistriesConf contains the registries configured in containers registries.conf, loaded once so that it is
+// consistent for all images pulled in a run.
+type RegistriesConf struct {
+	registries []sysregistriesv2.Registry
+}
+
+// LoadRegistriesConf loads the containers registries.conf files found via sysCtx, which can be nil to use the
+// default locations.
+func LoadRegistriesConf(sysCtx *types.SystemContext) (*RegistriesConf, error) {
+	registries, err := sysregistriesv2.GetRegistries(sysCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registries configuration: %w", err)
+	}
+	return &RegistriesConf{registries: registries}, nil
+}
+
+// findRegistry returns the registry with the longest prefix matching ref, following the same rules as
+// sysregistriesv2.FindRegistry. Returns nil if no registry matches.
+func (c *RegistriesConf) findRegistry(ref string) *sysregistriesv2.Registry {
+	var found *sysregistriesv2.Registry
+	for i := range c.registries {
+		r := &c.registries[i]
+		if prefixMatches(ref, r.Prefix) && (found == nil || len(r.Prefix) > len(found.Prefix)) {
+			found = r
+		}
+	}
+	return found
+}
+
+// prefixMatches returns true if ref is prefix or is nested in prefix. Prefixes starting with *. match all
+// subdomains of the rest of the prefix.
+func prefixMatches(ref, prefix string) bool {
+	if domain, ok := strings.CutPrefix(prefix, "*"); ok {
+		host, _, _ := strings.Cut(ref, "/")
+		host, _, _ = strings.Cut(host, ":")
+		host, _, _ = strings.Cut(host, "@")
+		return strings.HasSuffix(host, domain)
+	}
+	rest, ok := strings.CutPrefix(ref, prefix)
+	return ok && (rest == "" || strings.ContainsAny(rest[:1], ":/@"))
+}
+
+// PullSources returns the sources to try in order when pulling the image: the mirrors followed by the origin
+// registry. If no mirrors are specified, the mirrors configured for the image in registriesConf are used if it is
+// not nil.
+func PullSources(image string, mirrors []Mirror, registriesConf *RegistriesConf) ([]Source, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+
+	if len(mirrors) == 0 {
+		if registriesConf == nil {
+			return []Source{{Reference: image}}, nil
+		}
+		return registriesConf.pullSources(image, named)
+	}
+
+	suffix := ""
+	if tagged, ok := named.(reference.Tagged); ok {
+		suffix = ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		suffix += "@" + digested.Digest().String()
+	}
+
+	sources := make([]Source, 0, len(mirrors)+1)
+	for _, m := range mirrors {
+		sources = append(sources, Source{
+			Reference: m.Location + "/" + reference.Path(named) + suffix,
+			Insecure:  m.Insecure,
+			Mirror:    true,
+		})
+	}
+	sources = append(sources, Source{Reference: image})
+
+	return sources, nil
+}
+
+func (c *RegistriesConf) pullSources(image string, named reference.Named) ([]Source, error) {
+	registry := c.findRegistry(named.String())
+	if registry == nil {
+		return []Source{{Reference: image}}, nil
+	}
+	if registry.Blocked {
+		return nil, fmt.Errorf("registry for image %q is blocked in registries configuration", image)
+	}
+
+	pullSources, err := registry.PullSourcesFromReference(named)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull sources for image %q: %w", image, err)
+	}
+
+	sources := make([]Source, 0, len(pullSources))
+	for i, ps := range pullSources {
+		sources = append(sources, Source{
+			Reference: ps.Reference.String(),
+			Insecure:  ps.Endpoint.Insecure,
+			// The origin registry is always the last pull source.
+			Mirror: i < len(pullSources)-1,
+		})
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package mirrors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containers/image/v5/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRegistryMirror(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in           string
+		wantRegistry string
+		want         Mirror
+		wantErr      string
+	}{{
+		in:           "docker.io=mirror.corp:5000",
+		wantRegistry: "docker.io",
+		want:         Mirror{Location: "mirror.corp:5000"},
+	}, {
+		in:           "quay.io=https://mirror.corp/quay/",
+		wantRegistry: "quay.io",
+		want:         Mirror{Location: "mirror.corp/quay"},
+	}, {
+		in:           "docker.io=http://127.0.0.1:5000",
+		wantRegistry: "docker.io",
+		want:         Mirror{Location: "127.0.0.1:5000", Insecure: true},
+	}, {
+		in:      "mirror.corp:5000",
+		wantErr: "must be specified as <registry>=<mirror>",
+	}, {
+		in:      "docker.io=",
+		wantErr: "must be specified as <registry>=<mirror>",
+	}, {
+		in:      "docker.io=http://",
+		wantErr: `invalid registry mirror "http://"`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+
+			registry, m, err := ParseRegistryMirror(tt.in)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRegistry, registry)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestPullSourcesExplicitMirrors(t *testing.T) {
+	t.Parallel()
+
+	mirrors := []Mirror{
+		{Location: "mirror.corp:5000"},
+		{Location: "other.corp/dockerhub", Insecure: true},
+	}
+
+	sources, err := PullSources("docker.io/nginx:1.25", mirrors, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Source{
+		{Reference: "mirror.corp:5000/library/nginx:1.25", Mirror: true},
+		{Reference: "other.corp/dockerhub/library/nginx:1.25", Insecure: true, Mirror: true},
+		{Reference: "docker.io/nginx:1.25"},
+	}, sources)
+
+	digest := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	sources, err = PullSources("quay.io/org/app@"+digest, mirrors[:1], nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Source{
+		{Reference: "mirror.corp:5000/org/app@" + digest, Mirror: true},
+		{Reference: "quay.io/org/app@" + digest},
+	}, sources)
+}
+
+func registriesConf(t *testing.T, content string) *RegistriesConf {
+	t.Helper()
+	dir := t.TempDir()
+	confFile := filepath.Join(dir, "registries.conf")
+	require.NoError(t, os.WriteFile(confFile, []byte(content), 0o600))
+	conf, err := LoadRegistriesConf(&types.SystemContext{
+		SystemRegistriesConfPath:    confFile,
+		SystemRegistriesConfDirPath: filepath.Join(dir, "registries.conf.d"),
+	})
+	require.NoError(t, err)
+	return conf
+}
+
+func TestPullSourcesRegistriesConf(t *testing.T) {
+	t.Parallel()
+
+	conf := registriesConf(t, `
+[[registry]]
+location = "docker.io"
+
+[[registry.mirror]]
+location = "mirror.corp:5000"
+insecure = true
+
+[[registry]]
+prefix = "docker.io/org"
+location = "docker.io/org"
+
+[[registry.mirror]]
+location = "org-mirror.corp/org"
+
+[[registry]]
+prefix = "*.example.com"
+location = "example.com"
+
+[[registry.mirror]]
+location = "example-mirror.corp"
+
+[[registry]]
+location = "blocked.example.com"
+blocked = true
+`)
+
+	sources, err := PullSources("docker.io/nginx:1.25", nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []Source{
+		{Reference: "mirror.corp:5000/library/nginx:1.25", Insecure: true, Mirror: true},
+		{Reference: "docker.io/library/nginx:1.25"},
+	}, sources)
+
+	sources, err = PullSources("quay.io/org/app:v1", nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []Source{{Reference: "quay.io/org/app:v1"}}, sources)
+
+	// The registry with the longest matching prefix is used.
+	sources, err = PullSources("docker.io/org/app:v1", nil, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []Source{
+		{Reference: "org-mirror.corp/org/app:v1", Mirror: true},
+		{Reference: "docker.io/org/app:v1"},
+	}, sources)
+
+	for ref, wantPrefix := range map[string]string{
+		"registry.example.com:5000/app:v1": "*.example.com",
+		"blocked.example.com/app:v1":       "blocked.example.com",
+		"example.com/app:v1":               "",
+		"docker.io/organization/app:v1":    "docker.io",
+	} {
+		got := conf.findRegistry(ref)
+		if wantPrefix == "" {
+			assert.Nil(t, got, ref)
+			continue
+		}
+		require.NotNil(t, got, ref)
+		assert.Equal(t, wantPrefix, got.Prefix, ref)
+	}
+
+	_, err = PullSources("blocked.example.com/app:v1", nil, conf)
+	require.ErrorContains(t, err, "is blocked in registries configuration")
+
+	// Explicit mirrors take precedence over registries.conf.
+	sources, err = PullSources("docker.io/nginx:1.25", []Mirror{{Location: "explicit.corp"}}, conf)
+	require.NoError(t, err)
+	assert.Equal(t, []Source{
+		{Reference: "explicit.corp/library/nginx:1.25", Mirror: true},
+		{Reference: "docker.io/nginx:1.25"},
+	}, sources)
+}
+
+func TestPullSourcesWithoutRegistriesConf(t *testing.T) {
+	t.Parallel()
+
+	sources, err := PullSources("docker.io/nginx:1.25", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Source{{Reference: "docker.io/nginx:1.25"}}, sources)
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 D2iQ, Inc. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bundletest provides helpers for tests that create and read bundles, such as registries to write images to
+// and bundles with given images and Helm charts.
+package bundletest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mesosphere/mindthegap/archive"
+	"github.com/mesosphere/mindthegap/config"
+	"github.com/mesosphere/mindthegap/docker/registry"
+)
+
+// StartRegistry starts a registry serving the storage and waits until it accepts connections. The registry is shut
+// down when the test finishes.
+func StartRegistry(t *testing.T, storage registry.Storage) *registry.Registry {
+	t.Helper()
+
+	reg, err := registry.NewRegistry(registry.Config{Storage: storage})
+	require.NoError(t, err)
+	go func() {
+		assert.NoError(t, reg.ListenAndServe(logr.Discard()))
+	}()
+	t.Cleanup(func() { _ = reg.Shutdown(context.Background()) })
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", reg.Address())
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	return reg
+}
+
+// NewPlatformImage returns a random single layer image for linux and the architecture.
+func NewPlatformImage(t *testing.T, arch string) v1.Image {
+	t.Helper()
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	cfg.OS, cfg.Architecture = "linux", arch
+	img, err = mutate.ConfigFile(img, cfg)
+	require.NoError(t, err)
+	return img
+}
+
+// NewChart returns a Helm chart OCI artifact with random content.
+func NewChart(t *testing.T) v1.Image {
+	t.Helper()
+
+	layer, err := random.Layer(64, types.OCILayer)
+	require.NoError(t, err)
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, "application/vnd.cncf.helm.config.v1+json")
+	img, err = mutate.Append(img, mutate.Addendum{
+		Layer:     layer,
+		MediaType: "application/vnd.cncf.helm.chart.content.v1.tar+gzip",
+	})
+	require.NoError(t, err)
+	return img
+}
+
+// ParseReference parses the reference, allowing plain HTTP registries.
+func ParseReference(t *testing.T, s string) name.Reference {
+	t.Helper()
+
+	ref, err := name.ParseReference(s, name.Insecure)
+	require.NoError(t, err)
+	return ref
+}
+
+// Digest returns the digest of the image or image index.
+func Digest(t *testing.T, d interface{ Digest() (v1.Hash, error) }) v1.Hash {
+	t.Helper()
+
+	h, err := d.Digest()
+	require.NoError(t, err)
+	return h
+}
+
+// WriteBundleDir writes the images, keyed by their reference such as example.com/team/app:v1, and the Helm charts
+// to a new directory in the layout of a bundle, along with the bundle configs, and returns the directory. Images can
+// be images or image indexes. Every version of the Helm charts is written as a new chart artifact.
+func WriteBundleDir(t *testing.T, images map[string]remote.Taggable, charts config.HelmChartsConfig) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	reg := StartRegistry(t, registry.FilesystemStorage(dir))
+
+	imagesCfg := config.ImagesConfig{}
+	for _, ref := range slices.Sorted(maps.Keys(images)) {
+		image := images[ref]
+		tag, err := name.NewTag(ref, name.StrictValidation)
+		require.NoError(t, err)
+		// Images are stored in bundles under their name without the registry.
+		destRef := ParseReference(t, fmt.Sprintf("%s/%s:%s", reg.Address(), tag.RepositoryStr(), tag.TagStr()))
+		switch image := image.(type) {
+		case v1.ImageIndex:
+			require.NoError(t, remote.WriteIndex(destRef, image))
+		case v1.Image:
+			require.NoError(t, remote.Write(destRef, image))
+		default:
+			require.Failf(t, "unsupported image type", "%s: %T", ref, image)
+		}
+
+		registryConfig, ok := imagesCfg[tag.RegistryStr()]
+		if !ok {
+			registryConfig = config.RegistrySyncConfig{Images: map[string][]string{}}
+		}
+		registryConfig.Images[tag.RepositoryStr()] = append(registryConfig.Images[tag.RepositoryStr()], tag.TagStr())
+		imagesCfg[tag.RegistryStr()] = registryConfig
+	}
+
+	for _, repoConfig := range charts.Repositories {
+		for chartName, versions := range repoConfig.Charts {
+			for _, version := range versions {
+				// Helm replaces + in chart versions with _ in OCI tags.
+				destRef := ParseReference(t, fmt.Sprintf(
+					"%s/charts/%s:%s", reg.Address(), chartName, strings.ReplaceAll(version, "+", "_"),
+				))
+				require.NoError(t, remote.Write(destRef, NewChart(t)))
+			}
+		}
+	}
+
+	require.NoError(t, reg.Shutdown(context.Background()))
+
+	if len(imagesCfg) > 0 {
+		require.NoError(t, config.WriteSanitizedImagesConfigs(filepath.Join(dir, "images.yaml"), imagesCfg))
+	}
+	if len(charts.Repositories) > 0 {
+		require.NoError(t, config.WriteSanitizedHelmChartsConfig(filepath.Join(dir, "charts.yaml"), charts))
+	}
+
+	return dir
+}
+
+// WriteBundle writes the images and Helm charts to a new bundle file and returns its path. See WriteBundleDir.
+func WriteBundle(t *testing.T, images map[string]remote.Taggable, charts config.HelmChartsConfig) string {
+	t.Helper()
+
+	bundleFile := filepath.Join(t.TempDir(), "bundle.tar")
+	require.NoError(t, archive.ArchiveDirectory(WriteBundleDir(t, images, charts), bundleFile))
+	return bundleFile
+}